@@ -21,21 +21,16 @@ func main() {
 
 	log.Info("application starting", slog.Any("grpc", cfg.GrpcSrv))
 
-	application := app.New(
-		log,
-		cfg.StorageUrl,
-		cfg.GrpcSrv.Port,
-		cfg.TokenTTL,
-	)
+	application := app.New(log, cfg)
 
-	go application.GrpcSrv.MustRun()
+	go application.MustRun()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
 	sign := <-stop
 	log.Info("application stopped", slog.String("signal", sign.String()))
-	application.GrpcSrv.Stop()
+	application.Stop()
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"domofon/internal/app"
+	"domofon/internal/config"
+	"domofon/internal/storage/postgres"
+	"flag"
+	"fmt"
+)
+
+// secrets encrypts plaintext app secrets and moves sealed ones to the primary master key:
+//
+//	secrets -config=./config/local.yaml -action=reseal
+func main() {
+	var action string
+
+	flag.StringVar(&action, "action", "reseal", "reseal")
+	cfg := config.MustLoad()
+
+	if action != "reseal" {
+		panic("unknown action: " + action)
+	}
+
+	// reseal is the migration of plaintext secrets, so they are always read
+	cfg.Secrets.AllowPlaintext = true
+	cipher := app.MustSecretCipher(cfg)
+	if cipher == nil {
+		panic("secrets.key_file is required")
+	}
+
+	storage, err := postgres.NewStorage(cfg.StorageUrl, cipher)
+	if err != nil {
+		panic(err)
+	}
+
+	n, err := storage.ResealAppSecrets(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%d app secrets resealed\n", n)
+}
@@ -2,32 +2,307 @@ package app
 
 import (
 	grpcapp "domofon/internal/app/grpc"
+	metricsapp "domofon/internal/app/metrics"
+	"domofon/internal/config"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/dpop"
+	"domofon/internal/lib/envelope"
+	"domofon/internal/lib/geoip"
+	"domofon/internal/lib/hasher"
+	"domofon/internal/lib/mail"
+	"domofon/internal/lib/policy"
+	"domofon/internal/lib/tlsreload"
+	"domofon/internal/lib/tokens"
+	"domofon/internal/services/admin"
+	"domofon/internal/services/apps"
 	"domofon/internal/services/auth"
+	"domofon/internal/services/authz"
+	"domofon/internal/services/groups"
+	"domofon/internal/services/history"
+	"domofon/internal/services/invitations"
+	"domofon/internal/services/orgs"
+	policyservice "domofon/internal/services/policy"
+	"domofon/internal/services/rbac"
+	"domofon/internal/services/sessions"
+	tokenservice "domofon/internal/services/tokens"
 	"domofon/internal/storage/postgres"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/credentials"
 	"log/slog"
-	"time"
 )
 
 type App struct {
-	GrpcSrv *grpcapp.App
+	GrpcSrv    *grpcapp.App
+	MetricsSrv *metricsapp.App
+	hasher     *hasher.Pool
+	mailQueue  *mail.Queue
+	tls        *tlsreload.Reloader
+	sweeper    *sessions.Sweeper
+	geo        *geoip.Resolver
 }
 
 func New(
 	log *slog.Logger,
-	storageUrl string,
-	port int,
-	tokenTTL time.Duration,
+	cfg *config.Config,
 ) *App {
-	storage, err := postgres.NewStorage(storageUrl)
+	storage, err := postgres.NewStorage(cfg.StorageUrl, MustSecretCipher(cfg))
 	if err != nil {
 		panic(err)
 	}
 
-	authService := auth.NewAuth(log, storage, storage, storage, tokenTTL)
+	mailSender := mail.NewSender(
+		log,
+		cfg.Mail.Host,
+		cfg.Mail.Port,
+		cfg.Mail.Username,
+		cfg.Mail.Password,
+		cfg.Mail.From,
+	)
+
+	mailQueue := mail.NewQueue(log, mailSender, cfg.Mail.QueueSize)
+
+	hasherPool := hasher.New(
+		cfg.Hasher.Workers,
+		cfg.Hasher.QueuePerWorker,
+		cfg.Hasher.MaxWait,
+		bcrypt.DefaultCost,
+	)
+
+	policyEngine, err := policy.NewEngine()
+	if err != nil {
+		panic(err)
+	}
+
+	tokenIssuer, err := tokens.NewIssuer(
+		cfg.Tokens.Issuer,
+		storage,
+		storage,
+		storage,
+		cfg.Tokens.PasetoSeed,
+		cfg.Tokens.OpaqueCacheTTL,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	proofVerifier, err := dpop.NewVerifier(
+		[]byte(cfg.DPoP.NonceKey),
+		cfg.DPoP.NonceTTL,
+		cfg.DPoP.ProofMaxAge,
+		cfg.DPoP.RequireNonce,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	geoResolver, err := geoip.Open(cfg.GeoIP.CityDB, cfg.GeoIP.ASNDB)
+	if err != nil {
+		panic(err)
+	}
+
+	authService := auth.NewAuth(
+		log,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		mailQueue,
+		hasherPool,
+		policyEngine,
+		auth.TokenOptions{
+			Issuer:   tokenIssuer,
+			Defaults: tokenDefaults(cfg),
+		},
+		auth.SessionOptions{
+			Saver: storage,
+			Defaults: models.SessionSettings{
+				IdleTimeout: &cfg.Sessions.IdleTimeout,
+				MaxLifetime: &cfg.Sessions.MaxLifetime,
+				MaxSessions: &cfg.Sessions.MaxSessions,
+				LimitPolicy: cfg.Sessions.LimitPolicy,
+			},
+		},
+		auth.RiskOptions{
+			History: storage,
+			Geo:     geoResolver,
+			Defaults: models.RiskSettings{
+				StepUpScore: &cfg.Risk.StepUpScore,
+				DenyScore:   &cfg.Risk.DenyScore,
+			},
+			FailureWindow: cfg.Risk.FailureWindow,
+			Travel: geoip.TravelPolicy{
+				MaxSpeed:    cfg.GeoIP.MaxTravelSpeed,
+				MinDistance: cfg.GeoIP.MinTravelDistance,
+			},
+			ChallengeTravel: mustChallengeTravel(cfg),
+		},
+		cfg.Auth.EnumerationSafeRegister,
+	)
+
+	var creds credentials.TransportCredentials
+	tlsReloader := mustTLSReloader(log, cfg)
+	if tlsReloader != nil {
+		creds = credentials.NewTLS(tlsReloader.TLSConfig())
+	}
+
+	application := &App{
+		GrpcSrv: grpcapp.New(
+			log,
+			cfg.GrpcSrv.Port,
+			grpcapp.Services{
+				Auth: authService,
+				RBAC: rbac.NewRBAC(log, storage, storage),
+				Authz: authz.NewAuthz(
+					log,
+					storage,
+					storage,
+					config.MustLoadNamespaces(cfg.Authz.NamespacesPath),
+					cfg.Authz.MaxDepth,
+				),
+				Policy: policyservice.NewPolicy(log, storage, storage, storage, policyEngine),
+				Orgs:   orgs.NewOrgs(log, storage, storage),
+				Invitations: invitations.NewInvitations(
+					log,
+					storage,
+					storage,
+					storage,
+					hasherPool,
+					mailQueue,
+					mustInvitationKey(cfg),
+					cfg.Invitation.TTL,
+				),
+				Groups:   groups.NewGroups(log, storage, storage),
+				Admin:    admin.NewAdmin(log, storage, storage),
+				Apps:     apps.NewApps(log, storage, storage, cfg.Apps.SecretGrace),
+				Tokens:   tokenservice.NewTokens(log, storage, tokenIssuer, proofVerifier),
+				Sessions: sessions.NewSessions(log, storage, storage),
+				History:  history.NewHistory(log, storage),
+			},
+			grpcapp.Security{
+				TokenParser:   tokenIssuer,
+				UserProvider:  storage,
+				DPoPBaseURL:   cfg.DPoP.BaseURL,
+				ProofVerifier: proofVerifier,
+				Creds:         creds,
+			},
+		),
+		hasher:    hasherPool,
+		mailQueue: mailQueue,
+		tls:       tlsReloader,
+		geo:       geoResolver,
+		sweeper: sessions.NewSweeper(
+			log,
+			storage,
+			cfg.Sessions.SweepInterval,
+			cfg.Sessions.SweepBatch,
+			cfg.History.Retention,
+		),
+	}
+	if cfg.Metrics.Port != 0 {
+		application.MetricsSrv = metricsapp.New(log, cfg.Metrics.Port)
+	}
+
+	return application
+}
+
+func (a *App) MustRun() {
+	a.sweeper.Start()
+	if a.MetricsSrv != nil {
+		go a.MetricsSrv.MustRun()
+	}
+
+	a.GrpcSrv.MustRun()
+}
+
+func (a *App) Stop() {
+	a.GrpcSrv.Stop()
+	if a.MetricsSrv != nil {
+		a.MetricsSrv.Stop()
+	}
+	a.hasher.Stop()
+	a.sweeper.Stop()
+	a.mailQueue.Stop()
+	if a.tls != nil {
+		a.tls.Stop()
+	}
+	_ = a.geo.Close()
+}
+
+// mustChallengeTravel reports whether impossible travel puts login to step-up rather than only being flagged
+func mustChallengeTravel(cfg *config.Config) bool {
+	switch cfg.GeoIP.ImpossibleTravel {
+	case "flag":
+		return false
+	case "challenge":
+		return true
+	}
+
+	panic("unknown geoip impossible_travel: " + cfg.GeoIP.ImpossibleTravel)
+}
+
+// mustTLSReloader watches configured certificate files, nil when gRPC serves plaintext
+func mustTLSReloader(log *slog.Logger, cfg *config.Config) *tlsreload.Reloader {
+	tlsCfg := cfg.GrpcSrv.TLS
+	if tlsCfg.CertFile == "" {
+		return nil
+	}
 
-	return &App{GrpcSrv: grpcapp.New(
+	clientAuth, err := tlsreload.ParseClientAuth(tlsCfg.ClientAuth)
+	if err != nil {
+		panic(err)
+	}
+
+	reloader, err := tlsreload.New(
 		log,
-		port,
-		authService,
-	)}
+		tlsCfg.CertFile,
+		tlsCfg.KeyFile,
+		tlsCfg.ClientCAFile,
+		clientAuth,
+		tlsCfg.ReloadInterval,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return reloader
+}
+
+// mustInvitationKey returns key invitation tokens are signed with, empty key would let anyone forge them
+func mustInvitationKey(cfg *config.Config) []byte {
+	if cfg.Invitation.SigningKey == "" {
+		panic("invitation signing key is empty")
+	}
+
+	return []byte(cfg.Invitation.SigningKey)
+}
+
+// MustSecretCipher returns keyring of configured master key file, nil keeps app secrets in plaintext
+func MustSecretCipher(cfg *config.Config) postgres.SecretCipher {
+	if cfg.Secrets.KeyFile == "" {
+		return nil
+	}
+
+	keys := config.MustLoadMasterKeys(cfg.Secrets.KeyFile)
+
+	keyring, err := envelope.NewKeyring(keys.Primary, keys.Keys, cfg.Secrets.AllowPlaintext)
+	if err != nil {
+		panic(err)
+	}
+
+	return keyring
+}
+
+func tokenDefaults(cfg *config.Config) models.TokenSettings {
+	return models.TokenSettings{
+		Format:    cfg.Tokens.Format,
+		AccessTTL: cfg.TokenTTL,
+		Issuer:    cfg.Tokens.Issuer,
+		Claims: models.ClaimSettings{
+			Version: cfg.Tokens.Claims.Version,
+			Email:   &cfg.Tokens.Claims.Email,
+			Roles:   &cfg.Tokens.Claims.Roles,
+			Admin:   &cfg.Tokens.Claims.Admin,
+		},
+	}
 }
@@ -1,10 +1,25 @@
 package grpcapp
 
 import (
+	"domofon/internal/grpc/admin"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/apps"
 	"domofon/internal/grpc/auth"
+	"domofon/internal/grpc/authz"
+	"domofon/internal/grpc/groups"
+	"domofon/internal/grpc/history"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/grpc/invitations"
+	"domofon/internal/grpc/orgs"
+	"domofon/internal/grpc/policy"
+	"domofon/internal/grpc/rbac"
+	"domofon/internal/grpc/sessions"
+	"domofon/internal/grpc/tokens"
 	"fmt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"log/slog"
+	"maps"
 	"net"
 )
 
@@ -14,13 +29,82 @@ type App struct {
 	port    int
 }
 
+// Services implement gRPC services the server registers
+type Services struct {
+	Auth        auth.Auth
+	RBAC        rbac.RBAC
+	Authz       authz.Authz
+	Policy      policy.Policy
+	Orgs        orgs.Orgs
+	Invitations invitations.Invitations
+	Groups      groups.Groups
+	Admin       admin.Admin
+	Apps        apps.Apps
+	Tokens      tokens.Tokens
+	Sessions    sessions.Sessions
+	History     history.History
+}
+
+// Security authenticates connections and callers of protected methods
+type Security struct {
+	TokenParser  interceptors.TokenParser
+	UserProvider interceptors.UserProvider
+	// DPoPBaseURL followed by full method name is URL DPoP proofs are made for
+	DPoPBaseURL   string
+	ProofVerifier interceptors.ProofVerifier
+	// Creds are TLS credentials of the server, nil serves plaintext
+	Creds credentials.TransportCredentials
+}
+
 func New(
 	log *slog.Logger,
 	port int,
-	authService auth.Auth,
+	services Services,
+	security Security,
 ) *App {
-	grpcSrv := grpc.NewServer()
-	auth.Register(grpcSrv, authService)
+	methods := interceptors.Methods{}
+	for _, access := range []interceptors.Methods{
+		rbac.Access,
+		authz.Access,
+		policy.Access,
+		orgs.Access,
+		invitations.Access,
+		groups.Access,
+		admin.Access,
+		apps.Access,
+		tokens.Access,
+		sessions.Access,
+		history.Access,
+	} {
+		maps.Copy(methods, access)
+	}
+
+	opts := []grpc.ServerOption{
+		// api services are json, the codec isn't registered for the whole process
+		grpc.ForceServerCodec(api.ServerCodec()),
+		grpc.ChainUnaryInterceptor(
+			interceptors.ClientCert(),
+			interceptors.DPoP(log, security.DPoPBaseURL, security.ProofVerifier),
+			interceptors.TokenAuth(log, methods, security.TokenParser, security.UserProvider),
+		),
+	}
+	if security.Creds != nil {
+		opts = append(opts, grpc.Creds(security.Creds))
+	}
+
+	grpcSrv := grpc.NewServer(opts...)
+	auth.Register(grpcSrv, services.Auth)
+	rbac.Register(grpcSrv, services.RBAC)
+	authz.Register(grpcSrv, services.Authz)
+	policy.Register(grpcSrv, services.Policy)
+	orgs.Register(grpcSrv, services.Orgs)
+	invitations.Register(grpcSrv, services.Invitations)
+	groups.Register(grpcSrv, services.Groups)
+	admin.Register(grpcSrv, services.Admin)
+	apps.Register(grpcSrv, services.Apps)
+	tokens.Register(grpcSrv, services.Tokens)
+	sessions.Register(grpcSrv, services.Sessions)
+	history.Register(grpcSrv, services.History)
 
 	return &App{log: log, port: port, grpcSrv: grpcSrv}
 }
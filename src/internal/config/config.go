@@ -8,10 +8,25 @@ import (
 )
 
 type Config struct {
-	Env        string        `yaml:"env" env-default:"local"`
-	StorageUrl string        `yaml:"storage_url"`
-	TokenTTL   time.Duration `yaml:"token_ttl" env-required:"true"`
-	GrpcSrv    GrpcConfig    `yaml:"grpc"`
+	Env        string `yaml:"env" env-default:"local"`
+	StorageUrl string `yaml:"storage_url"`
+	// TokenTTL is default lifetime of access tokens
+	TokenTTL   time.Duration    `yaml:"token_ttl" env-required:"true"`
+	Tokens     TokensConfig     `yaml:"tokens"`
+	GrpcSrv    GrpcConfig       `yaml:"grpc"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Mail       MailConfig       `yaml:"mail"`
+	Hasher     HasherConfig     `yaml:"hasher"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Authz      AuthzConfig      `yaml:"authz"`
+	Invitation InvitationConfig `yaml:"invitation"`
+	Apps       AppsConfig       `yaml:"apps"`
+	Secrets    SecretsConfig    `yaml:"secrets"`
+	DPoP       DPoPConfig       `yaml:"dpop"`
+	Sessions   SessionsConfig   `yaml:"sessions"`
+	GeoIP      GeoIPConfig      `yaml:"geoip"`
+	Risk       RiskConfig       `yaml:"risk"`
+	History    HistoryConfig    `yaml:"history"`
 }
 
 func MustLoad() *Config {
@@ -33,6 +48,13 @@ func MustLoadByPath(path string) *Config {
 		panic("can't read config file: " + path)
 	}
 
+	if cfg.DPoP.NonceTTL <= 0 {
+		panic("dpop nonce_ttl must be positive")
+	}
+	if cfg.DPoP.ProofMaxAge <= 0 {
+		panic("dpop proof_max_age must be positive")
+	}
+
 	return &cfg
 }
 
@@ -52,4 +74,148 @@ func fetchConfigPath() string {
 type GrpcConfig struct {
 	Port    int           `yaml:"port"`
 	Timeout time.Duration `yaml:"timeout"`
+	TLS     TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig of gRPC server, empty CertFile serves plaintext
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile verifies client certificates, needed by verifying ClientAuth modes
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is one of none, request, require_any, verify_if_given and require_and_verify
+	ClientAuth string `yaml:"client_auth" env-default:"none"`
+	// ReloadInterval is how often files are checked for changes, 0 disables reload
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+type AuthConfig struct {
+	// EnumerationSafeRegister hides whether email is already registered,
+	// the existing owner gets notification by email instead
+	EnumerationSafeRegister bool `yaml:"enumeration_safe_register" env-default:"false"`
+}
+
+type MailConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" env:"MAIL_PASSWORD"`
+	From     string `yaml:"from" env-default:"no-reply@domofon.local"`
+	// QueueSize bounds notifications waiting to be sent, more are dropped
+	QueueSize int `yaml:"queue_size" env-default:"100"`
+}
+
+type HasherConfig struct {
+	// Workers defaults to GOMAXPROCS
+	Workers        int           `yaml:"workers"`
+	QueuePerWorker int           `yaml:"queue_per_worker" env-default:"4"`
+	MaxWait        time.Duration `yaml:"max_wait" env-default:"2s"`
+}
+
+type MetricsConfig struct {
+	// Port 0 disables metrics server
+	Port int `yaml:"port"`
+}
+
+type AuthzConfig struct {
+	NamespacesPath string `yaml:"namespaces_path" env-default:"./config/namespaces.yaml"`
+	// MaxDepth bounds recursion of relation rewrites
+	MaxDepth int `yaml:"max_depth" env-default:"25"`
+}
+
+type InvitationConfig struct {
+	SigningKey string        `yaml:"signing_key" env:"INVITATION_SIGNING_KEY"`
+	TTL        time.Duration `yaml:"ttl" env-default:"168h"`
+}
+
+type AppsConfig struct {
+	// SecretGrace is how long tokens signed with rotated secret stay valid
+	SecretGrace time.Duration `yaml:"secret_grace" env-default:"24h"`
+}
+
+// SessionsConfig is default of session settings apps don't set
+type SessionsConfig struct {
+	// IdleTimeout ends session unused that long, 0 never ends it
+	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"0s"`
+	// MaxLifetime ends session that long after login, 0 never ends it
+	MaxLifetime time.Duration `yaml:"max_lifetime" env-default:"0s"`
+	// MaxSessions bounds active sessions of user in app, 0 doesn't bound them
+	MaxSessions int `yaml:"max_sessions" env-default:"0"`
+	// LimitPolicy is reject or evict_oldest, what happens to login over MaxSessions
+	LimitPolicy string `yaml:"limit_policy" env-default:"reject"`
+	// SweepInterval is how often ended sessions are purged, 0 disables purging
+	SweepInterval time.Duration `yaml:"sweep_interval" env-default:"5m"`
+	// SweepBatch is number of sessions deleted per statement
+	SweepBatch int `yaml:"sweep_batch" env-default:"1000"`
+}
+
+// GeoIPConfig of login location lookup in local MaxMind-format databases
+type GeoIPConfig struct {
+	// CityDB is path of city database, empty leaves country, city and coordinates unknown
+	CityDB string `yaml:"city_db"`
+	// ASNDB is path of ASN database, empty leaves autonomous system unknown
+	ASNDB string `yaml:"asn_db"`
+	// MaxTravelSpeed in km/h between logins, faster is impossible travel. 0 disables the check
+	MaxTravelSpeed float64 `yaml:"max_travel_speed" env-default:"1000"`
+	// MinTravelDistance in km is never impossible travel, GeoIP coordinates are that inaccurate
+	MinTravelDistance float64 `yaml:"min_travel_distance" env-default:"200"`
+	// ImpossibleTravel is flag to only record it or challenge to also count it into risk score
+	// and flag the login for step-up, its tokens get acr 0. It never refuses login by itself
+	ImpossibleTravel string `yaml:"impossible_travel" env-default:"flag"`
+}
+
+// RiskConfig is default of risk settings apps don't set, scores are 0 to 100
+type RiskConfig struct {
+	// StepUpScore is risk score from which login requires step-up, 0 never requires it.
+	// Such login isn't refused, its tokens get acr 0 for resource servers to ask for step-up
+	StepUpScore int `yaml:"step_up_score" env-default:"0"`
+	// DenyScore is risk score from which login is denied, 0 never denies it
+	DenyScore int `yaml:"deny_score" env-default:"0"`
+	// FailureWindow is how far back failed password attempts raise risk of login
+	FailureWindow time.Duration `yaml:"failure_window" env-default:"1h"`
+}
+
+type HistoryConfig struct {
+	// Retention is how long login attempts are kept, the sessions sweeper deletes older ones.
+	// 0 keeps them forever
+	Retention time.Duration `yaml:"retention" env-default:"2160h"`
+}
+
+type SecretsConfig struct {
+	// KeyFile holds master keys encrypting app secrets, empty stores them in plaintext
+	KeyFile string `yaml:"key_file" env:"APP_SECRETS_KEY_FILE"`
+	// AllowPlaintext accepts secrets stored before key_file was set. Keep it only until
+	// `secrets -action reseal` sealed them, otherwise a plaintext secret is refused
+	AllowPlaintext bool `yaml:"allow_plaintext" env:"APP_SECRETS_ALLOW_PLAINTEXT"`
+}
+
+// TokensConfig is default of token settings apps don't set
+type TokensConfig struct {
+	// Issuer is iss claim of tokens
+	Issuer string `yaml:"issuer" env-default:"domofon"`
+	// Format is one of jwt, v4.public, v4.local and opaque
+	Format string `yaml:"format" env-default:"jwt"`
+	// PasetoSeed is hex encoded Ed25519 seed signing v4.public tokens
+	PasetoSeed string `yaml:"paseto_seed" env:"PASETO_SEED"`
+	// OpaqueCacheTTL is how long revoked opaque token may still pass on other instances
+	OpaqueCacheTTL time.Duration `yaml:"opaque_cache_ttl" env-default:"30s"`
+	Claims         ClaimsConfig  `yaml:"claims"`
+}
+
+type ClaimsConfig struct {
+	// Version 2 switches tokens to RFC 9068 claims, 1 keeps uid and app claims
+	Version int  `yaml:"version" env-default:"1"`
+	Email   bool `yaml:"email" env-default:"true"`
+	Roles   bool `yaml:"roles" env-default:"true"`
+	Admin   bool `yaml:"admin" env-default:"false"`
+}
+
+type DPoPConfig struct {
+	// BaseURL followed by gRPC full method name is htu of proofs
+	BaseURL string `yaml:"base_url" env-default:"https://localhost"`
+	// NonceKey signs nonces, instances behind one address must share it
+	NonceKey     string        `yaml:"nonce_key" env:"DPOP_NONCE_KEY"`
+	NonceTTL     time.Duration `yaml:"nonce_ttl" env-default:"5m"`
+	ProofMaxAge  time.Duration `yaml:"proof_max_age" env-default:"5m"`
+	RequireNonce bool          `yaml:"require_nonce" env-default:"false"`
 }
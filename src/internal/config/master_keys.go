@@ -0,0 +1,27 @@
+package config
+
+import (
+	"github.com/ilyakaznacheev/cleanenv"
+	"os"
+)
+
+// MasterKeys encrypt app secrets at rest, new secrets are sealed with Primary key.
+// Keep retired keys until `secrets -action reseal` moved every secret off them
+type MasterKeys struct {
+	Primary string `yaml:"primary"`
+	// Keys are base64 encoded 256-bit keys by their ids
+	Keys map[string]string `yaml:"keys"`
+}
+
+func MustLoadMasterKeys(path string) *MasterKeys {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		panic("master key file didn't create: " + path)
+	}
+
+	var keys MasterKeys
+	if err := cleanenv.ReadConfig(path, &keys); err != nil {
+		panic("can't read master key file: " + path)
+	}
+
+	return &keys
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"github.com/ilyakaznacheev/cleanenv"
+	"os"
+)
+
+// Namespaces describes relations of every object type for authz checks
+type Namespaces struct {
+	Namespaces map[string]Namespace `yaml:"namespaces"`
+}
+
+type Namespace struct {
+	Relations map[string]Relation `yaml:"relations"`
+}
+
+// Relation is a union of rewrites, empty union means tuples stored for the relation itself
+type Relation struct {
+	Union []Rewrite `yaml:"union"`
+}
+
+// Rewrite sets exactly one of its fields
+type Rewrite struct {
+	// This includes subjects stored directly for the relation
+	This bool `yaml:"this"`
+	// ComputedUserset includes subjects of another relation of the same object
+	ComputedUserset string `yaml:"computed_userset"`
+	// TupleToUserset follows Tupleset relation to other objects
+	// and includes subjects of their ComputedUserset relation
+	TupleToUserset *TupleToUserset `yaml:"tuple_to_userset"`
+}
+
+type TupleToUserset struct {
+	Tupleset        string `yaml:"tupleset"`
+	ComputedUserset string `yaml:"computed_userset"`
+}
+
+func MustLoadNamespaces(path string) *Namespaces {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		panic("namespaces file didn't create: " + path)
+	}
+
+	var ns Namespaces
+	if err := cleanenv.ReadConfig(path, &ns); err != nil {
+		panic("can't read namespaces file: " + path)
+	}
+
+	return &ns
+}
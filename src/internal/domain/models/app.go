@@ -1,7 +1,144 @@
 package models
 
+import "time"
+
+const (
+	GrantPassword     = "password"
+	GrantRefreshToken = "refresh_token"
+)
+
+const (
+	TokenFormatJWT = "jwt"
+	// TokenFormatPasetoPublic is signed with service key, anyone with its public key can verify
+	TokenFormatPasetoPublic = "v4.public"
+	// TokenFormatPasetoLocal is encrypted with key of app, for internal apps only
+	TokenFormatPasetoLocal = "v4.local"
+	// TokenFormatOpaque is random reference to token state kept by the service
+	TokenFormatOpaque = "opaque"
+)
+
+const (
+	// ClaimsV1 is original format of access tokens with uid and app claims
+	ClaimsV1 = 1
+	// ClaimsV2 follows JWT access token profile of RFC 9068
+	ClaimsV2 = 2
+)
+
 type App struct {
 	Id     int32
+	OrgId  int32
 	Name   string
 	Secret string
+	// LoginPolicy is CEL expression deciding whether user may log in, empty allows everyone
+	LoginPolicy string
+	// ClaimsPolicy is CEL expression returning extra token claims
+	ClaimsPolicy string
+	// GroupsClaim adds names of user groups to tokens
+	GroupsClaim  bool
+	RedirectURIs []string
+	GrantTypes   []string
+	Tokens       TokenSettings
+	Sessions     SessionSettings
+	Risk         RiskSettings
+	// TLSClientAuth requires login over mTLS with matching certificate, nil allows any client
+	TLSClientAuth *TLSClientAuth
+	CreatedAt     time.Time
+}
+
+// AllowsGrant reports whether app may obtain tokens with grant type
+func (a App) AllowsGrant(grant string) bool {
+	for _, g := range a.GrantTypes {
+		if g == grant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AppUpdate changes only non-nil fields
+type AppUpdate struct {
+	Name         *string
+	RedirectURIs []string
+	GrantTypes   []string
+	Tokens       *TokenSettings
+	Sessions     *SessionSettings
+	Risk         *RiskSettings
+	// TLSClientAuth without subject and thumbprints turns client certificate auth off
+	TLSClientAuth *TLSClientAuth
+}
+
+// TLSClientAuth is client certificate of confidential app (RFC 8705).
+// Certificate matches by SubjectDN when it chains to trusted client CA,
+// otherwise by one of registered Thumbprints
+type TLSClientAuth struct {
+	SubjectDN string
+	// Thumbprints are x5t#S256 of self-signed certificates
+	Thumbprints []string
+}
+
+// IsZero reports whether no certificate can match
+func (t TLSClientAuth) IsZero() bool {
+	return t.SubjectDN == "" && len(t.Thumbprints) == 0
+}
+
+// TokenSettings of app override global defaults, zero values keep the default
+type TokenSettings struct {
+	// Format is one of TokenFormatJWT, TokenFormatPasetoPublic, TokenFormatPasetoLocal and TokenFormatOpaque
+	Format    string
+	AccessTTL time.Duration
+	Claims    ClaimSettings
+	// EncryptionKey is public JWK of app, jwt tokens are nested into JWE encrypted to it when set.
+	// Only the app reads such tokens, they can't call Domofon RPCs or be introspected
+	EncryptionKey string
+	// EncryptionAlg is key management algorithm, empty picks default of the key type
+	EncryptionAlg string
+	// RequireDPoP refuses login without DPoP proof, so every token is bound to client key
+	RequireDPoP bool
+	// Issuer is iss claim, it's global and always taken from defaults
+	Issuer string
+}
+
+// ClaimSettings select claims of access tokens
+type ClaimSettings struct {
+	// Version is format of claims, one of ClaimsV1 and ClaimsV2
+	Version int
+	Email   *bool
+	Roles   *bool
+	Admin   *bool
+	// Static claims are added to every token, they can't override the standard ones
+	Static map[string]any
+	// Audience is aud claim of tokens
+	Audience []string
+}
+
+// WithDefaults fills settings not set for app from defaults
+func (s TokenSettings) WithDefaults(defaults TokenSettings) TokenSettings {
+	if s.Format == "" {
+		s.Format = defaults.Format
+	}
+	if s.AccessTTL <= 0 {
+		s.AccessTTL = defaults.AccessTTL
+	}
+	if s.Claims.Version == 0 {
+		s.Claims.Version = defaults.Claims.Version
+	}
+	if s.Claims.Email == nil {
+		s.Claims.Email = defaults.Claims.Email
+	}
+	if s.Claims.Roles == nil {
+		s.Claims.Roles = defaults.Claims.Roles
+	}
+	if s.Claims.Admin == nil {
+		s.Claims.Admin = defaults.Claims.Admin
+	}
+	if s.Claims.Static == nil {
+		s.Claims.Static = defaults.Claims.Static
+	}
+	if s.Claims.Audience == nil {
+		s.Claims.Audience = defaults.Claims.Audience
+	}
+	s.Issuer = defaults.Issuer
+
+	return s
 }
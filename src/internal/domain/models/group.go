@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type Group struct {
+	Id        int32
+	OrgId     int32
+	Name      string
+	CreatedAt time.Time
+}
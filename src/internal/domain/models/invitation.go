@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Invitation struct {
+	Id         int64
+	OrgId      int32
+	Email      string
+	Roles      []InvitationRole
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// InvitationRole is assigned to invitee once invitation is accepted
+type InvitationRole struct {
+	AppId int32  `json:"app_id"`
+	Role  string `json:"role"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Reasons of failed login attempts
+const (
+	LoginUnknownUser        = "unknown_user"
+	LoginInvalidPassword    = "invalid_password"
+	LoginUserDisabled       = "user_disabled"
+	LoginGrantNotAllowed    = "grant_not_allowed"
+	LoginDPoPRequired       = "dpop_required"
+	LoginClientCertRequired = "client_cert_required"
+	LoginPolicyDenied       = "policy_denied"
+	LoginSessionLimit       = "session_limit"
+	LoginRiskDenied         = "risk_denied"
+)
+
+// LoginEvent is login attempt to app of organization
+type LoginEvent struct {
+	Id    int64
+	OrgId int32
+	AppId int32
+	// UserId is nil when email matched no user
+	UserId  *int64
+	Email   string
+	Success bool
+	// Reason is why attempt failed, empty for successful one
+	Reason    string
+	IP        string
+	UserAgent string
+	// Device is fingerprint of client device
+	Device string
+	// Network is address range of IP, /24 for IPv4 and /48 for IPv6
+	Network string
+	Geo     GeoLocation
+	// ImpossibleTravel is set when distance from previous login can't be covered in time since it
+	ImpossibleTravel bool
+	// RiskScore is 0 to 100, RiskSignals are what raised it. Zero for attempts failed before assessment
+	RiskScore   int
+	RiskSignals []string
+	// StepUp is set when risk score reached step-up score of app. Login isn't refused for it,
+	// its tokens get acr 0
+	StepUp    bool
+	CreatedAt time.Time
+}
+
+// GeoLocation of IP address, zero for unknown one
+type GeoLocation struct {
+	// Country is ISO 3166-1 alpha-2 code
+	Country   string
+	City      string
+	ASN       uint32
+	ASOrg     string
+	Latitude  float64
+	Longitude float64
+	// HasCoordinates tells zero coordinates from unknown ones
+	HasCoordinates bool
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DefaultOrgID is the organization created by migration for pre-existing users and apps
+const DefaultOrgID = 1
+
+type Organization struct {
+	Id        int32
+	Name      string
+	CreatedAt time.Time
+}
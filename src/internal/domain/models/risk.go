@@ -0,0 +1,45 @@
+package models
+
+// Risk signals of login, each raises its risk score
+const (
+	RiskFailedAttempts   = "failed_attempts"
+	RiskNewDevice        = "new_device"
+	RiskNewNetwork       = "new_network"
+	RiskNewCountry       = "new_country"
+	RiskImpossibleTravel = "impossible_travel"
+	RiskUnusualHour      = "unusual_hour"
+)
+
+// LoginFamiliarity tells how login compares to previous successful ones of user
+type LoginFamiliarity struct {
+	// RecentFailures is number of failed attempts of user from the same IP or device in the recent window
+	RecentFailures int
+	// FirstLogin is set for user who never logged in, nothing is unfamiliar to such user
+	FirstLogin   bool
+	KnownDevice  bool
+	KnownNetwork bool
+	KnownCountry bool
+	// KnownHour is set when user logged in within an hour of this time of day before
+	KnownHour bool
+}
+
+// RiskSettings of app override global defaults, nil fields keep the default,
+// so app can set 0 to turn off threshold of the defaults
+type RiskSettings struct {
+	// StepUpScore is risk score from which login requires step-up, its tokens get acr 0. 0 never requires it
+	StepUpScore *int
+	// DenyScore is risk score from which login is denied, 0 never denies it
+	DenyScore *int
+}
+
+// WithDefaults fills settings not set for app from defaults
+func (s RiskSettings) WithDefaults(defaults RiskSettings) RiskSettings {
+	if s.StepUpScore == nil {
+		s.StepUpScore = defaults.StepUpScore
+	}
+	if s.DenyScore == nil {
+		s.DenyScore = defaults.DenyScore
+	}
+
+	return s
+}
@@ -0,0 +1,8 @@
+package models
+
+type Role struct {
+	Id          int32
+	AppId       int32
+	Name        string
+	Permissions []string
+}
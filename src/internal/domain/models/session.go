@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Session is sign-in of user to app, tokens issued on it carry its id as sid claim
+type Session struct {
+	Id         string
+	OrgId      int32
+	AppId      int32
+	UserId     int64
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// IdleTimeout ends session unused that long, 0 never ends it
+	IdleTimeout time.Duration
+	// ExpiresAt is end of session whatever its use, nil for session without one
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+// Active reports whether tokens of session are still accepted at now
+func (s Session) Active(now time.Time) bool {
+	if s.RevokedAt != nil {
+		return false
+	}
+	if s.ExpiresAt != nil && !now.Before(*s.ExpiresAt) {
+		return false
+	}
+	if s.IdleTimeout > 0 && !now.Before(s.LastSeenAt.Add(s.IdleTimeout)) {
+		return false
+	}
+
+	return true
+}
+
+const (
+	// SessionLimitReject refuses login over session limit
+	SessionLimitReject = "reject"
+	// SessionLimitEvictOldest revokes the oldest sessions to make room for the new one
+	SessionLimitEvictOldest = "evict_oldest"
+)
+
+// SessionSettings of app override global defaults, nil fields keep the default,
+// so app can set 0 to turn off limit of the defaults
+type SessionSettings struct {
+	// IdleTimeout ends session unused that long, 0 never ends it
+	IdleTimeout *time.Duration
+	// MaxLifetime ends session that long after login, 0 never ends it
+	MaxLifetime *time.Duration
+	// MaxSessions bounds active sessions of user in app, 0 doesn't bound them
+	MaxSessions *int
+	// LimitPolicy is one of SessionLimitReject and SessionLimitEvictOldest
+	LimitPolicy string
+}
+
+// WithDefaults fills settings not set for app from defaults
+func (s SessionSettings) WithDefaults(defaults SessionSettings) SessionSettings {
+	if s.IdleTimeout == nil {
+		s.IdleTimeout = defaults.IdleTimeout
+	}
+	if s.MaxLifetime == nil {
+		s.MaxLifetime = defaults.MaxLifetime
+	}
+	if s.MaxSessions == nil {
+		s.MaxSessions = defaults.MaxSessions
+	}
+	if s.LimitPolicy == "" {
+		s.LimitPolicy = defaults.LimitPolicy
+	}
+
+	return s
+}
+
+// Value returns setting, zero when neither app nor defaults set it
+func Value[T any](setting *T) T {
+	var res T
+	if setting != nil {
+		res = *setting
+	}
+
+	return res
+}
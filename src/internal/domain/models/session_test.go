@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSessionSettings_WithDefaults(t *testing.T) {
+	hour, zero := time.Hour, time.Duration(0)
+	five, none := 5, 0
+	defaults := SessionSettings{
+		IdleTimeout: &hour,
+		MaxLifetime: &hour,
+		MaxSessions: &five,
+		LimitPolicy: SessionLimitReject,
+	}
+
+	got := SessionSettings{IdleTimeout: &zero, MaxSessions: &none}.WithDefaults(defaults)
+
+	assert.Equal(t, time.Duration(0), Value(got.IdleTimeout), "explicit zero turns idle timeout off")
+	assert.Equal(t, time.Hour, Value(got.MaxLifetime))
+	assert.Equal(t, 0, Value(got.MaxSessions), "explicit zero turns session limit off")
+	assert.Equal(t, SessionLimitReject, got.LimitPolicy)
+}
+
+func TestRiskSettings_WithDefaults(t *testing.T) {
+	step, deny, none := 40, 80, 0
+	defaults := RiskSettings{StepUpScore: &step, DenyScore: &deny}
+
+	got := RiskSettings{DenyScore: &none}.WithDefaults(defaults)
+
+	assert.Equal(t, 40, Value(got.StepUpScore))
+	assert.Equal(t, 0, Value(got.DenyScore), "explicit zero never denies")
+	assert.Zero(t, Value[int](nil))
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	// ACRStepUpRequired is acr of login its risk asked to step up from,
+	// it meets no assurance level like acr 0 of OpenID Connect
+	ACRStepUpRequired = "0"
+	// ACRPassword is acr of login by password alone
+	ACRPassword = "1"
+	// AMRPassword is amr of login by password (RFC 8176)
+	AMRPassword = "pwd"
+)
+
+// Authentication is how user logged in, tokens issued for the login carry it in sid, acr and amr claims
+type Authentication struct {
+	// SessionID is empty for tokens issued outside of a session
+	SessionID string
+	ACR       string
+	AMR       []string
+}
+
+// AccessToken is server-side state of opaque access token
+type AccessToken struct {
+	Id     int64
+	OrgId  int32
+	AppId  int32
+	UserId int64
+	// Claims are what a self-contained token of the app would carry
+	Claims    map[string]any
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// Active reports whether token can still be used at now
+func (t AccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
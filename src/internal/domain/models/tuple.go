@@ -0,0 +1,38 @@
+package models
+
+// Subject is either a user ("user:12") or a userset ("building:7#resident")
+type Subject struct {
+	Namespace string
+	Id        string
+	Relation  string
+}
+
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Namespace + ":" + s.Id
+	}
+
+	return s.Namespace + ":" + s.Id + "#" + s.Relation
+}
+
+// RelationTuple is object#relation@subject, e.g. "door:3#opener@user:12"
+type RelationTuple struct {
+	Namespace string
+	ObjectId  string
+	Relation  string
+	Subject   Subject
+}
+
+func (t RelationTuple) String() string {
+	return t.Namespace + ":" + t.ObjectId + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// UsersetTree is the result of expanding object#relation
+type UsersetTree struct {
+	// Operation is one of "union", "this", "computed_userset", "tuple_to_userset"
+	Operation string
+	Userset   Subject
+	// Subjects are direct subjects, filled for "this" leaves
+	Subjects []Subject
+	Children []UsersetTree
+}
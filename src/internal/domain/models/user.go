@@ -1,8 +1,30 @@
 package models
 
+import "time"
+
 type User struct {
-	Id       int64
-	Email    string
-	PassHash []byte
-	IsAdmin  bool
+	Id            int64
+	OrgId         int32
+	Email         string
+	PassHash      []byte
+	IsAdmin       bool
+	EmailVerified bool
+	Profile       map[string]any
+	CreatedAt     time.Time
+	DisabledAt    *time.Time
+}
+
+// UserFilter narrows user listing, zero fields don't filter
+type UserFilter struct {
+	EmailPrefix   string
+	IsAdmin       *bool
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// UserUpdate changes only non-nil fields
+type UserUpdate struct {
+	Email         *string
+	EmailVerified *bool
+	Profile       map[string]any
 }
@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/admin"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+var Access = interceptors.Methods{
+	api.AdminService: interceptors.AdminOnly,
+}
+
+type Admin interface {
+	ListUsers(
+		ctx context.Context,
+		orgID int,
+		filter models.UserFilter,
+		cursor string,
+		pageSize int,
+	) ([]models.User, string, error)
+	GetUser(ctx context.Context, orgID int, userID int64) (models.User, error)
+	UpdateUser(ctx context.Context, orgID int, userID int64, upd models.UserUpdate) error
+	DisableUser(ctx context.Context, orgID int, adminID int64, userID int64, disabled bool) error
+	SetAdmin(ctx context.Context, orgID int, adminID int64, userID int64, isAdmin bool) error
+	DeleteUser(ctx context.Context, orgID int, adminID int64, userID int64) error
+}
+
+type handler struct {
+	admin Admin
+}
+
+func Register(grpcSrv *grpc.Server, admin Admin) {
+	api.RegisterAdminServer(grpcSrv, &handler{admin: admin})
+}
+
+func (h handler) ListUsers(ctx context.Context, request *api.ListUsersRequest) (*api.ListUsersResponse, error) {
+	filter := models.UserFilter{
+		EmailPrefix: request.EmailPrefix,
+		IsAdmin:     request.IsAdmin,
+	}
+	if request.CreatedAfter != nil {
+		filter.CreatedAfter = *request.CreatedAfter
+	}
+	if request.CreatedBefore != nil {
+		filter.CreatedBefore = *request.CreatedBefore
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	users, next, err := h.admin.ListUsers(ctx, int(caller.OrgID), filter, request.Cursor, int(request.PageSize))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListUsersResponse{Users: make([]api.User, 0, len(users)), NextCursor: next}
+	for _, user := range users {
+		res.Users = append(res.Users, userResponse(user))
+	}
+
+	return res, nil
+}
+
+func (h handler) GetUser(ctx context.Context, request *api.GetUserRequest) (*api.User, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	user, err := h.admin.GetUser(ctx, int(caller.OrgID), request.UserId)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := userResponse(user)
+
+	return &res, nil
+}
+
+func (h handler) UpdateUser(ctx context.Context, request *api.UpdateUserRequest) (*api.Empty, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if request.Email != nil && *request.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty email")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	err := h.admin.UpdateUser(ctx, int(caller.OrgID), request.UserId, models.UserUpdate{
+		Email:         request.Email,
+		EmailVerified: request.EmailVerified,
+		Profile:       request.Profile,
+	})
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) DisableUser(ctx context.Context, request *api.DisableUserRequest) (*api.Empty, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	err := h.admin.DisableUser(ctx, int(caller.OrgID), caller.UserID, request.UserId, request.Disabled)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) SetAdmin(ctx context.Context, request *api.SetAdminRequest) (*api.Empty, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	err := h.admin.SetAdmin(ctx, int(caller.OrgID), caller.UserID, request.UserId, request.IsAdmin)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) DeleteUser(ctx context.Context, request *api.DeleteUserRequest) (*api.Empty, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	if err := h.admin.DeleteUser(ctx, int(caller.OrgID), caller.UserID, request.UserId); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func userResponse(user models.User) api.User {
+	return api.User{
+		Id:            user.Id,
+		Email:         user.Email,
+		IsAdmin:       user.IsAdmin,
+		EmailVerified: user.EmailVerified,
+		Profile:       user.Profile,
+		CreatedAt:     user.CreatedAt,
+		DisabledAt:    user.DisabledAt,
+	}
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, admin.ErrUserNotFound):
+		res = status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, admin.ErrUserExists):
+		res = status.Error(codes.AlreadyExists, "user already exists")
+	case errors.Is(err, admin.ErrInvalidCursor):
+		res = status.Error(codes.InvalidArgument, "invalid cursor")
+	case errors.Is(err, admin.ErrSelfLockout):
+		res = status.Error(codes.FailedPrecondition, "admin can't disable, demote or delete themselves")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
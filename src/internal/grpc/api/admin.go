@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const AdminService = "domofon.Admin"
+
+type User struct {
+	Id            int64          `json:"id"`
+	Email         string         `json:"email"`
+	IsAdmin       bool           `json:"is_admin"`
+	EmailVerified bool           `json:"email_verified"`
+	Profile       map[string]any `json:"profile,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	DisabledAt    *time.Time     `json:"disabled_at,omitempty"`
+}
+
+// ListUsersRequest filters by non-empty fields
+type ListUsersRequest struct {
+	EmailPrefix   string     `json:"email_prefix,omitempty"`
+	IsAdmin       *bool      `json:"is_admin,omitempty"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	Cursor        string     `json:"cursor,omitempty"`
+	PageSize      int32      `json:"page_size,omitempty"`
+}
+
+type ListUsersResponse struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type GetUserRequest struct {
+	UserId int64 `json:"user_id"`
+}
+
+// UpdateUserRequest changes only fields that are set
+type UpdateUserRequest struct {
+	UserId        int64          `json:"user_id"`
+	Email         *string        `json:"email,omitempty"`
+	EmailVerified *bool          `json:"email_verified,omitempty"`
+	Profile       map[string]any `json:"profile,omitempty"`
+}
+
+type DisableUserRequest struct {
+	UserId   int64 `json:"user_id"`
+	Disabled bool  `json:"disabled"`
+}
+
+type SetAdminRequest struct {
+	UserId  int64 `json:"user_id"`
+	IsAdmin bool  `json:"is_admin"`
+}
+
+type DeleteUserRequest struct {
+	UserId int64 `json:"user_id"`
+}
+
+type AdminServer interface {
+	ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error)
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	UpdateUser(context.Context, *UpdateUserRequest) (*Empty, error)
+	DisableUser(context.Context, *DisableUserRequest) (*Empty, error)
+	SetAdmin(context.Context, *SetAdminRequest) (*Empty, error)
+	DeleteUser(context.Context, *DeleteUserRequest) (*Empty, error)
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AdminService,
+		HandlerType: (*AdminServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(AdminService, "ListUsers", AdminServer.ListUsers),
+			method(AdminService, "GetUser", AdminServer.GetUser),
+			method(AdminService, "UpdateUser", AdminServer.UpdateUser),
+			method(AdminService, "DisableUser", AdminServer.DisableUser),
+			method(AdminService, "SetAdmin", AdminServer.SetAdmin),
+			method(AdminService, "DeleteUser", AdminServer.DeleteUser),
+		},
+	}, srv)
+}
+
+type AdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) *AdminClient {
+	return &AdminClient{cc: cc}
+}
+
+func (c *AdminClient) ListUsers(
+	ctx context.Context,
+	in *ListUsersRequest,
+	opts ...grpc.CallOption,
+) (*ListUsersResponse, error) {
+	return invoke[ListUsersResponse](ctx, c.cc, "/"+AdminService+"/ListUsers", in, opts)
+}
+
+func (c *AdminClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	return invoke[User](ctx, c.cc, "/"+AdminService+"/GetUser", in, opts)
+}
+
+func (c *AdminClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+AdminService+"/UpdateUser", in, opts)
+}
+
+func (c *AdminClient) DisableUser(ctx context.Context, in *DisableUserRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+AdminService+"/DisableUser", in, opts)
+}
+
+func (c *AdminClient) SetAdmin(ctx context.Context, in *SetAdminRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+AdminService+"/SetAdmin", in, opts)
+}
+
+func (c *AdminClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+AdminService+"/DeleteUser", in, opts)
+}
@@ -0,0 +1,114 @@
+// Package api describes gRPC services of Domofon that aren't in domofon-proto yet.
+// Messages are plain structs sent with the json codec. The codec isn't registered globally,
+// clients force it per call and the server gets ServerCodec
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	protoencoding "google.golang.org/grpc/encoding/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec is content subtype the services are called with
+const Codec = "json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return Codec
+}
+
+// ServerCodec returns codec of server serving domofon-proto services next to these ones,
+// proto messages go through the proto codec and plain structs through json
+func ServerCodec() encoding.Codec {
+	return serverCodec{proto: encoding.GetCodec(protoencoding.Name)}
+}
+
+type serverCodec struct {
+	proto encoding.Codec
+}
+
+func (c serverCodec) Marshal(v any) ([]byte, error) {
+	if _, ok := v.(proto.Message); ok {
+		return c.proto.Marshal(v)
+	}
+
+	return jsonCodec{}.Marshal(v)
+}
+
+func (c serverCodec) Unmarshal(data []byte, v any) error {
+	if _, ok := v.(proto.Message); ok {
+		return c.proto.Unmarshal(data, v)
+	}
+
+	return jsonCodec{}.Unmarshal(data, v)
+}
+
+func (c serverCodec) Name() string {
+	return c.proto.Name()
+}
+
+// Empty is request or response without fields
+type Empty struct{}
+
+// method describes unary method served by call of server S
+func method[S any, Req any, Resp any](
+	service string,
+	name string,
+	call func(S, context.Context, *Req) (*Resp, error),
+) grpc.MethodDesc {
+	fullMethod := "/" + service + "/" + name
+
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(
+			srv any,
+			ctx context.Context,
+			dec func(any) error,
+			interceptor grpc.UnaryServerInterceptor,
+		) (any, error) {
+			in := new(Req)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(S), ctx, in)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return call(srv.(S), ctx, req.(*Req))
+			}
+
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+// invoke calls unary method with the json codec
+func invoke[Resp any](
+	ctx context.Context,
+	cc grpc.ClientConnInterface,
+	fullMethod string,
+	in any,
+	opts []grpc.CallOption,
+) (*Resp, error) {
+	out := new(Resp)
+	opts = append([]grpc.CallOption{grpc.ForceCodec(jsonCodec{})}, opts...)
+	if err := cc.Invoke(ctx, fullMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net"
+	"testing"
+)
+
+type fakeRBAC struct {
+	RBACServer
+}
+
+func (fakeRBAC) CheckPermission(_ context.Context, in *CheckPermissionRequest) (*CheckPermissionResponse, error) {
+	if in.Permission == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty permission")
+	}
+
+	return &CheckPermissionResponse{Allowed: in.UserId == 7 && in.AppId == 1}, nil
+}
+
+func TestClient_roundTrip(t *testing.T) {
+	l := bufconn.Listen(1 << 20)
+
+	var methods []string
+	srv := grpc.NewServer(grpc.ForceServerCodec(ServerCodec()), grpc.UnaryInterceptor(func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		methods = append(methods, info.FullMethod)
+		return handler(ctx, req)
+	}))
+	RegisterRBACServer(srv, fakeRBAC{})
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	cc, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer cc.Close()
+
+	client := NewRBACClient(cc)
+
+	tests := []struct {
+		name     string
+		in       *CheckPermissionRequest
+		want     bool
+		wantCode codes.Code
+	}{
+		{name: "allowed", in: &CheckPermissionRequest{UserId: 7, AppId: 1, Permission: "door:open"}, want: true},
+		{name: "denied", in: &CheckPermissionRequest{UserId: 8, AppId: 1, Permission: "door:open"}},
+		{name: "status error", in: &CheckPermissionRequest{UserId: 7, AppId: 1}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.CheckPermission(context.Background(), tt.in)
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.Allowed)
+		})
+	}
+
+	assert.Equal(t, "/domofon.RBAC/CheckPermission", methods[0])
+}
+
+func TestServerCodec(t *testing.T) {
+	// other gRPC clients and servers of the process keep their codecs
+	assert.Nil(t, encoding.GetCodec(Codec))
+
+	codec := ServerCodec()
+
+	raw, err := codec.Marshal(wrapperspb.String("door"))
+	require.NoError(t, err)
+	msg := &wrapperspb.StringValue{}
+	require.NoError(t, proto.Unmarshal(raw, msg))
+	assert.Equal(t, "door", msg.GetValue())
+
+	raw, err = codec.Marshal(&CheckPermissionRequest{UserId: 7, Permission: "door:open"})
+	require.NoError(t, err)
+	assert.True(t, json.Valid(raw))
+
+	var in CheckPermissionRequest
+	require.NoError(t, codec.Unmarshal(raw, &in))
+	assert.Equal(t, CheckPermissionRequest{UserId: 7, Permission: "door:open"}, in)
+}
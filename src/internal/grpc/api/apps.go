@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const AppsService = "domofon.Apps"
+
+// TokenSettings of app, durations are Go duration strings like "15m", empty keeps the default
+type TokenSettings struct {
+	Format        string        `json:"format,omitempty"`
+	AccessTTL     string        `json:"access_ttl,omitempty"`
+	Claims        ClaimSettings `json:"claims"`
+	EncryptionKey string        `json:"encryption_key,omitempty"`
+	EncryptionAlg string        `json:"encryption_alg,omitempty"`
+	RequireDPoP   bool          `json:"require_dpop,omitempty"`
+}
+
+type ClaimSettings struct {
+	Version  int            `json:"version,omitempty"`
+	Email    *bool          `json:"email,omitempty"`
+	Roles    *bool          `json:"roles,omitempty"`
+	Admin    *bool          `json:"admin,omitempty"`
+	Static   map[string]any `json:"static,omitempty"`
+	Audience []string       `json:"audience,omitempty"`
+}
+
+// SessionSettings of app, durations are Go duration strings. Empty or absent fields
+// keep the default, "0s" and 0 turn the limit off
+type SessionSettings struct {
+	IdleTimeout string `json:"idle_timeout,omitempty"`
+	MaxLifetime string `json:"max_lifetime,omitempty"`
+	MaxSessions *int   `json:"max_sessions,omitempty"`
+	LimitPolicy string `json:"limit_policy,omitempty"`
+}
+
+// RiskSettings of app, absent scores keep the default, 0 turns the threshold off
+type RiskSettings struct {
+	StepUpScore *int `json:"step_up_score,omitempty"`
+	DenyScore   *int `json:"deny_score,omitempty"`
+}
+
+type TLSClientAuth struct {
+	SubjectDN   string   `json:"subject_dn,omitempty"`
+	Thumbprints []string `json:"thumbprints,omitempty"`
+}
+
+type App struct {
+	Id            int32           `json:"id"`
+	Name          string          `json:"name"`
+	RedirectURIs  []string        `json:"redirect_uris"`
+	GrantTypes    []string        `json:"grant_types"`
+	Tokens        TokenSettings   `json:"tokens"`
+	Sessions      SessionSettings `json:"sessions"`
+	Risk          RiskSettings    `json:"risk"`
+	TLSClientAuth *TLSClientAuth  `json:"tls_client_auth,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type CreateAppRequest struct {
+	Name          string           `json:"name"`
+	RedirectURIs  []string         `json:"redirect_uris,omitempty"`
+	GrantTypes    []string         `json:"grant_types,omitempty"`
+	Tokens        *TokenSettings   `json:"tokens,omitempty"`
+	Sessions      *SessionSettings `json:"sessions,omitempty"`
+	Risk          *RiskSettings    `json:"risk,omitempty"`
+	TLSClientAuth *TLSClientAuth   `json:"tls_client_auth,omitempty"`
+}
+
+// CreateAppResponse is the only response carrying secret of app
+type CreateAppResponse struct {
+	App    App    `json:"app"`
+	Secret string `json:"secret"`
+}
+
+type GetAppRequest struct {
+	AppId int32 `json:"app_id"`
+}
+
+type ListAppsResponse struct {
+	Apps []App `json:"apps"`
+}
+
+// UpdateAppRequest changes only fields that are set, empty list clears redirect uris
+type UpdateAppRequest struct {
+	AppId         int32            `json:"app_id"`
+	Name          *string          `json:"name,omitempty"`
+	RedirectURIs  []string         `json:"redirect_uris"`
+	GrantTypes    []string         `json:"grant_types"`
+	Tokens        *TokenSettings   `json:"tokens,omitempty"`
+	Sessions      *SessionSettings `json:"sessions,omitempty"`
+	Risk          *RiskSettings    `json:"risk,omitempty"`
+	TLSClientAuth *TLSClientAuth   `json:"tls_client_auth,omitempty"`
+}
+
+type DeleteAppRequest struct {
+	AppId int32 `json:"app_id"`
+}
+
+type RotateAppSecretRequest struct {
+	AppId int32 `json:"app_id"`
+}
+
+// RotateAppSecretResponse is the only response carrying the new secret
+type RotateAppSecretResponse struct {
+	Secret string `json:"secret"`
+}
+
+type AppsServer interface {
+	CreateApp(context.Context, *CreateAppRequest) (*CreateAppResponse, error)
+	GetApp(context.Context, *GetAppRequest) (*App, error)
+	ListApps(context.Context, *Empty) (*ListAppsResponse, error)
+	UpdateApp(context.Context, *UpdateAppRequest) (*Empty, error)
+	DeleteApp(context.Context, *DeleteAppRequest) (*Empty, error)
+	RotateAppSecret(context.Context, *RotateAppSecretRequest) (*RotateAppSecretResponse, error)
+}
+
+func RegisterAppsServer(s grpc.ServiceRegistrar, srv AppsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AppsService,
+		HandlerType: (*AppsServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(AppsService, "CreateApp", AppsServer.CreateApp),
+			method(AppsService, "GetApp", AppsServer.GetApp),
+			method(AppsService, "ListApps", AppsServer.ListApps),
+			method(AppsService, "UpdateApp", AppsServer.UpdateApp),
+			method(AppsService, "DeleteApp", AppsServer.DeleteApp),
+			method(AppsService, "RotateAppSecret", AppsServer.RotateAppSecret),
+		},
+	}, srv)
+}
+
+type AppsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAppsClient(cc grpc.ClientConnInterface) *AppsClient {
+	return &AppsClient{cc: cc}
+}
+
+func (c *AppsClient) CreateApp(
+	ctx context.Context,
+	in *CreateAppRequest,
+	opts ...grpc.CallOption,
+) (*CreateAppResponse, error) {
+	return invoke[CreateAppResponse](ctx, c.cc, "/"+AppsService+"/CreateApp", in, opts)
+}
+
+func (c *AppsClient) GetApp(ctx context.Context, in *GetAppRequest, opts ...grpc.CallOption) (*App, error) {
+	return invoke[App](ctx, c.cc, "/"+AppsService+"/GetApp", in, opts)
+}
+
+func (c *AppsClient) ListApps(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListAppsResponse, error) {
+	return invoke[ListAppsResponse](ctx, c.cc, "/"+AppsService+"/ListApps", in, opts)
+}
+
+func (c *AppsClient) UpdateApp(ctx context.Context, in *UpdateAppRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+AppsService+"/UpdateApp", in, opts)
+}
+
+func (c *AppsClient) DeleteApp(ctx context.Context, in *DeleteAppRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+AppsService+"/DeleteApp", in, opts)
+}
+
+func (c *AppsClient) RotateAppSecret(
+	ctx context.Context,
+	in *RotateAppSecretRequest,
+	opts ...grpc.CallOption,
+) (*RotateAppSecretResponse, error) {
+	return invoke[RotateAppSecretResponse](ctx, c.cc, "/"+AppsService+"/RotateAppSecret", in, opts)
+}
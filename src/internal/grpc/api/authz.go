@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+const AuthzService = "domofon.Authz"
+
+// WriteTuplesRequest carries tuples as "namespace:object#relation@subject"
+type WriteTuplesRequest struct {
+	Inserts []string `json:"inserts"`
+	Deletes []string `json:"deletes"`
+}
+
+type WriteTuplesResponse struct {
+	Token string `json:"token"`
+}
+
+type CheckRequest struct {
+	Tuple string `json:"tuple"`
+	Token string `json:"token"`
+}
+
+type CheckResponse struct {
+	Allowed bool   `json:"allowed"`
+	Token   string `json:"token"`
+}
+
+// ExpandRequest carries userset as "namespace:object#relation"
+type ExpandRequest struct {
+	Userset string `json:"userset"`
+	Token   string `json:"token"`
+}
+
+type UsersetTree struct {
+	Operation string        `json:"operation"`
+	Userset   string        `json:"userset"`
+	Subjects  []string      `json:"subjects,omitempty"`
+	Children  []UsersetTree `json:"children,omitempty"`
+}
+
+type ExpandResponse struct {
+	Tree  UsersetTree `json:"tree"`
+	Token string      `json:"token"`
+}
+
+type ListObjectsRequest struct {
+	Namespace string `json:"namespace"`
+	Relation  string `json:"relation"`
+	Subject   string `json:"subject"`
+	Token     string `json:"token"`
+	Cursor    string `json:"cursor"`
+	PageSize  int32  `json:"page_size"`
+}
+
+type ListObjectsResponse struct {
+	ObjectIds  []string `json:"object_ids"`
+	NextCursor string   `json:"next_cursor"`
+	Token      string   `json:"token"`
+}
+
+type AuthzServer interface {
+	WriteTuples(context.Context, *WriteTuplesRequest) (*WriteTuplesResponse, error)
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+	Expand(context.Context, *ExpandRequest) (*ExpandResponse, error)
+	ListObjects(context.Context, *ListObjectsRequest) (*ListObjectsResponse, error)
+}
+
+func RegisterAuthzServer(s grpc.ServiceRegistrar, srv AuthzServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AuthzService,
+		HandlerType: (*AuthzServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(AuthzService, "WriteTuples", AuthzServer.WriteTuples),
+			method(AuthzService, "Check", AuthzServer.Check),
+			method(AuthzService, "Expand", AuthzServer.Expand),
+			method(AuthzService, "ListObjects", AuthzServer.ListObjects),
+		},
+	}, srv)
+}
+
+type AuthzClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthzClient(cc grpc.ClientConnInterface) *AuthzClient {
+	return &AuthzClient{cc: cc}
+}
+
+func (c *AuthzClient) WriteTuples(
+	ctx context.Context,
+	in *WriteTuplesRequest,
+	opts ...grpc.CallOption,
+) (*WriteTuplesResponse, error) {
+	return invoke[WriteTuplesResponse](ctx, c.cc, "/"+AuthzService+"/WriteTuples", in, opts)
+}
+
+func (c *AuthzClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	return invoke[CheckResponse](ctx, c.cc, "/"+AuthzService+"/Check", in, opts)
+}
+
+func (c *AuthzClient) Expand(ctx context.Context, in *ExpandRequest, opts ...grpc.CallOption) (*ExpandResponse, error) {
+	return invoke[ExpandResponse](ctx, c.cc, "/"+AuthzService+"/Expand", in, opts)
+}
+
+func (c *AuthzClient) ListObjects(
+	ctx context.Context,
+	in *ListObjectsRequest,
+	opts ...grpc.CallOption,
+) (*ListObjectsResponse, error) {
+	return invoke[ListObjectsResponse](ctx, c.cc, "/"+AuthzService+"/ListObjects", in, opts)
+}
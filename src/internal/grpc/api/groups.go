@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const GroupsService = "domofon.Groups"
+
+type Group struct {
+	Id        int32     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateGroupResponse struct {
+	Id int32 `json:"id"`
+}
+
+type DeleteGroupRequest struct {
+	GroupId int32 `json:"group_id"`
+}
+
+type ListGroupsResponse struct {
+	Groups []Group `json:"groups"`
+}
+
+type GroupUserRequest struct {
+	GroupId int32 `json:"group_id"`
+	UserId  int64 `json:"user_id"`
+}
+
+type SubgroupRequest struct {
+	ParentId int32 `json:"parent_id"`
+	ChildId  int32 `json:"child_id"`
+}
+
+type UserGroupsRequest struct {
+	UserId int64 `json:"user_id"`
+}
+
+type GroupUsersRequest struct {
+	GroupId int32 `json:"group_id"`
+}
+
+type GroupUsersResponse struct {
+	UserIds []int64 `json:"user_ids"`
+}
+
+type GroupRoleRequest struct {
+	GroupId int32  `json:"group_id"`
+	AppId   int32  `json:"app_id"`
+	Role    string `json:"role"`
+}
+
+type GroupsServer interface {
+	CreateGroup(context.Context, *CreateGroupRequest) (*CreateGroupResponse, error)
+	DeleteGroup(context.Context, *DeleteGroupRequest) (*Empty, error)
+	ListGroups(context.Context, *Empty) (*ListGroupsResponse, error)
+	AddUser(context.Context, *GroupUserRequest) (*Empty, error)
+	RemoveUser(context.Context, *GroupUserRequest) (*Empty, error)
+	AddSubgroup(context.Context, *SubgroupRequest) (*Empty, error)
+	RemoveSubgroup(context.Context, *SubgroupRequest) (*Empty, error)
+	UserGroups(context.Context, *UserGroupsRequest) (*ListGroupsResponse, error)
+	GroupUsers(context.Context, *GroupUsersRequest) (*GroupUsersResponse, error)
+	AssignRole(context.Context, *GroupRoleRequest) (*Empty, error)
+	RevokeRole(context.Context, *GroupRoleRequest) (*Empty, error)
+}
+
+func RegisterGroupsServer(s grpc.ServiceRegistrar, srv GroupsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: GroupsService,
+		HandlerType: (*GroupsServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(GroupsService, "CreateGroup", GroupsServer.CreateGroup),
+			method(GroupsService, "DeleteGroup", GroupsServer.DeleteGroup),
+			method(GroupsService, "ListGroups", GroupsServer.ListGroups),
+			method(GroupsService, "AddUser", GroupsServer.AddUser),
+			method(GroupsService, "RemoveUser", GroupsServer.RemoveUser),
+			method(GroupsService, "AddSubgroup", GroupsServer.AddSubgroup),
+			method(GroupsService, "RemoveSubgroup", GroupsServer.RemoveSubgroup),
+			method(GroupsService, "UserGroups", GroupsServer.UserGroups),
+			method(GroupsService, "GroupUsers", GroupsServer.GroupUsers),
+			method(GroupsService, "AssignRole", GroupsServer.AssignRole),
+			method(GroupsService, "RevokeRole", GroupsServer.RevokeRole),
+		},
+	}, srv)
+}
+
+type GroupsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGroupsClient(cc grpc.ClientConnInterface) *GroupsClient {
+	return &GroupsClient{cc: cc}
+}
+
+func (c *GroupsClient) CreateGroup(
+	ctx context.Context,
+	in *CreateGroupRequest,
+	opts ...grpc.CallOption,
+) (*CreateGroupResponse, error) {
+	return invoke[CreateGroupResponse](ctx, c.cc, "/"+GroupsService+"/CreateGroup", in, opts)
+}
+
+func (c *GroupsClient) DeleteGroup(ctx context.Context, in *DeleteGroupRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/DeleteGroup", in, opts)
+}
+
+func (c *GroupsClient) ListGroups(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListGroupsResponse, error) {
+	return invoke[ListGroupsResponse](ctx, c.cc, "/"+GroupsService+"/ListGroups", in, opts)
+}
+
+func (c *GroupsClient) AddUser(ctx context.Context, in *GroupUserRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/AddUser", in, opts)
+}
+
+func (c *GroupsClient) RemoveUser(ctx context.Context, in *GroupUserRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/RemoveUser", in, opts)
+}
+
+func (c *GroupsClient) AddSubgroup(ctx context.Context, in *SubgroupRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/AddSubgroup", in, opts)
+}
+
+func (c *GroupsClient) RemoveSubgroup(ctx context.Context, in *SubgroupRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/RemoveSubgroup", in, opts)
+}
+
+func (c *GroupsClient) UserGroups(
+	ctx context.Context,
+	in *UserGroupsRequest,
+	opts ...grpc.CallOption,
+) (*ListGroupsResponse, error) {
+	return invoke[ListGroupsResponse](ctx, c.cc, "/"+GroupsService+"/UserGroups", in, opts)
+}
+
+func (c *GroupsClient) GroupUsers(
+	ctx context.Context,
+	in *GroupUsersRequest,
+	opts ...grpc.CallOption,
+) (*GroupUsersResponse, error) {
+	return invoke[GroupUsersResponse](ctx, c.cc, "/"+GroupsService+"/GroupUsers", in, opts)
+}
+
+func (c *GroupsClient) AssignRole(ctx context.Context, in *GroupRoleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/AssignRole", in, opts)
+}
+
+func (c *GroupsClient) RevokeRole(ctx context.Context, in *GroupRoleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+GroupsService+"/RevokeRole", in, opts)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const HistoryService = "domofon.History"
+
+type LoginEvent struct {
+	Id               int64     `json:"id"`
+	AppId            int32     `json:"app_id"`
+	Success          bool      `json:"success"`
+	Reason           string    `json:"reason,omitempty"`
+	IP               string    `json:"ip,omitempty"`
+	UserAgent        string    `json:"user_agent,omitempty"`
+	Country          string    `json:"country,omitempty"`
+	City             string    `json:"city,omitempty"`
+	ImpossibleTravel bool      `json:"impossible_travel,omitempty"`
+	RiskScore        int       `json:"risk_score,omitempty"`
+	RiskSignals      []string  `json:"risk_signals,omitempty"`
+	StepUp           bool      `json:"step_up,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type ListLoginEventsRequest struct {
+	UserId   int64  `json:"user_id"`
+	Cursor   string `json:"cursor,omitempty"`
+	PageSize int32  `json:"page_size,omitempty"`
+}
+
+type ListLoginEventsResponse struct {
+	Events     []LoginEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+type HistoryServer interface {
+	ListLoginEvents(context.Context, *ListLoginEventsRequest) (*ListLoginEventsResponse, error)
+}
+
+func RegisterHistoryServer(s grpc.ServiceRegistrar, srv HistoryServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: HistoryService,
+		HandlerType: (*HistoryServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(HistoryService, "ListLoginEvents", HistoryServer.ListLoginEvents),
+		},
+	}, srv)
+}
+
+type HistoryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHistoryClient(cc grpc.ClientConnInterface) *HistoryClient {
+	return &HistoryClient{cc: cc}
+}
+
+func (c *HistoryClient) ListLoginEvents(
+	ctx context.Context,
+	in *ListLoginEventsRequest,
+	opts ...grpc.CallOption,
+) (*ListLoginEventsResponse, error) {
+	return invoke[ListLoginEventsResponse](ctx, c.cc, "/"+HistoryService+"/ListLoginEvents", in, opts)
+}
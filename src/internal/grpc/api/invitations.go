@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const InvitationsService = "domofon.Invitations"
+
+type InvitationRole struct {
+	AppId int32  `json:"app_id"`
+	Role  string `json:"role"`
+}
+
+type Invitation struct {
+	Id         int64            `json:"id"`
+	Email      string           `json:"email"`
+	Roles      []InvitationRole `json:"roles"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ExpiresAt  time.Time        `json:"expires_at"`
+	AcceptedAt *time.Time       `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time       `json:"revoked_at,omitempty"`
+}
+
+type CreateInvitationRequest struct {
+	Email string           `json:"email"`
+	Roles []InvitationRole `json:"roles"`
+}
+
+type CreateInvitationResponse struct {
+	Id    int64  `json:"id"`
+	Token string `json:"token"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type AcceptInvitationResponse struct {
+	UserId int64 `json:"user_id"`
+}
+
+type RevokeInvitationRequest struct {
+	InvitationId int64 `json:"invitation_id"`
+}
+
+type ListInvitationsResponse struct {
+	Invitations []Invitation `json:"invitations"`
+}
+
+type InvitationsServer interface {
+	CreateInvitation(context.Context, *CreateInvitationRequest) (*CreateInvitationResponse, error)
+	AcceptInvitation(context.Context, *AcceptInvitationRequest) (*AcceptInvitationResponse, error)
+	RevokeInvitation(context.Context, *RevokeInvitationRequest) (*Empty, error)
+	ListInvitations(context.Context, *Empty) (*ListInvitationsResponse, error)
+}
+
+func RegisterInvitationsServer(s grpc.ServiceRegistrar, srv InvitationsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: InvitationsService,
+		HandlerType: (*InvitationsServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(InvitationsService, "CreateInvitation", InvitationsServer.CreateInvitation),
+			method(InvitationsService, "AcceptInvitation", InvitationsServer.AcceptInvitation),
+			method(InvitationsService, "RevokeInvitation", InvitationsServer.RevokeInvitation),
+			method(InvitationsService, "ListInvitations", InvitationsServer.ListInvitations),
+		},
+	}, srv)
+}
+
+type InvitationsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInvitationsClient(cc grpc.ClientConnInterface) *InvitationsClient {
+	return &InvitationsClient{cc: cc}
+}
+
+func (c *InvitationsClient) CreateInvitation(
+	ctx context.Context,
+	in *CreateInvitationRequest,
+	opts ...grpc.CallOption,
+) (*CreateInvitationResponse, error) {
+	return invoke[CreateInvitationResponse](ctx, c.cc, "/"+InvitationsService+"/CreateInvitation", in, opts)
+}
+
+func (c *InvitationsClient) AcceptInvitation(
+	ctx context.Context,
+	in *AcceptInvitationRequest,
+	opts ...grpc.CallOption,
+) (*AcceptInvitationResponse, error) {
+	return invoke[AcceptInvitationResponse](ctx, c.cc, "/"+InvitationsService+"/AcceptInvitation", in, opts)
+}
+
+func (c *InvitationsClient) RevokeInvitation(
+	ctx context.Context,
+	in *RevokeInvitationRequest,
+	opts ...grpc.CallOption,
+) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+InvitationsService+"/RevokeInvitation", in, opts)
+}
+
+func (c *InvitationsClient) ListInvitations(
+	ctx context.Context,
+	in *Empty,
+	opts ...grpc.CallOption,
+) (*ListInvitationsResponse, error) {
+	return invoke[ListInvitationsResponse](ctx, c.cc, "/"+InvitationsService+"/ListInvitations", in, opts)
+}
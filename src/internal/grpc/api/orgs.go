@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const OrgsService = "domofon.Orgs"
+
+type Org struct {
+	Id        int32     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateOrgResponse struct {
+	Id int32 `json:"id"`
+}
+
+type GetOrgRequest struct {
+	OrgId int32 `json:"org_id"`
+}
+
+type ListOrgsResponse struct {
+	Orgs []Org `json:"orgs"`
+}
+
+type RenameOrgRequest struct {
+	OrgId int32  `json:"org_id"`
+	Name  string `json:"name"`
+}
+
+type DeleteOrgRequest struct {
+	OrgId int32 `json:"org_id"`
+}
+
+type OrgsServer interface {
+	CreateOrg(context.Context, *CreateOrgRequest) (*CreateOrgResponse, error)
+	GetOrg(context.Context, *GetOrgRequest) (*Org, error)
+	ListOrgs(context.Context, *Empty) (*ListOrgsResponse, error)
+	RenameOrg(context.Context, *RenameOrgRequest) (*Empty, error)
+	DeleteOrg(context.Context, *DeleteOrgRequest) (*Empty, error)
+}
+
+func RegisterOrgsServer(s grpc.ServiceRegistrar, srv OrgsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: OrgsService,
+		HandlerType: (*OrgsServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(OrgsService, "CreateOrg", OrgsServer.CreateOrg),
+			method(OrgsService, "GetOrg", OrgsServer.GetOrg),
+			method(OrgsService, "ListOrgs", OrgsServer.ListOrgs),
+			method(OrgsService, "RenameOrg", OrgsServer.RenameOrg),
+			method(OrgsService, "DeleteOrg", OrgsServer.DeleteOrg),
+		},
+	}, srv)
+}
+
+type OrgsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrgsClient(cc grpc.ClientConnInterface) *OrgsClient {
+	return &OrgsClient{cc: cc}
+}
+
+func (c *OrgsClient) CreateOrg(
+	ctx context.Context,
+	in *CreateOrgRequest,
+	opts ...grpc.CallOption,
+) (*CreateOrgResponse, error) {
+	return invoke[CreateOrgResponse](ctx, c.cc, "/"+OrgsService+"/CreateOrg", in, opts)
+}
+
+func (c *OrgsClient) GetOrg(ctx context.Context, in *GetOrgRequest, opts ...grpc.CallOption) (*Org, error) {
+	return invoke[Org](ctx, c.cc, "/"+OrgsService+"/GetOrg", in, opts)
+}
+
+func (c *OrgsClient) ListOrgs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListOrgsResponse, error) {
+	return invoke[ListOrgsResponse](ctx, c.cc, "/"+OrgsService+"/ListOrgs", in, opts)
+}
+
+func (c *OrgsClient) RenameOrg(ctx context.Context, in *RenameOrgRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+OrgsService+"/RenameOrg", in, opts)
+}
+
+func (c *OrgsClient) DeleteOrg(ctx context.Context, in *DeleteOrgRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+OrgsService+"/DeleteOrg", in, opts)
+}
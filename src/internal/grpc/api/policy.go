@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+const PolicyService = "domofon.Policy"
+
+// SetAppPoliciesRequest replaces CEL policies of app, empty expression disables the policy
+type SetAppPoliciesRequest struct {
+	AppId        int32  `json:"app_id"`
+	LoginPolicy  string `json:"login_policy"`
+	ClaimsPolicy string `json:"claims_policy"`
+}
+
+// DryRunRequest evaluates policies against user, empty expressions are taken from the app
+type DryRunRequest struct {
+	AppId        int32  `json:"app_id"`
+	UserId       int64  `json:"user_id"`
+	LoginPolicy  string `json:"login_policy"`
+	ClaimsPolicy string `json:"claims_policy"`
+}
+
+type DryRunResponse struct {
+	Allowed bool           `json:"allowed"`
+	Claims  map[string]any `json:"claims"`
+}
+
+type PolicyServer interface {
+	SetAppPolicies(context.Context, *SetAppPoliciesRequest) (*Empty, error)
+	DryRun(context.Context, *DryRunRequest) (*DryRunResponse, error)
+}
+
+func RegisterPolicyServer(s grpc.ServiceRegistrar, srv PolicyServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: PolicyService,
+		HandlerType: (*PolicyServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(PolicyService, "SetAppPolicies", PolicyServer.SetAppPolicies),
+			method(PolicyService, "DryRun", PolicyServer.DryRun),
+		},
+	}, srv)
+}
+
+type PolicyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPolicyClient(cc grpc.ClientConnInterface) *PolicyClient {
+	return &PolicyClient{cc: cc}
+}
+
+func (c *PolicyClient) SetAppPolicies(
+	ctx context.Context,
+	in *SetAppPoliciesRequest,
+	opts ...grpc.CallOption,
+) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+PolicyService+"/SetAppPolicies", in, opts)
+}
+
+func (c *PolicyClient) DryRun(ctx context.Context, in *DryRunRequest, opts ...grpc.CallOption) (*DryRunResponse, error) {
+	return invoke[DryRunResponse](ctx, c.cc, "/"+PolicyService+"/DryRun", in, opts)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+const RBACService = "domofon.RBAC"
+
+type AssignRoleRequest struct {
+	UserId int64  `json:"user_id"`
+	AppId  int32  `json:"app_id"`
+	Role   string `json:"role"`
+}
+
+type RevokeRoleRequest struct {
+	UserId int64  `json:"user_id"`
+	AppId  int32  `json:"app_id"`
+	Role   string `json:"role"`
+}
+
+type ListRolesRequest struct {
+	UserId int64 `json:"user_id"`
+	AppId  int32 `json:"app_id"`
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type ListRolesResponse struct {
+	Roles []Role `json:"roles"`
+}
+
+type CheckPermissionRequest struct {
+	UserId     int64  `json:"user_id"`
+	AppId      int32  `json:"app_id"`
+	Permission string `json:"permission"`
+}
+
+type CheckPermissionResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+type RBACServer interface {
+	AssignRole(context.Context, *AssignRoleRequest) (*Empty, error)
+	RevokeRole(context.Context, *RevokeRoleRequest) (*Empty, error)
+	ListRoles(context.Context, *ListRolesRequest) (*ListRolesResponse, error)
+	CheckPermission(context.Context, *CheckPermissionRequest) (*CheckPermissionResponse, error)
+}
+
+func RegisterRBACServer(s grpc.ServiceRegistrar, srv RBACServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: RBACService,
+		HandlerType: (*RBACServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(RBACService, "AssignRole", RBACServer.AssignRole),
+			method(RBACService, "RevokeRole", RBACServer.RevokeRole),
+			method(RBACService, "ListRoles", RBACServer.ListRoles),
+			method(RBACService, "CheckPermission", RBACServer.CheckPermission),
+		},
+	}, srv)
+}
+
+type RBACClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRBACClient(cc grpc.ClientConnInterface) *RBACClient {
+	return &RBACClient{cc: cc}
+}
+
+func (c *RBACClient) AssignRole(ctx context.Context, in *AssignRoleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+RBACService+"/AssignRole", in, opts)
+}
+
+func (c *RBACClient) RevokeRole(ctx context.Context, in *RevokeRoleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+RBACService+"/RevokeRole", in, opts)
+}
+
+func (c *RBACClient) ListRoles(
+	ctx context.Context,
+	in *ListRolesRequest,
+	opts ...grpc.CallOption,
+) (*ListRolesResponse, error) {
+	return invoke[ListRolesResponse](ctx, c.cc, "/"+RBACService+"/ListRoles", in, opts)
+}
+
+func (c *RBACClient) CheckPermission(
+	ctx context.Context,
+	in *CheckPermissionRequest,
+	opts ...grpc.CallOption,
+) (*CheckPermissionResponse, error) {
+	return invoke[CheckPermissionResponse](ctx, c.cc, "/"+RBACService+"/CheckPermission", in, opts)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const SessionsService = "domofon.Sessions"
+
+type Session struct {
+	Id         string     `json:"id"`
+	AppId      int32      `json:"app_id"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+type ListSessionsRequest struct {
+	UserId int64 `json:"user_id"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []Session `json:"sessions"`
+}
+
+type RevokeSessionRequest struct {
+	UserId    int64  `json:"user_id"`
+	SessionId string `json:"session_id"`
+}
+
+type SessionsServer interface {
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
+	RevokeSession(context.Context, *RevokeSessionRequest) (*Empty, error)
+}
+
+func RegisterSessionsServer(s grpc.ServiceRegistrar, srv SessionsServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: SessionsService,
+		HandlerType: (*SessionsServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(SessionsService, "ListSessions", SessionsServer.ListSessions),
+			method(SessionsService, "RevokeSession", SessionsServer.RevokeSession),
+		},
+	}, srv)
+}
+
+type SessionsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSessionsClient(cc grpc.ClientConnInterface) *SessionsClient {
+	return &SessionsClient{cc: cc}
+}
+
+func (c *SessionsClient) ListSessions(
+	ctx context.Context,
+	in *ListSessionsRequest,
+	opts ...grpc.CallOption,
+) (*ListSessionsResponse, error) {
+	return invoke[ListSessionsResponse](ctx, c.cc, "/"+SessionsService+"/ListSessions", in, opts)
+}
+
+func (c *SessionsClient) RevokeSession(
+	ctx context.Context,
+	in *RevokeSessionRequest,
+	opts ...grpc.CallOption,
+) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+SessionsService+"/RevokeSession", in, opts)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+const TokensService = "domofon.Tokens"
+
+// ClientCredentials authenticate resource server as app. Without secret the app
+// authenticates with its TLS client certificate
+type ClientCredentials struct {
+	ClientId     int32  `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type IntrospectRequest struct {
+	ClientCredentials
+	Token string `json:"token"`
+}
+
+// IntrospectResponse is RFC 7662 response, claims are set only for active tokens.
+// Clients the token isn't meant for get only exp and aud claims
+type IntrospectResponse struct {
+	Active bool           `json:"active"`
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+type RevokeRequest struct {
+	ClientCredentials
+	Token string `json:"token"`
+}
+
+// ValidateRequest is access token resource server got with request of method to url.
+// DPoP proof of the request is required for tokens bound to DPoP key,
+// fresh nonce for the client is in dpop-nonce header of the response
+type ValidateRequest struct {
+	Token     string `json:"token"`
+	DPoPProof string `json:"dpop_proof,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Url       string `json:"url,omitempty"`
+}
+
+// ValidateResponse identifies user of active token. X5T is thumbprint of client certificate
+// the token is bound to, resource server compares it with certificate of the request.
+// ACR is "0" for tokens of logins risk asked to step up from, "1" for the rest
+type ValidateResponse struct {
+	Active    bool   `json:"active"`
+	UserId    int64  `json:"user_id,omitempty"`
+	AppId     int32  `json:"app_id,omitempty"`
+	OrgId     int32  `json:"org_id,omitempty"`
+	SessionId string `json:"session_id,omitempty"`
+	ACR       string `json:"acr,omitempty"`
+	JKT       string `json:"jkt,omitempty"`
+	X5T       string `json:"x5t,omitempty"`
+}
+
+type TokensServer interface {
+	Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error)
+	Revoke(context.Context, *RevokeRequest) (*Empty, error)
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+}
+
+func RegisterTokensServer(s grpc.ServiceRegistrar, srv TokensServer) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: TokensService,
+		HandlerType: (*TokensServer)(nil),
+		Methods: []grpc.MethodDesc{
+			method(TokensService, "Introspect", TokensServer.Introspect),
+			method(TokensService, "Revoke", TokensServer.Revoke),
+			method(TokensService, "Validate", TokensServer.Validate),
+		},
+	}, srv)
+}
+
+type TokensClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokensClient(cc grpc.ClientConnInterface) *TokensClient {
+	return &TokensClient{cc: cc}
+}
+
+func (c *TokensClient) Introspect(
+	ctx context.Context,
+	in *IntrospectRequest,
+	opts ...grpc.CallOption,
+) (*IntrospectResponse, error) {
+	return invoke[IntrospectResponse](ctx, c.cc, "/"+TokensService+"/Introspect", in, opts)
+}
+
+func (c *TokensClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*Empty, error) {
+	return invoke[Empty](ctx, c.cc, "/"+TokensService+"/Revoke", in, opts)
+}
+
+func (c *TokensClient) Validate(
+	ctx context.Context,
+	in *ValidateRequest,
+	opts ...grpc.CallOption,
+) (*ValidateResponse, error) {
+	return invoke[ValidateResponse](ctx, c.cc, "/"+TokensService+"/Validate", in, opts)
+}
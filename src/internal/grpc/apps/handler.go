@@ -0,0 +1,359 @@
+package apps
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/apps"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+const (
+	EmptyValue = 0
+)
+
+var Access = interceptors.Methods{
+	api.AppsService: interceptors.AdminOnly,
+}
+
+type Apps interface {
+	CreateApp(
+		ctx context.Context,
+		orgID int,
+		name string,
+		redirectURIs []string,
+		grantTypes []string,
+		tokens models.TokenSettings,
+		sessions models.SessionSettings,
+		riskSettings models.RiskSettings,
+		clientAuth *models.TLSClientAuth,
+	) (models.App, error)
+	GetApp(ctx context.Context, orgID int, appID int) (models.App, error)
+	ListApps(ctx context.Context, orgID int) ([]models.App, error)
+	UpdateApp(ctx context.Context, orgID int, appID int, upd models.AppUpdate) error
+	DeleteApp(ctx context.Context, orgID int, appID int) error
+	RotateAppSecret(ctx context.Context, orgID int, appID int) (string, error)
+}
+
+type handler struct {
+	apps Apps
+}
+
+func Register(grpcSrv *grpc.Server, apps Apps) {
+	api.RegisterAppsServer(grpcSrv, &handler{apps: apps})
+}
+
+func (h handler) CreateApp(ctx context.Context, request *api.CreateAppRequest) (*api.CreateAppResponse, error) {
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty name")
+	}
+
+	var (
+		settings     models.TokenSettings
+		sessions     models.SessionSettings
+		riskSettings models.RiskSettings
+		err          error
+	)
+	if request.Tokens != nil {
+		if settings, err = tokenSettings(*request.Tokens); err != nil {
+			return nil, err
+		}
+	}
+	if request.Sessions != nil {
+		if sessions, err = sessionSettings(*request.Sessions); err != nil {
+			return nil, err
+		}
+	}
+
+	if request.Risk != nil {
+		riskSettings = models.RiskSettings{StepUpScore: request.Risk.StepUpScore, DenyScore: request.Risk.DenyScore}
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	app, err := h.apps.CreateApp(
+		ctx,
+		int(caller.OrgID),
+		request.Name,
+		request.RedirectURIs,
+		request.GrantTypes,
+		settings,
+		sessions,
+		riskSettings,
+		clientAuth(request.TLSClientAuth),
+	)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.CreateAppResponse{App: appResponse(app), Secret: app.Secret}, nil
+}
+
+func (h handler) GetApp(ctx context.Context, request *api.GetAppRequest) (*api.App, error) {
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	app, err := h.apps.GetApp(ctx, int(caller.OrgID), int(request.AppId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := appResponse(app)
+
+	return &res, nil
+}
+
+func (h handler) ListApps(ctx context.Context, _ *api.Empty) (*api.ListAppsResponse, error) {
+	caller, _ := interceptors.CallerFromContext(ctx)
+	list, err := h.apps.ListApps(ctx, int(caller.OrgID))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListAppsResponse{Apps: make([]api.App, 0, len(list))}
+	for _, app := range list {
+		res.Apps = append(res.Apps, appResponse(app))
+	}
+
+	return res, nil
+}
+
+func (h handler) UpdateApp(ctx context.Context, request *api.UpdateAppRequest) (*api.Empty, error) {
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+	if request.Name != nil && *request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty name")
+	}
+
+	upd := models.AppUpdate{
+		Name:          request.Name,
+		RedirectURIs:  request.RedirectURIs,
+		GrantTypes:    request.GrantTypes,
+		TLSClientAuth: clientAuth(request.TLSClientAuth),
+	}
+	if request.Tokens != nil {
+		settings, err := tokenSettings(*request.Tokens)
+		if err != nil {
+			return nil, err
+		}
+		upd.Tokens = &settings
+	}
+	if request.Sessions != nil {
+		sessions, err := sessionSettings(*request.Sessions)
+		if err != nil {
+			return nil, err
+		}
+		upd.Sessions = &sessions
+	}
+
+	if request.Risk != nil {
+		upd.Risk = &models.RiskSettings{StepUpScore: request.Risk.StepUpScore, DenyScore: request.Risk.DenyScore}
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	if err := h.apps.UpdateApp(ctx, int(caller.OrgID), int(request.AppId), upd); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) DeleteApp(ctx context.Context, request *api.DeleteAppRequest) (*api.Empty, error) {
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	if err := h.apps.DeleteApp(ctx, int(caller.OrgID), int(request.AppId)); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) RotateAppSecret(
+	ctx context.Context,
+	request *api.RotateAppSecretRequest,
+) (*api.RotateAppSecretResponse, error) {
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	secret, err := h.apps.RotateAppSecret(ctx, int(caller.OrgID), int(request.AppId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.RotateAppSecretResponse{Secret: secret}, nil
+}
+
+func tokenSettings(s api.TokenSettings) (models.TokenSettings, error) {
+	res := models.TokenSettings{
+		Format: s.Format,
+		Claims: models.ClaimSettings{
+			Version:  s.Claims.Version,
+			Email:    s.Claims.Email,
+			Roles:    s.Claims.Roles,
+			Admin:    s.Claims.Admin,
+			Static:   s.Claims.Static,
+			Audience: s.Claims.Audience,
+		},
+		EncryptionKey: s.EncryptionKey,
+		EncryptionAlg: s.EncryptionAlg,
+		RequireDPoP:   s.RequireDPoP,
+	}
+
+	var err error
+	if res.AccessTTL, err = duration("access_ttl", s.AccessTTL); err != nil {
+		return models.TokenSettings{}, err
+	}
+
+	return res, nil
+}
+
+func sessionSettings(s api.SessionSettings) (models.SessionSettings, error) {
+	res := models.SessionSettings{MaxSessions: s.MaxSessions, LimitPolicy: s.LimitPolicy}
+
+	var err error
+	if res.IdleTimeout, err = optionalDuration("idle_timeout", s.IdleTimeout); err != nil {
+		return models.SessionSettings{}, err
+	}
+	if res.MaxLifetime, err = optionalDuration("max_lifetime", s.MaxLifetime); err != nil {
+		return models.SessionSettings{}, err
+	}
+
+	return res, nil
+}
+
+func clientAuth(a *api.TLSClientAuth) *models.TLSClientAuth {
+	if a == nil {
+		return nil
+	}
+
+	return &models.TLSClientAuth{SubjectDN: a.SubjectDN, Thumbprints: a.Thumbprints}
+}
+
+// duration parses Go duration string of field, empty is zero
+func duration(field string, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid "+field)
+	}
+
+	return d, nil
+}
+
+// optionalDuration parses Go duration string of field, empty is nil so zero stays explicit
+func optionalDuration(field string, s string) (*time.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	d, err := duration(field, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func formatOptionalDuration(d *time.Duration) string {
+	if d == nil {
+		return ""
+	}
+
+	return d.String()
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return d.String()
+}
+
+func appResponse(app models.App) api.App {
+	res := api.App{
+		Id:           app.Id,
+		Name:         app.Name,
+		RedirectURIs: app.RedirectURIs,
+		GrantTypes:   app.GrantTypes,
+		Tokens: api.TokenSettings{
+			Format:    app.Tokens.Format,
+			AccessTTL: formatDuration(app.Tokens.AccessTTL),
+			Claims: api.ClaimSettings{
+				Version:  app.Tokens.Claims.Version,
+				Email:    app.Tokens.Claims.Email,
+				Roles:    app.Tokens.Claims.Roles,
+				Admin:    app.Tokens.Claims.Admin,
+				Static:   app.Tokens.Claims.Static,
+				Audience: app.Tokens.Claims.Audience,
+			},
+			EncryptionKey: app.Tokens.EncryptionKey,
+			EncryptionAlg: app.Tokens.EncryptionAlg,
+			RequireDPoP:   app.Tokens.RequireDPoP,
+		},
+		Sessions: api.SessionSettings{
+			IdleTimeout: formatOptionalDuration(app.Sessions.IdleTimeout),
+			MaxLifetime: formatOptionalDuration(app.Sessions.MaxLifetime),
+			MaxSessions: app.Sessions.MaxSessions,
+			LimitPolicy: app.Sessions.LimitPolicy,
+		},
+		Risk:      api.RiskSettings{StepUpScore: app.Risk.StepUpScore, DenyScore: app.Risk.DenyScore},
+		CreatedAt: app.CreatedAt,
+	}
+	if app.TLSClientAuth != nil {
+		res.TLSClientAuth = &api.TLSClientAuth{
+			SubjectDN:   app.TLSClientAuth.SubjectDN,
+			Thumbprints: app.TLSClientAuth.Thumbprints,
+		}
+	}
+
+	return res
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, apps.ErrAppExists):
+		res = status.Error(codes.AlreadyExists, "application already exists")
+	case errors.Is(err, apps.ErrAppNotFound):
+		res = status.Error(codes.NotFound, "application not found")
+	case errors.Is(err, apps.ErrInvalidRedirectURI),
+		errors.Is(err, apps.ErrInvalidGrantType),
+		errors.Is(err, apps.ErrInvalidTokenTTL),
+		errors.Is(err, apps.ErrReservedClaim),
+		errors.Is(err, apps.ErrInvalidClaimsVer),
+		errors.Is(err, apps.ErrInvalidTokenFormat),
+		errors.Is(err, apps.ErrEncryptionFormat),
+		errors.Is(err, apps.ErrInvalidEncryption),
+		errors.Is(err, apps.ErrInvalidClientAuth),
+		errors.Is(err, apps.ErrInvalidSessionTTL),
+		errors.Is(err, apps.ErrInvalidSessionMax),
+		errors.Is(err, apps.ErrInvalidRiskScore):
+		// validation errors name the offending value, they are safe to return
+		res = status.Error(codes.InvalidArgument, errors.Unwrap(err).Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"domofon/internal/lib/requestmeta"
 	"domofon/internal/services/auth"
 	"errors"
 	domofon_v1 "github.com/zose43/domofon-proto/out/go"
@@ -10,14 +11,12 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-const (
-	EmptyValue = 0
-)
+const EmptyValue = 0
 
 type Auth interface {
 	Login(ctx context.Context, pass string, email string, appID int) (string, error)
-	Register(ctx context.Context, pass string, email string) (int64, error)
-	IsAdmin(ctx context.Context, userID int) (bool, error)
+	Register(ctx context.Context, pass string, email string, appID int) (int64, error)
+	IsAdmin(ctx context.Context, appID int, userID int) (bool, error)
 }
 
 type handler struct {
@@ -71,8 +70,12 @@ func (h handler) IsAdmin(
 	if err := validateIsAdmin(request); err != nil {
 		return nil, err
 	}
+	appID, err := requestAppID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := h.auth.IsAdmin(ctx, int(request.GetUserId()))
+	res, err := h.auth.IsAdmin(ctx, appID, int(request.GetUserId()))
 	if err != nil {
 		return nil, printError(err)
 	}
@@ -95,8 +98,12 @@ func (h handler) Register(
 	if err := validateRegister(request); err != nil {
 		return nil, err
 	}
+	appID, err := requestAppID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	userID, err := h.auth.Register(ctx, request.GetPassword(), request.GetEmail())
+	userID, err := h.auth.Register(ctx, request.GetPassword(), request.GetEmail(), appID)
 	if err != nil {
 		return nil, printError(err)
 	}
@@ -115,6 +122,17 @@ func validateRegister(request *domofon_v1.RegisterRequest) error {
 	return nil
 }
 
+// requestAppID returns app of x-app-id metadata, Register and IsAdmin messages have no app_id.
+// Organization of the request is the one of the app
+func requestAppID(ctx context.Context) (int, error) {
+	appID := requestmeta.FromContext(ctx).AppID
+	if appID == EmptyValue {
+		return 0, status.Error(codes.InvalidArgument, "empty x-app-id metadata")
+	}
+
+	return int(appID), nil
+}
+
 func printError(err error) error {
 	var res error
 
@@ -123,10 +141,32 @@ func printError(err error) error {
 		res = status.Error(codes.AlreadyExists, "user already exists")
 	case errors.Is(err, auth.ErrInvalidCredentials):
 		res = status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, auth.ErrInvalidOrg):
+		res = status.Error(codes.NotFound, "organization not found")
 	case errors.Is(err, auth.ErrInvalidApp):
 		res = status.Error(codes.NotFound, "app not found")
 	case errors.Is(err, auth.ErrUserNotFound):
 		res = status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, auth.ErrUserDisabled):
+		res = status.Error(codes.PermissionDenied, "user disabled")
+	case errors.Is(err, auth.ErrDPoPRequired):
+		res = status.Error(codes.Unauthenticated, "dpop proof required")
+	case errors.Is(err, auth.ErrClientCertRequired):
+		res = status.Error(codes.Unauthenticated, "client certificate required")
+	case errors.Is(err, auth.ErrSessionLimit):
+		res = status.Error(codes.ResourceExhausted, "session limit reached")
+	case errors.Is(err, auth.ErrGrantNotAllowed):
+		res = status.Error(codes.PermissionDenied, "grant type not allowed for app")
+	case errors.Is(err, auth.ErrPolicyDenied):
+		res = status.Error(codes.PermissionDenied, "login denied by app policy")
+	case errors.Is(err, auth.ErrRiskDenied):
+		res = status.Error(codes.PermissionDenied, "login denied as too risky")
+	case errors.Is(err, auth.ErrBusy):
+		res = status.Error(codes.Unavailable, "service is busy, retry later")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
 	default:
 		res = status.Error(codes.Internal, "internal error")
 	}
@@ -0,0 +1,194 @@
+package authz
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/authz"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Access lets members of organization check its tuples, writing them is up to admins
+var Access = interceptors.Methods{
+	api.AuthzService:                        interceptors.UserOnly,
+	"/" + api.AuthzService + "/WriteTuples": interceptors.AdminOnly,
+}
+
+type Authz interface {
+	WriteTuples(
+		ctx context.Context,
+		orgID int,
+		inserts []models.RelationTuple,
+		deletes []models.RelationTuple,
+	) (string, error)
+	Check(ctx context.Context, orgID int, tuple models.RelationTuple, token string) (bool, string, error)
+	Expand(ctx context.Context, orgID int, userset models.Subject, token string) (models.UsersetTree, string, error)
+	ListObjects(
+		ctx context.Context,
+		orgID int,
+		namespace string,
+		relation string,
+		subject models.Subject,
+		token string,
+		cursor string,
+		pageSize int,
+	) ([]string, string, string, error)
+}
+
+type handler struct {
+	authz Authz
+}
+
+func Register(grpcSrv *grpc.Server, authz Authz) {
+	api.RegisterAuthzServer(grpcSrv, &handler{authz: authz})
+}
+
+func (h handler) WriteTuples(ctx context.Context, request *api.WriteTuplesRequest) (*api.WriteTuplesResponse, error) {
+	if len(request.Inserts) == 0 && len(request.Deletes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty inserts and deletes")
+	}
+
+	inserts, err := parseTuples(request.Inserts)
+	if err != nil {
+		return nil, err
+	}
+	deletes, err := parseTuples(request.Deletes)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	token, err := h.authz.WriteTuples(ctx, int(caller.OrgID), inserts, deletes)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.WriteTuplesResponse{Token: token}, nil
+}
+
+func parseTuples(raw []string) ([]models.RelationTuple, error) {
+	tuples := make([]models.RelationTuple, 0, len(raw))
+	for _, s := range raw {
+		t, err := authz.ParseTuple(s)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid tuple %q", s)
+		}
+		tuples = append(tuples, t)
+	}
+
+	return tuples, nil
+}
+
+func (h handler) Check(ctx context.Context, request *api.CheckRequest) (*api.CheckResponse, error) {
+	if request.Tuple == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty tuple")
+	}
+
+	tuple, err := authz.ParseTuple(request.Tuple)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tuple %q", request.Tuple)
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	allowed, token, err := h.authz.Check(ctx, int(caller.OrgID), tuple, request.Token)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.CheckResponse{Allowed: allowed, Token: token}, nil
+}
+
+func (h handler) Expand(ctx context.Context, request *api.ExpandRequest) (*api.ExpandResponse, error) {
+	if request.Userset == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty userset")
+	}
+
+	userset, err := authz.ParseSubject(request.Userset)
+	if err != nil || userset.Relation == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid userset %q", request.Userset)
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	tree, token, err := h.authz.Expand(ctx, int(caller.OrgID), userset, request.Token)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.ExpandResponse{Tree: toTree(tree), Token: token}, nil
+}
+
+func toTree(tree models.UsersetTree) api.UsersetTree {
+	res := api.UsersetTree{Operation: tree.Operation, Userset: tree.Userset.String()}
+	for _, s := range tree.Subjects {
+		res.Subjects = append(res.Subjects, s.String())
+	}
+	for _, c := range tree.Children {
+		res.Children = append(res.Children, toTree(c))
+	}
+
+	return res
+}
+
+func (h handler) ListObjects(ctx context.Context, request *api.ListObjectsRequest) (*api.ListObjectsResponse, error) {
+	if request.Namespace == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty namespace")
+	}
+	if request.Relation == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty relation")
+	}
+
+	subject, err := authz.ParseSubject(request.Subject)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid subject %q", request.Subject)
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	objects, next, token, err := h.authz.ListObjects(
+		ctx,
+		int(caller.OrgID),
+		request.Namespace,
+		request.Relation,
+		subject,
+		request.Token,
+		request.Cursor,
+		int(request.PageSize),
+	)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.ListObjectsResponse{ObjectIds: objects, NextCursor: next, Token: token}, nil
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, authz.ErrUnknownNamespace):
+		res = status.Error(codes.InvalidArgument, "unknown namespace")
+	case errors.Is(err, authz.ErrUnknownRelation):
+		res = status.Error(codes.InvalidArgument, "unknown relation")
+	case errors.Is(err, authz.ErrInvalidToken):
+		res = status.Error(codes.InvalidArgument, "invalid consistency token")
+	case errors.Is(err, authz.ErrInvalidCursor):
+		res = status.Error(codes.InvalidArgument, "invalid cursor")
+	case errors.Is(err, authz.ErrMaxDepth):
+		res = status.Error(codes.FailedPrecondition, "max depth exceeded")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
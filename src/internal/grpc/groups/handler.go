@@ -0,0 +1,263 @@
+package groups
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/groups"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+// Access lets users look into own groups, managing them is up to admins
+var Access = interceptors.Methods{
+	api.GroupsService:                       interceptors.AdminOnly,
+	"/" + api.GroupsService + "/UserGroups": interceptors.UserOnly,
+}
+
+type Groups interface {
+	CreateGroup(ctx context.Context, orgID int, name string) (int32, error)
+	DeleteGroup(ctx context.Context, orgID int, groupID int) error
+	ListGroups(ctx context.Context, orgID int) ([]models.Group, error)
+	AddUser(ctx context.Context, orgID int, groupID int, userID int64) error
+	RemoveUser(ctx context.Context, orgID int, groupID int, userID int64) error
+	AddSubgroup(ctx context.Context, orgID int, parentID int, childID int) error
+	RemoveSubgroup(ctx context.Context, orgID int, parentID int, childID int) error
+	UserGroups(ctx context.Context, orgID int, userID int64) ([]models.Group, error)
+	GroupUsers(ctx context.Context, orgID int, groupID int) ([]int64, error)
+	AssignRole(ctx context.Context, orgID int, groupID int, appID int, role string) error
+	RevokeRole(ctx context.Context, orgID int, groupID int, appID int, role string) error
+}
+
+type handler struct {
+	groups Groups
+}
+
+func Register(grpcSrv *grpc.Server, groups Groups) {
+	api.RegisterGroupsServer(grpcSrv, &handler{groups: groups})
+}
+
+func (h handler) CreateGroup(ctx context.Context, request *api.CreateGroupRequest) (*api.CreateGroupResponse, error) {
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty name")
+	}
+
+	id, err := h.groups.CreateGroup(ctx, callerOrg(ctx), request.Name)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.CreateGroupResponse{Id: id}, nil
+}
+
+func (h handler) DeleteGroup(ctx context.Context, request *api.DeleteGroupRequest) (*api.Empty, error) {
+	if request.GroupId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty group_id")
+	}
+
+	if err := h.groups.DeleteGroup(ctx, callerOrg(ctx), int(request.GroupId)); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) ListGroups(ctx context.Context, _ *api.Empty) (*api.ListGroupsResponse, error) {
+	list, err := h.groups.ListGroups(ctx, callerOrg(ctx))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return groupsResponse(list), nil
+}
+
+func (h handler) AddUser(ctx context.Context, request *api.GroupUserRequest) (*api.Empty, error) {
+	if err := validateGroupUser(request); err != nil {
+		return nil, err
+	}
+
+	if err := h.groups.AddUser(ctx, callerOrg(ctx), int(request.GroupId), request.UserId); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) RemoveUser(ctx context.Context, request *api.GroupUserRequest) (*api.Empty, error) {
+	if err := validateGroupUser(request); err != nil {
+		return nil, err
+	}
+
+	if err := h.groups.RemoveUser(ctx, callerOrg(ctx), int(request.GroupId), request.UserId); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) AddSubgroup(ctx context.Context, request *api.SubgroupRequest) (*api.Empty, error) {
+	if err := validateSubgroup(request); err != nil {
+		return nil, err
+	}
+
+	err := h.groups.AddSubgroup(ctx, callerOrg(ctx), int(request.ParentId), int(request.ChildId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) RemoveSubgroup(ctx context.Context, request *api.SubgroupRequest) (*api.Empty, error) {
+	if err := validateSubgroup(request); err != nil {
+		return nil, err
+	}
+
+	err := h.groups.RemoveSubgroup(ctx, callerOrg(ctx), int(request.ParentId), int(request.ChildId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) UserGroups(ctx context.Context, request *api.UserGroupsRequest) (*api.ListGroupsResponse, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if err := interceptors.CheckAccess(ctx, request.UserId); err != nil {
+		return nil, err
+	}
+
+	list, err := h.groups.UserGroups(ctx, callerOrg(ctx), request.UserId)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return groupsResponse(list), nil
+}
+
+func (h handler) GroupUsers(ctx context.Context, request *api.GroupUsersRequest) (*api.GroupUsersResponse, error) {
+	if request.GroupId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty group_id")
+	}
+
+	ids, err := h.groups.GroupUsers(ctx, callerOrg(ctx), int(request.GroupId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.GroupUsersResponse{UserIds: ids}, nil
+}
+
+func (h handler) AssignRole(ctx context.Context, request *api.GroupRoleRequest) (*api.Empty, error) {
+	if err := validateGroupRole(request); err != nil {
+		return nil, err
+	}
+
+	err := h.groups.AssignRole(ctx, callerOrg(ctx), int(request.GroupId), int(request.AppId), request.Role)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) RevokeRole(ctx context.Context, request *api.GroupRoleRequest) (*api.Empty, error) {
+	if err := validateGroupRole(request); err != nil {
+		return nil, err
+	}
+
+	err := h.groups.RevokeRole(ctx, callerOrg(ctx), int(request.GroupId), int(request.AppId), request.Role)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+// callerOrg returns organization of the caller, groups never cross it
+func callerOrg(ctx context.Context) int {
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	return int(caller.OrgID)
+}
+
+func groupsResponse(list []models.Group) *api.ListGroupsResponse {
+	res := &api.ListGroupsResponse{Groups: make([]api.Group, 0, len(list))}
+	for _, g := range list {
+		res.Groups = append(res.Groups, api.Group{Id: g.Id, Name: g.Name, CreatedAt: g.CreatedAt})
+	}
+
+	return res
+}
+
+func validateGroupUser(request *api.GroupUserRequest) error {
+	if request.GroupId == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty group_id")
+	}
+	if request.UserId == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty user_id")
+	}
+
+	return nil
+}
+
+func validateSubgroup(request *api.SubgroupRequest) error {
+	if request.ParentId == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty parent_id")
+	}
+	if request.ChildId == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty child_id")
+	}
+
+	return nil
+}
+
+func validateGroupRole(request *api.GroupRoleRequest) error {
+	if request.GroupId == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty group_id")
+	}
+	if request.AppId == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty app_id")
+	}
+	if request.Role == "" {
+		return status.Error(codes.InvalidArgument, "empty role")
+	}
+
+	return nil
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, groups.ErrGroupExists):
+		res = status.Error(codes.AlreadyExists, "group already exists")
+	case errors.Is(err, groups.ErrGroupNotFound):
+		res = status.Error(codes.NotFound, "group not found")
+	case errors.Is(err, groups.ErrGroupCycle):
+		res = status.Error(codes.FailedPrecondition, "group membership cycle")
+	case errors.Is(err, groups.ErrUserNotFound):
+		res = status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, groups.ErrRoleNotFound):
+		res = status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, groups.ErrRoleNotAssigned):
+		res = status.Error(codes.NotFound, "role not assigned")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/history"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+// Access lets users see own login attempts, admins see those of their organization
+var Access = interceptors.Methods{
+	api.HistoryService: interceptors.UserOnly,
+}
+
+type History interface {
+	ListLoginEvents(
+		ctx context.Context,
+		orgID int,
+		userID int64,
+		cursor string,
+		pageSize int,
+	) ([]models.LoginEvent, string, error)
+}
+
+type handler struct {
+	history History
+}
+
+func Register(grpcSrv *grpc.Server, history History) {
+	api.RegisterHistoryServer(grpcSrv, &handler{history: history})
+}
+
+func (h handler) ListLoginEvents(
+	ctx context.Context,
+	request *api.ListLoginEventsRequest,
+) (*api.ListLoginEventsResponse, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if err := interceptors.CheckAccess(ctx, request.UserId); err != nil {
+		return nil, err
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	events, next, err := h.history.ListLoginEvents(
+		ctx,
+		int(caller.OrgID),
+		request.UserId,
+		request.Cursor,
+		int(request.PageSize),
+	)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListLoginEventsResponse{Events: make([]api.LoginEvent, 0, len(events)), NextCursor: next}
+	for _, e := range events {
+		res.Events = append(res.Events, api.LoginEvent{
+			Id:               e.Id,
+			AppId:            e.AppId,
+			Success:          e.Success,
+			Reason:           e.Reason,
+			IP:               e.IP,
+			UserAgent:        e.UserAgent,
+			Country:          e.Geo.Country,
+			City:             e.Geo.City,
+			ImpossibleTravel: e.ImpossibleTravel,
+			RiskScore:        e.RiskScore,
+			RiskSignals:      e.RiskSignals,
+			StepUp:           e.StepUp,
+			CreatedAt:        e.CreatedAt,
+		})
+	}
+
+	return res, nil
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, history.ErrInvalidCursor):
+		res = status.Error(codes.InvalidArgument, "invalid cursor")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
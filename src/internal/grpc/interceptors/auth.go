@@ -0,0 +1,178 @@
+package interceptors
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/cnf"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/tokens"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+)
+
+const (
+	authorizationKey = "authorization"
+	bearerPrefix     = "bearer "
+	dpopPrefix       = "dpop "
+)
+
+// Access is what caller of method has to present
+type Access int
+
+const (
+	// Public methods don't need a token
+	Public Access = iota
+	// UserOnly methods need token of enabled user
+	UserOnly
+	// AdminOnly methods need token of enabled admin
+	AdminOnly
+)
+
+// Methods maps services and full method names to access, entry of method wins.
+// Methods of unlisted services are public
+type Methods map[string]Access
+
+func (m Methods) access(fullMethod string) Access {
+	if access, ok := m[fullMethod]; ok {
+		return access
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+
+	return m[service]
+}
+
+type TokenParser interface {
+	Parse(ctx context.Context, token string) (jwt.Claims, error)
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, orgID int32, userID int64) (models.User, error)
+}
+
+type callerKey struct{}
+
+// Caller describes user authenticated by TokenAuth
+type Caller struct {
+	UserID  int64
+	OrgID   int32
+	IsAdmin bool
+}
+
+// CanAccess reports whether caller may act on behalf of user of own organization
+func (c Caller) CanAccess(userID int64) bool {
+	return c.IsAdmin || c.UserID == userID
+}
+
+// CallerFromContext returns caller authenticated by TokenAuth
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// CheckAccess returns PermissionDenied status unless caller may act on behalf of user
+func CheckAccess(ctx context.Context, userID int64) error {
+	if caller, ok := CallerFromContext(ctx); !ok || !caller.CanAccess(userID) {
+		return status.Error(codes.PermissionDenied, "access to other user denied")
+	}
+
+	return nil
+}
+
+// TokenAuth requires bearer token of an enabled user, or an admin, for methods as listed in methods
+func TokenAuth(
+	log *slog.Logger,
+	methods Methods,
+	tokenParser TokenParser,
+	userProvider UserProvider,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		access := methods.access(info.FullMethod)
+		if access == Public {
+			return handler(ctx, req)
+		}
+
+		const op = "interceptors.tokenAuth"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("method", info.FullMethod),
+		)
+
+		token := authorizationToken(ctx)
+		if token == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing token")
+		}
+
+		claims, err := tokenParser.Parse(ctx, token)
+		if err != nil {
+			if errors.Is(err, tokens.ErrEncrypted) {
+				log.Warn("encrypted token", sl.Err(err))
+				return nil, status.Error(codes.Unauthenticated, "encrypted token is readable only by its app")
+			}
+
+			log.Warn("invalid token", sl.Err(err))
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		// DPoP interceptor verified the proof is for this token, the key must be the bound one
+		if claims.JKT != "" && cnf.FromContext(ctx).JKT != claims.JKT {
+			log.Warn("token used without its dpop key")
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if claims.X5T != "" && cnf.FromContext(ctx).X5T != claims.X5T {
+			log.Warn("token used without its client certificate")
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		user, err := userProvider.UserByID(ctx, claims.OrgID, claims.UserID)
+		if err != nil {
+			log.Warn("token user not found", sl.Err(err))
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if user.DisabledAt != nil {
+			log.Warn("user disabled", slog.Int64("user_id", user.Id))
+			return nil, status.Error(codes.PermissionDenied, "user disabled")
+		}
+		if access == AdminOnly && !user.IsAdmin {
+			log.Warn("user isn't admin", slog.Int64("user_id", user.Id))
+			return nil, status.Error(codes.PermissionDenied, "admin required")
+		}
+
+		ctx = context.WithValue(ctx, callerKey{}, Caller{UserID: user.Id, OrgID: user.OrgId, IsAdmin: user.IsAdmin})
+
+		return handler(ctx, req)
+	}
+}
+
+// authorizationToken returns access token of Bearer or DPoP authorization scheme
+func authorizationToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return ""
+	}
+
+	for _, prefix := range []string{bearerPrefix, dpopPrefix} {
+		if len(values[0]) >= len(prefix) && strings.EqualFold(values[0][:len(prefix)], prefix) {
+			return strings.TrimSpace(values[0][len(prefix):])
+		}
+	}
+
+	return ""
+}
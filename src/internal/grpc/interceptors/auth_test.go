@@ -0,0 +1,29 @@
+package interceptors
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMethods_access(t *testing.T) {
+	methods := Methods{
+		"domofon.RBAC":                  AdminOnly,
+		"/domofon.RBAC/CheckPermission": UserOnly,
+	}
+
+	tests := []struct {
+		fullMethod string
+		want       Access
+	}{
+		{fullMethod: "/domofon.RBAC/AssignRole", want: AdminOnly},
+		{fullMethod: "/domofon.RBAC/CheckPermission", want: UserOnly},
+		{fullMethod: "/domofon.Auth/Login", want: Public},
+		{fullMethod: "/domofon.RBACX/AssignRole", want: Public},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fullMethod, func(t *testing.T) {
+			assert.Equal(t, tt.want, methods.access(tt.fullMethod))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package interceptors
+
+import (
+	"context"
+	"domofon/internal/lib/cnf"
+	"domofon/internal/lib/dpop"
+	"domofon/internal/lib/logger/sl"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	dpopKey      = "dpop"
+	dpopNonceKey = "dpop-nonce"
+)
+
+type ProofVerifier interface {
+	Verify(proof string, method string, uri string, accessToken string) (string, error)
+	Nonce() string
+}
+
+// DPoP verifies DPoP proof sent in dpop metadata and puts thumbprint of its key into context.
+// gRPC requests are POST to baseURL followed by full method name.
+// Requests without proof pass as is, every response carries fresh nonce
+func DPoP(log *slog.Logger, baseURL string, verifier ProofVerifier) grpc.UnaryServerInterceptor {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		const op = "interceptors.dpop"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("method", info.FullMethod),
+		)
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(dpopNonceKey, verifier.Nonce()))
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		proofs := md.Get(dpopKey)
+		if len(proofs) == 0 {
+			return handler(ctx, req)
+		}
+		if len(proofs) > 1 {
+			return nil, status.Error(codes.InvalidArgument, "single dpop proof expected")
+		}
+
+		jkt, err := verifier.Verify(proofs[0], http.MethodPost, baseURL+info.FullMethod, authorizationToken(ctx))
+		if err != nil {
+			switch {
+			case errors.Is(err, dpop.ErrUseNonce):
+				return nil, status.Error(codes.Unauthenticated, dpop.ErrUseNonce.Error())
+			case errors.Is(err, dpop.ErrBusy):
+				log.Warn("dpop replay cache is full")
+				return nil, status.Error(codes.Unavailable, "service is busy")
+			}
+
+			log.Warn("invalid dpop proof", sl.Err(err))
+			return nil, status.Error(codes.InvalidArgument, "invalid dpop proof")
+		}
+
+		return handler(cnf.WithJKT(ctx, jkt), req)
+	}
+}
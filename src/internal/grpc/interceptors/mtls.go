@@ -0,0 +1,26 @@
+package interceptors
+
+import (
+	"context"
+	"domofon/internal/lib/cnf"
+	"domofon/internal/lib/requestmeta"
+	"google.golang.org/grpc"
+)
+
+// ClientCert puts thumbprint of TLS client certificate into context,
+// so tokens issued over the connection are bound to it (RFC 8705).
+// TLS handshake already proved the client holds the certificate key
+func ClientCert() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if cert := requestmeta.FromContext(ctx).ClientCert; cert != nil {
+			ctx = cnf.WithX5T(ctx, cnf.Thumbprint(cert))
+		}
+
+		return handler(ctx, req)
+	}
+}
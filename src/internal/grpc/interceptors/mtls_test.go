@@ -0,0 +1,131 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/cnf"
+	"domofon/internal/lib/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type fakeParser struct {
+	claims jwt.Claims
+}
+
+func (p fakeParser) Parse(context.Context, string) (jwt.Claims, error) {
+	return p.claims, nil
+}
+
+type fakeUsers struct{}
+
+func (fakeUsers) UserByID(_ context.Context, orgID int32, userID int64) (models.User, error) {
+	return models.User{Id: userID, OrgId: orgID}, nil
+}
+
+func clientCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// overTLS returns ctx of request that came over connection authenticated with cert, nil is plain TLS
+func overTLS(ctx context.Context, cert *x509.Certificate) context.Context {
+	var state tls.ConnectionState
+	if cert != nil {
+		state.PeerCertificates = []*x509.Certificate{cert}
+	}
+
+	return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestClientCert(t *testing.T) {
+	cert := clientCert(t)
+
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		wantX5T string
+	}{
+		{name: "client certificate", cert: cert, wantX5T: cnf.Thumbprint(cert)},
+		{name: "no client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			_, err := ClientCert()(overTLS(context.Background(), tt.cert), nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					got = cnf.FromContext(ctx).X5T
+					return nil, nil
+				})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantX5T, got)
+		})
+	}
+}
+
+func TestTokenAuth_certBound(t *testing.T) {
+	bound, other := clientCert(t), clientCert(t)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	parser := fakeParser{claims: jwt.Claims{UserID: 1, OrgID: 1, X5T: cnf.Thumbprint(bound)}}
+	auth := TokenAuth(log, Methods{"domofon.Auth": UserOnly}, parser, fakeUsers{})
+	info := &grpc.UnaryServerInfo{FullMethod: "/domofon.Auth/Logout"}
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		wantCode codes.Code
+	}{
+		{name: "bound certificate", cert: bound, wantCode: codes.OK},
+		{name: "different certificate", cert: other, wantCode: codes.Unauthenticated},
+		{name: "no certificate", wantCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(overTLS(context.Background(), tt.cert),
+				metadata.Pairs(authorizationKey, "Bearer token"))
+
+			// ClientCert runs before TokenAuth in the chain
+			_, err := ClientCert()(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				return auth(ctx, req, info, func(context.Context, any) (any, error) { return nil, nil })
+			})
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package invitations
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/invitations"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+// Access lets admins manage invitations of their organization,
+// invitees accept them without an account
+var Access = interceptors.Methods{
+	api.InvitationsService:                             interceptors.AdminOnly,
+	"/" + api.InvitationsService + "/AcceptInvitation": interceptors.Public,
+}
+
+type Invitations interface {
+	CreateInvitation(
+		ctx context.Context,
+		orgID int,
+		email string,
+		roles []models.InvitationRole,
+	) (int64, string, error)
+	AcceptInvitation(ctx context.Context, token string, pass string) (int64, error)
+	RevokeInvitation(ctx context.Context, orgID int, invitationID int64) error
+	ListInvitations(ctx context.Context, orgID int) ([]models.Invitation, error)
+}
+
+type handler struct {
+	invitations Invitations
+}
+
+func Register(grpcSrv *grpc.Server, invitations Invitations) {
+	api.RegisterInvitationsServer(grpcSrv, &handler{invitations: invitations})
+}
+
+func (h handler) CreateInvitation(
+	ctx context.Context,
+	request *api.CreateInvitationRequest,
+) (*api.CreateInvitationResponse, error) {
+	if request.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty email")
+	}
+
+	roles := make([]models.InvitationRole, 0, len(request.Roles))
+	for _, r := range request.Roles {
+		if r.AppId == EmptyValue {
+			return nil, status.Error(codes.InvalidArgument, "empty app_id")
+		}
+		if r.Role == "" {
+			return nil, status.Error(codes.InvalidArgument, "empty role")
+		}
+		roles = append(roles, models.InvitationRole{AppId: r.AppId, Role: r.Role})
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	id, token, err := h.invitations.CreateInvitation(ctx, int(caller.OrgID), request.Email, roles)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.CreateInvitationResponse{Id: id, Token: token}, nil
+}
+
+func (h handler) AcceptInvitation(
+	ctx context.Context,
+	request *api.AcceptInvitationRequest,
+) (*api.AcceptInvitationResponse, error) {
+	if request.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty token")
+	}
+
+	userID, err := h.invitations.AcceptInvitation(ctx, request.Token, request.Password)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.AcceptInvitationResponse{UserId: userID}, nil
+}
+
+func (h handler) RevokeInvitation(ctx context.Context, request *api.RevokeInvitationRequest) (*api.Empty, error) {
+	if request.InvitationId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty invitation_id")
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+	if err := h.invitations.RevokeInvitation(ctx, int(caller.OrgID), request.InvitationId); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) ListInvitations(ctx context.Context, _ *api.Empty) (*api.ListInvitationsResponse, error) {
+	caller, _ := interceptors.CallerFromContext(ctx)
+	list, err := h.invitations.ListInvitations(ctx, int(caller.OrgID))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListInvitationsResponse{Invitations: make([]api.Invitation, 0, len(list))}
+	for _, inv := range list {
+		roles := make([]api.InvitationRole, 0, len(inv.Roles))
+		for _, r := range inv.Roles {
+			roles = append(roles, api.InvitationRole{AppId: r.AppId, Role: r.Role})
+		}
+		res.Invitations = append(res.Invitations, api.Invitation{
+			Id:         inv.Id,
+			Email:      inv.Email,
+			Roles:      roles,
+			CreatedAt:  inv.CreatedAt,
+			ExpiresAt:  inv.ExpiresAt,
+			AcceptedAt: inv.AcceptedAt,
+			RevokedAt:  inv.RevokedAt,
+		})
+	}
+
+	return res, nil
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, invitations.ErrInvalidInvitation):
+		res = status.Error(codes.NotFound, "invitation not found, used, revoked or expired")
+	case errors.Is(err, invitations.ErrPasswordRequired):
+		res = status.Error(codes.InvalidArgument, "password required for new account")
+	case errors.Is(err, invitations.ErrRoleNotFound):
+		res = status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, invitations.ErrBusy):
+		res = status.Error(codes.Unavailable, "service is busy, retry later")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
@@ -0,0 +1,157 @@
+package orgs
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/orgs"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+	// platformOrgID is organization whose admins manage every organization,
+	// admins of other organizations only see and rename their own
+	platformOrgID = models.DefaultOrgID
+)
+
+var Access = interceptors.Methods{
+	api.OrgsService: interceptors.AdminOnly,
+}
+
+type Orgs interface {
+	CreateOrg(ctx context.Context, name string) (int32, error)
+	Org(ctx context.Context, orgID int) (models.Organization, error)
+	ListOrgs(ctx context.Context) ([]models.Organization, error)
+	RenameOrg(ctx context.Context, orgID int, name string) error
+	DeleteOrg(ctx context.Context, orgID int) error
+}
+
+type handler struct {
+	orgs Orgs
+}
+
+func Register(grpcSrv *grpc.Server, orgs Orgs) {
+	api.RegisterOrgsServer(grpcSrv, &handler{orgs: orgs})
+}
+
+func (h handler) CreateOrg(ctx context.Context, request *api.CreateOrgRequest) (*api.CreateOrgResponse, error) {
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty name")
+	}
+	if err := checkOrg(ctx, EmptyValue); err != nil {
+		return nil, err
+	}
+
+	id, err := h.orgs.CreateOrg(ctx, request.Name)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.CreateOrgResponse{Id: id}, nil
+}
+
+func (h handler) GetOrg(ctx context.Context, request *api.GetOrgRequest) (*api.Org, error) {
+	if request.OrgId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty org_id")
+	}
+	if err := checkOrg(ctx, request.OrgId); err != nil {
+		return nil, err
+	}
+
+	org, err := h.orgs.Org(ctx, int(request.OrgId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Org{Id: org.Id, Name: org.Name, CreatedAt: org.CreatedAt}, nil
+}
+
+func (h handler) ListOrgs(ctx context.Context, _ *api.Empty) (*api.ListOrgsResponse, error) {
+	if err := checkOrg(ctx, EmptyValue); err != nil {
+		return nil, err
+	}
+
+	list, err := h.orgs.ListOrgs(ctx)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListOrgsResponse{Orgs: make([]api.Org, 0, len(list))}
+	for _, org := range list {
+		res.Orgs = append(res.Orgs, api.Org{Id: org.Id, Name: org.Name, CreatedAt: org.CreatedAt})
+	}
+
+	return res, nil
+}
+
+func (h handler) RenameOrg(ctx context.Context, request *api.RenameOrgRequest) (*api.Empty, error) {
+	if request.OrgId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty org_id")
+	}
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty name")
+	}
+	if err := checkOrg(ctx, request.OrgId); err != nil {
+		return nil, err
+	}
+
+	if err := h.orgs.RenameOrg(ctx, int(request.OrgId), request.Name); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) DeleteOrg(ctx context.Context, request *api.DeleteOrgRequest) (*api.Empty, error) {
+	if request.OrgId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty org_id")
+	}
+	if err := checkOrg(ctx, EmptyValue); err != nil {
+		return nil, err
+	}
+
+	if err := h.orgs.DeleteOrg(ctx, int(request.OrgId)); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+// checkOrg lets platform admins manage any organization and other admins only their own,
+// empty orgID means the method is for platform admins only
+func checkOrg(ctx context.Context, orgID int32) error {
+	admin, _ := interceptors.CallerFromContext(ctx)
+	if admin.OrgID == platformOrgID || (orgID != EmptyValue && admin.OrgID == orgID) {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, "access to organization denied")
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, orgs.ErrOrgExists):
+		res = status.Error(codes.AlreadyExists, "organization already exists")
+	case errors.Is(err, orgs.ErrOrgNotFound):
+		res = status.Error(codes.NotFound, "organization not found")
+	case errors.Is(err, orgs.ErrOrgNotEmpty):
+		res = status.Error(codes.FailedPrecondition, "organization has users, apps or relation tuples")
+	case errors.Is(err, orgs.ErrDefaultOrg):
+		res = status.Error(codes.FailedPrecondition, "default organization can't be deleted")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
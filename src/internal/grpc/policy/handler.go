@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"context"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/policy"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+var Access = interceptors.Methods{
+	api.PolicyService: interceptors.AdminOnly,
+}
+
+type Policy interface {
+	SetAppPolicies(ctx context.Context, orgID int, appID int, loginPolicy string, claimsPolicy string) error
+	DryRun(
+		ctx context.Context,
+		orgID int,
+		appID int,
+		userID int64,
+		loginPolicy string,
+		claimsPolicy string,
+	) (policy.DryRunResult, error)
+}
+
+type handler struct {
+	policy Policy
+}
+
+func Register(grpcSrv *grpc.Server, policy Policy) {
+	api.RegisterPolicyServer(grpcSrv, &handler{policy: policy})
+}
+
+func (h handler) SetAppPolicies(ctx context.Context, request *api.SetAppPoliciesRequest) (*api.Empty, error) {
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+
+	admin, _ := interceptors.CallerFromContext(ctx)
+
+	err := h.policy.SetAppPolicies(
+		ctx,
+		int(admin.OrgID),
+		int(request.AppId),
+		request.LoginPolicy,
+		request.ClaimsPolicy,
+	)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) DryRun(ctx context.Context, request *api.DryRunRequest) (*api.DryRunResponse, error) {
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+
+	admin, _ := interceptors.CallerFromContext(ctx)
+
+	res, err := h.policy.DryRun(
+		ctx,
+		int(admin.OrgID),
+		int(request.AppId),
+		request.UserId,
+		request.LoginPolicy,
+		request.ClaimsPolicy,
+	)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.DryRunResponse{Allowed: res.Allowed, Claims: res.Claims}, nil
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, policy.ErrInvalidPolicy):
+		res = status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, policy.ErrInvalidApp):
+		res = status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, policy.ErrUserNotFound):
+		res = status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
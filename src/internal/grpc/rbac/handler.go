@@ -0,0 +1,155 @@
+package rbac
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/rbac"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+// Access lets users look into own roles, managing them is up to admins
+var Access = interceptors.Methods{
+	api.RBACService:                            interceptors.AdminOnly,
+	"/" + api.RBACService + "/ListRoles":       interceptors.UserOnly,
+	"/" + api.RBACService + "/CheckPermission": interceptors.UserOnly,
+}
+
+type RBAC interface {
+	AssignRole(ctx context.Context, orgID int, userID int64, appID int, role string) error
+	RevokeRole(ctx context.Context, orgID int, userID int64, appID int, role string) error
+	ListRoles(ctx context.Context, orgID int, userID int64, appID int) ([]models.Role, error)
+	CheckPermission(ctx context.Context, orgID int, userID int64, appID int, permission string) (bool, error)
+}
+
+type handler struct {
+	rbac RBAC
+}
+
+func Register(grpcSrv *grpc.Server, rbac RBAC) {
+	api.RegisterRBACServer(grpcSrv, &handler{rbac: rbac})
+}
+
+func (h handler) AssignRole(ctx context.Context, request *api.AssignRoleRequest) (*api.Empty, error) {
+	if err := validateRole(request.UserId, request.AppId, request.Role); err != nil {
+		return nil, err
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	if err := h.rbac.AssignRole(ctx, int(caller.OrgID), request.UserId, int(request.AppId), request.Role); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) RevokeRole(ctx context.Context, request *api.RevokeRoleRequest) (*api.Empty, error) {
+	if err := validateRole(request.UserId, request.AppId, request.Role); err != nil {
+		return nil, err
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	if err := h.rbac.RevokeRole(ctx, int(caller.OrgID), request.UserId, int(request.AppId), request.Role); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func validateRole(userID int64, appID int32, role string) error {
+	if userID == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if appID == EmptyValue {
+		return status.Error(codes.InvalidArgument, "empty app_id")
+	}
+	if role == "" {
+		return status.Error(codes.InvalidArgument, "empty role")
+	}
+
+	return nil
+}
+
+func (h handler) ListRoles(ctx context.Context, request *api.ListRolesRequest) (*api.ListRolesResponse, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+	if err := interceptors.CheckAccess(ctx, request.UserId); err != nil {
+		return nil, err
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	roles, err := h.rbac.ListRoles(ctx, int(caller.OrgID), request.UserId, int(request.AppId))
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListRolesResponse{Roles: make([]api.Role, 0, len(roles))}
+	for _, r := range roles {
+		res.Roles = append(res.Roles, api.Role{Name: r.Name, Permissions: r.Permissions})
+	}
+
+	return res, nil
+}
+
+func (h handler) CheckPermission(
+	ctx context.Context,
+	request *api.CheckPermissionRequest,
+) (*api.CheckPermissionResponse, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if request.AppId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty app_id")
+	}
+	if request.Permission == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty permission")
+	}
+	if err := interceptors.CheckAccess(ctx, request.UserId); err != nil {
+		return nil, err
+	}
+
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	allowed, err := h.rbac.CheckPermission(ctx, int(caller.OrgID), request.UserId, int(request.AppId), request.Permission)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.CheckPermissionResponse{Allowed: allowed}, nil
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, rbac.ErrUserNotFound):
+		res = status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, rbac.ErrRoleNotFound):
+		res = status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, rbac.ErrRoleNotAssigned):
+		res = status.Error(codes.NotFound, "role not assigned")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
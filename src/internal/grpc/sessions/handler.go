@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/services/sessions"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+// Access lets users manage own sessions, admins manage sessions of their organization
+var Access = interceptors.Methods{
+	api.SessionsService: interceptors.UserOnly,
+}
+
+type Sessions interface {
+	ListSessions(ctx context.Context, orgID int, userID int64) ([]models.Session, error)
+	RevokeSession(ctx context.Context, orgID int, userID int64, sessionID string) error
+}
+
+type handler struct {
+	sessions Sessions
+}
+
+func Register(grpcSrv *grpc.Server, sessions Sessions) {
+	api.RegisterSessionsServer(grpcSrv, &handler{sessions: sessions})
+}
+
+func (h handler) ListSessions(
+	ctx context.Context,
+	request *api.ListSessionsRequest,
+) (*api.ListSessionsResponse, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if err := interceptors.CheckAccess(ctx, request.UserId); err != nil {
+		return nil, err
+	}
+
+	list, err := h.sessions.ListSessions(ctx, callerOrg(ctx), request.UserId)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	res := &api.ListSessionsResponse{Sessions: make([]api.Session, 0, len(list))}
+	for _, s := range list {
+		res.Sessions = append(res.Sessions, api.Session{
+			Id:         s.Id,
+			AppId:      s.AppId,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+
+	return res, nil
+}
+
+func (h handler) RevokeSession(ctx context.Context, request *api.RevokeSessionRequest) (*api.Empty, error) {
+	if request.UserId == EmptyValue {
+		return nil, status.Error(codes.InvalidArgument, "empty user_id")
+	}
+	if request.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty session_id")
+	}
+	if err := interceptors.CheckAccess(ctx, request.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := h.sessions.RevokeSession(ctx, callerOrg(ctx), request.UserId, request.SessionId); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+// callerOrg returns organization of the caller, sessions never cross it
+func callerOrg(ctx context.Context) int {
+	caller, _ := interceptors.CallerFromContext(ctx)
+
+	return int(caller.OrgID)
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, sessions.ErrSessionNotFound):
+		res = status.Error(codes.NotFound, "session not found")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
@@ -0,0 +1,135 @@
+package tokens
+
+import (
+	"context"
+	"domofon/internal/grpc/api"
+	"domofon/internal/grpc/interceptors"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/lib/tokens"
+	tokenservice "domofon/internal/services/tokens"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	EmptyValue = 0
+)
+
+// Access keeps token endpoints public for users, Introspect and Revoke authenticate
+// the calling app themselves like RFC 7662 and RFC 7009 require
+var Access = interceptors.Methods{
+	api.TokensService: interceptors.Public,
+}
+
+type Tokens interface {
+	Introspect(ctx context.Context, client tokenservice.Client, token string) (tokens.Introspection, error)
+	Revoke(ctx context.Context, client tokenservice.Client, token string) error
+	Validate(ctx context.Context, token string, proof string, method string, uri string) (jwt.Claims, error)
+}
+
+type handler struct {
+	tokens Tokens
+}
+
+func Register(grpcSrv *grpc.Server, tokens Tokens) {
+	api.RegisterTokensServer(grpcSrv, &handler{tokens: tokens})
+}
+
+func (h handler) Introspect(ctx context.Context, request *api.IntrospectRequest) (*api.IntrospectResponse, error) {
+	if request.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty token")
+	}
+	if request.ClientId == EmptyValue {
+		return nil, status.Error(codes.Unauthenticated, "client authentication required")
+	}
+
+	res, err := h.tokens.Introspect(ctx, client(request.ClientCredentials), request.Token)
+	if err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.IntrospectResponse{Active: res.Active, Claims: res.Claims}, nil
+}
+
+func (h handler) Revoke(ctx context.Context, request *api.RevokeRequest) (*api.Empty, error) {
+	if request.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty token")
+	}
+	if request.ClientId == EmptyValue {
+		return nil, status.Error(codes.Unauthenticated, "client authentication required")
+	}
+
+	if err := h.tokens.Revoke(ctx, client(request.ClientCredentials), request.Token); err != nil {
+		return nil, printError(err)
+	}
+
+	return &api.Empty{}, nil
+}
+
+func (h handler) Validate(ctx context.Context, request *api.ValidateRequest) (*api.ValidateResponse, error) {
+	if request.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty token")
+	}
+	if request.DPoPProof != "" && (request.Method == "" || request.Url == "") {
+		return nil, status.Error(codes.InvalidArgument, "method and url of dpop proof required")
+	}
+
+	claims, err := h.tokens.Validate(ctx, request.Token, request.DPoPProof, request.Method, request.Url)
+	if err != nil {
+		if errors.Is(err, tokenservice.ErrInvalidToken) {
+			return &api.ValidateResponse{}, nil
+		}
+
+		return nil, printError(err)
+	}
+
+	return &api.ValidateResponse{
+		Active:    true,
+		UserId:    claims.UserID,
+		AppId:     claims.AppID,
+		OrgId:     claims.OrgID,
+		SessionId: claims.SessionID,
+		ACR:       claims.ACR,
+		JKT:       claims.JKT,
+		X5T:       claims.X5T,
+	}, nil
+}
+
+func client(c api.ClientCredentials) tokenservice.Client {
+	return tokenservice.Client{AppID: c.ClientId, Secret: c.ClientSecret}
+}
+
+func printError(err error) error {
+	var res error
+
+	switch {
+	case errors.Is(err, tokenservice.ErrInvalidClient):
+		res = status.Error(codes.Unauthenticated, "invalid client")
+	case errors.Is(err, tokenservice.ErrInvalidToken):
+		res = status.Error(codes.NotFound, "token not found or revoked")
+	case errors.Is(err, tokenservice.ErrNotRevocable):
+		res = status.Error(codes.FailedPrecondition, "only opaque tokens can be revoked")
+	case errors.Is(err, tokenservice.ErrFormatUnavailable):
+		res = status.Error(codes.FailedPrecondition, "token format isn't configured")
+	case errors.Is(err, tokenservice.ErrProofRequired):
+		res = status.Error(codes.InvalidArgument, "dpop proof required for bound token")
+	case errors.Is(err, tokenservice.ErrInvalidProof):
+		res = status.Error(codes.InvalidArgument, "invalid dpop proof")
+	case errors.Is(err, tokenservice.ErrUseNonce):
+		res = status.Error(codes.Unauthenticated, "use_dpop_nonce")
+	case errors.Is(err, tokenservice.ErrBusy):
+		res = status.Error(codes.Unavailable, "service is busy")
+	case errors.Is(err, tokenservice.ErrEncrypted):
+		res = status.Error(codes.FailedPrecondition, "encrypted token is readable only by its app")
+	case errors.Is(err, context.DeadlineExceeded):
+		res = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		res = status.Error(codes.Canceled, "request canceled")
+	default:
+		res = status.Error(codes.Internal, "internal error")
+	}
+
+	return res
+}
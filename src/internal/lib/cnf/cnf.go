@@ -0,0 +1,63 @@
+package cnf
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+)
+
+type confirmationKey struct{}
+
+// Confirmation is key the client proved to hold, tokens issued in the request are bound to it (RFC 7800)
+type Confirmation struct {
+	// JKT is SHA-256 thumbprint of DPoP proof key
+	JKT string
+	// X5T is SHA-256 thumbprint of TLS client certificate (RFC 8705)
+	X5T string
+}
+
+// WithJKT returns ctx carrying thumbprint of verified DPoP key
+func WithJKT(ctx context.Context, jkt string) context.Context {
+	c := FromContext(ctx)
+	c.JKT = jkt
+
+	return context.WithValue(ctx, confirmationKey{}, c)
+}
+
+// WithX5T returns ctx carrying thumbprint of TLS client certificate
+func WithX5T(ctx context.Context, x5t string) context.Context {
+	c := FromContext(ctx)
+	c.X5T = x5t
+
+	return context.WithValue(ctx, confirmationKey{}, c)
+}
+
+// FromContext returns confirmation of the current request, empty when client proved nothing
+func FromContext(ctx context.Context) Confirmation {
+	c, _ := ctx.Value(confirmationKey{}).(Confirmation)
+	return c
+}
+
+// Claim returns cnf claim, nil for empty confirmation
+func (c Confirmation) Claim() map[string]any {
+	if c.JKT == "" && c.X5T == "" {
+		return nil
+	}
+
+	claim := make(map[string]any, 2)
+	if c.JKT != "" {
+		claim["jkt"] = c.JKT
+	}
+	if c.X5T != "" {
+		claim["x5t#S256"] = c.X5T
+	}
+
+	return claim
+}
+
+// Thumbprint returns x5t#S256 of certificate, base64url SHA-256 of its DER
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
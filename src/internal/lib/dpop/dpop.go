@@ -0,0 +1,229 @@
+package dpop
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	proofType = "dpop+jwt"
+	// maxProofs bounds replay cache, proofs above it are refused until old ones expire
+	maxProofs = 100_000
+	nonceMAC  = 16
+)
+
+var (
+	ErrInvalidProof = errors.New("invalid dpop proof")
+	// ErrUseNonce asks client to retry with the nonce from dpop-nonce header
+	ErrUseNonce = errors.New("use_dpop_nonce")
+	ErrReplay   = errors.New("dpop proof replayed")
+	ErrBusy     = errors.New("too many dpop proofs")
+	// ErrInvalidWindow is non-positive nonce ttl or proof max age
+	ErrInvalidWindow = errors.New("dpop nonce ttl and proof max age must be positive")
+)
+
+var proofAlgs = []string{"ES256", "ES384", "ES512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "EdDSA"}
+
+var encoding = base64.RawURLEncoding
+
+// Verifier checks DPoP proofs of RFC 9449, it remembers proof ids to refuse replays
+type Verifier struct {
+	nonceKey     []byte
+	nonceTTL     time.Duration
+	maxAge       time.Duration
+	requireNonce bool
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewVerifier returns Verifier accepting proofs issued at most maxAge ago or ahead.
+// Nonces are valid for nonceTTL, random nonceKey is used when empty,
+// then nonces aren't shared between instances
+func NewVerifier(nonceKey []byte, nonceTTL time.Duration, maxAge time.Duration, requireNonce bool) (*Verifier, error) {
+	// nonce windows are numbered by nonceTTL, proofs of zero age would never pass
+	if nonceTTL <= 0 || maxAge <= 0 {
+		return nil, ErrInvalidWindow
+	}
+
+	if len(nonceKey) == 0 {
+		nonceKey = make([]byte, 32)
+		if _, err := rand.Read(nonceKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Verifier{
+		nonceKey:     nonceKey,
+		nonceTTL:     nonceTTL,
+		maxAge:       maxAge,
+		requireNonce: requireNonce,
+		seen:         make(map[string]time.Time),
+	}, nil
+}
+
+// Nonce returns nonce for clients to put into their next proofs
+func (v *Verifier) Nonce() string {
+	return v.nonce(v.window(time.Now()))
+}
+
+// Verify checks proof of request with method and uri and returns thumbprint of its key.
+// With accessToken the proof must be bound to it by ath claim
+func (v *Verifier) Verify(proof string, method string, uri string, accessToken string) (string, error) {
+	var jwk jose.JSONWebKey
+
+	token, err := jwt.Parse(
+		proof,
+		func(token *jwt.Token) (interface{}, error) {
+			if token.Header["typ"] != proofType {
+				return nil, fmt.Errorf("typ must be %s", proofType)
+			}
+
+			raw, err := json.Marshal(token.Header["jwk"])
+			if err != nil {
+				return nil, err
+			}
+			if err = jwk.UnmarshalJSON(raw); err != nil {
+				return nil, err
+			}
+			if !jwk.IsPublic() {
+				return nil, errors.New("jwk must be public key")
+			}
+
+			return jwk.Key, nil
+		},
+		jwt.WithValidMethods(proofAlgs),
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	now := time.Now()
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return "", fmt.Errorf("%w: jti is required", ErrInvalidProof)
+	}
+	if claims["htm"] != method {
+		return "", fmt.Errorf("%w: htm mismatch", ErrInvalidProof)
+	}
+	htu, _ := claims["htu"].(string)
+	if !sameURI(htu, uri) {
+		return "", fmt.Errorf("%w: htu mismatch", ErrInvalidProof)
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return "", fmt.Errorf("%w: iat is required", ErrInvalidProof)
+	}
+	if iat.Before(now.Add(-v.maxAge)) || iat.After(now.Add(v.maxAge)) {
+		return "", fmt.Errorf("%w: iat out of range", ErrInvalidProof)
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		ath, _ := claims["ath"].(string)
+		if subtle.ConstantTimeCompare([]byte(ath), []byte(encoding.EncodeToString(sum[:]))) != 1 {
+			return "", fmt.Errorf("%w: ath mismatch", ErrInvalidProof)
+		}
+	}
+
+	nonce, hasNonce := claims["nonce"].(string)
+	if hasNonce && !v.validNonce(nonce, now) || !hasNonce && v.requireNonce {
+		return "", ErrUseNonce
+	}
+
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	jkt := encoding.EncodeToString(thumbprint)
+
+	if err = v.remember(jkt+"."+jti, now); err != nil {
+		return "", err
+	}
+
+	return jkt, nil
+}
+
+// remember refuses proof id seen within its acceptance window
+func (v *Verifier) remember(id string, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if until, ok := v.seen[id]; ok && now.Before(until) {
+		return ErrReplay
+	}
+
+	if len(v.seen) >= maxProofs {
+		for k, until := range v.seen {
+			if !now.Before(until) {
+				delete(v.seen, k)
+			}
+		}
+		if len(v.seen) >= maxProofs {
+			return ErrBusy
+		}
+	}
+
+	// proof is accepted while its iat is within maxAge either way
+	v.seen[id] = now.Add(2 * v.maxAge)
+
+	return nil
+}
+
+func (v *Verifier) window(t time.Time) uint64 {
+	return uint64(t.UnixNano() / int64(v.nonceTTL))
+}
+
+// nonce is window number with its mac, so nonces need no server state
+func (v *Verifier) nonce(window uint64) string {
+	b := binary.BigEndian.AppendUint64(nil, window)
+
+	mac := hmac.New(sha256.New, v.nonceKey)
+	mac.Write(b)
+
+	return encoding.EncodeToString(append(b, mac.Sum(nil)[:nonceMAC]...))
+}
+
+// validNonce accepts nonces of current and previous window
+func (v *Verifier) validNonce(nonce string, now time.Time) bool {
+	current := v.window(now)
+	for _, w := range []uint64{current, current - 1} {
+		if hmac.Equal([]byte(nonce), []byte(v.nonce(w))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameURI compares uris without query and fragment
+func sameURI(a string, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) && ua.Path == ub.Path
+}
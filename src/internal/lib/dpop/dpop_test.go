@@ -0,0 +1,236 @@
+package dpop
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const (
+	testMethod = "POST"
+	testURI    = "https://localhost/domofon.Tokens/Validate"
+	testToken  = "access-token"
+)
+
+type prover struct {
+	key *ecdsa.PrivateKey
+	jwk map[string]any
+	jkt string
+}
+
+func newProver(t *testing.T) prover {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pub := jose.JSONWebKey{Key: &key.PublicKey}
+	raw, err := pub.MarshalJSON()
+	require.NoError(t, err)
+	var jwk map[string]any
+	require.NoError(t, json.Unmarshal(raw, &jwk))
+
+	thumbprint, err := pub.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+
+	return prover{key: key, jwk: jwk, jkt: encoding.EncodeToString(thumbprint)}
+}
+
+// proof returns proof of testMethod request to testURI bound to testToken, edit changes it before signing
+func (p prover) proof(t *testing.T, edit func(header map[string]any, claims jwt.MapClaims)) string {
+	t.Helper()
+
+	jti := make([]byte, 16)
+	_, err := rand.Read(jti)
+	require.NoError(t, err)
+
+	ath := sha256.Sum256([]byte(testToken))
+	claims := jwt.MapClaims{
+		"jti": encoding.EncodeToString(jti),
+		"htm": testMethod,
+		"htu": testURI,
+		"iat": time.Now().Unix(),
+		"ath": encoding.EncodeToString(ath[:]),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = proofType
+	token.Header["jwk"] = p.jwk
+	if edit != nil {
+		edit(token.Header, claims)
+	}
+
+	signed, err := token.SignedString(p.key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func newTestVerifier(t *testing.T, requireNonce bool) *Verifier {
+	t.Helper()
+
+	v, err := NewVerifier([]byte("nonce-key"), time.Minute, time.Minute, requireNonce)
+	require.NoError(t, err)
+
+	return v
+}
+
+func TestNewVerifier_window(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonceTTL time.Duration
+		maxAge   time.Duration
+	}{
+		{name: "zero nonce ttl", nonceTTL: 0, maxAge: time.Minute},
+		{name: "negative nonce ttl", nonceTTL: -time.Minute, maxAge: time.Minute},
+		{name: "zero max age", nonceTTL: time.Minute, maxAge: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(nil, tt.nonceTTL, tt.maxAge, false)
+			assert.ErrorIs(t, err, ErrInvalidWindow)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p := newProver(t)
+
+	tests := []struct {
+		name         string
+		requireNonce bool
+		edit         func(v *Verifier, header map[string]any, claims jwt.MapClaims)
+		method       string
+		uri          string
+		wantErr      error
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "htu ignores query",
+			uri:  testURI + "?door=1",
+		},
+		{
+			name:    "wrong htm",
+			method:  "GET",
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name:    "wrong htu",
+			uri:     "https://localhost/domofon.Tokens/Revoke",
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name: "bad typ",
+			edit: func(_ *Verifier, header map[string]any, _ jwt.MapClaims) {
+				header["typ"] = "JWT"
+			},
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name: "no jti",
+			edit: func(_ *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				delete(claims, "jti")
+			},
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name: "old iat",
+			edit: func(_ *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				claims["iat"] = time.Now().Add(-2 * time.Minute).Unix()
+			},
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name: "future iat",
+			edit: func(_ *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				claims["iat"] = time.Now().Add(2 * time.Minute).Unix()
+			},
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name: "ath mismatch",
+			edit: func(_ *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				claims["ath"] = "other"
+			},
+			wantErr: ErrInvalidProof,
+		},
+		{
+			name:         "nonce required",
+			requireNonce: true,
+			wantErr:      ErrUseNonce,
+		},
+		{
+			name:         "issued nonce",
+			requireNonce: true,
+			edit: func(v *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				claims["nonce"] = v.Nonce()
+			},
+		},
+		{
+			name: "invalid nonce",
+			edit: func(_ *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				claims["nonce"] = "forged"
+			},
+			wantErr: ErrUseNonce,
+		},
+		{
+			name: "expired nonce",
+			edit: func(v *Verifier, _ map[string]any, claims jwt.MapClaims) {
+				claims["nonce"] = v.nonce(v.window(time.Now()) - 2)
+			},
+			wantErr: ErrUseNonce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, tt.requireNonce)
+
+			proof := p.proof(t, func(header map[string]any, claims jwt.MapClaims) {
+				if tt.edit != nil {
+					tt.edit(v, header, claims)
+				}
+			})
+
+			method, uri := testMethod, testURI
+			if tt.method != "" {
+				method = tt.method
+			}
+			if tt.uri != "" {
+				uri = tt.uri
+			}
+
+			jkt, err := v.Verify(proof, method, uri, testToken)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, p.jkt, jkt)
+		})
+	}
+}
+
+func TestVerify_replay(t *testing.T) {
+	p := newProver(t)
+	v := newTestVerifier(t, false)
+	proof := p.proof(t, nil)
+
+	_, err := v.Verify(proof, testMethod, testURI, testToken)
+	require.NoError(t, err)
+
+	_, err = v.Verify(proof, testMethod, testURI, testToken)
+	assert.ErrorIs(t, err, ErrReplay)
+}
@@ -0,0 +1,202 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// prefix marks sealed values, anything else is legacy plaintext
+	prefix  = "enc:v1:"
+	keySize = 32
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown master key")
+	ErrInvalidKey = errors.New("invalid master key")
+	ErrMalformed  = errors.New("malformed sealed value")
+	ErrDecryption = errors.New("failed decrypting sealed value")
+	ErrNoPrimary  = errors.New("primary master key isn't in keyring")
+	ErrPlaintext  = errors.New("value isn't sealed")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Keyring seals values of apps with a fresh data key wrapped by the primary master key,
+// the app id is authenticated so a value can't be moved to another app.
+// Older master keys are kept only to open values sealed before rotation
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+	// allowPlaintext opens legacy plaintext values as is, only while migrating them
+	allowPlaintext bool
+}
+
+// NewKeyring returns keyring of base64 encoded 256-bit master keys by their ids.
+// allowPlaintext lets Open return values stored before encryption was enabled
+func NewKeyring(primary string, keys map[string]string, allowPlaintext bool) (*Keyring, error) {
+	k := &Keyring{
+		primary:        primary,
+		keys:           make(map[string]cipher.AEAD, len(keys)),
+		allowPlaintext: allowPlaintext,
+	}
+
+	for id, raw := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: id %q", ErrInvalidKey, id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("%w: %q must be %d base64 encoded bytes", ErrInvalidKey, id, keySize)
+		}
+
+		k.keys[id], err = newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := k.keys[primary]; !ok {
+		return nil, ErrNoPrimary
+	}
+
+	return k, nil
+}
+
+// IsSealed reports whether value was produced by Seal
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Seal encrypts plaintext of app as enc:v1:<key id>:<wrapped data key>:<ciphertext>
+func (k *Keyring) Seal(appID int32, plaintext string) (string, error) {
+	aad := associatedData(appID)
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(k.keys[k.primary], dataKey, aad)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + k.primary + ":" + encoding.EncodeToString(wrapped) + ":" + encoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts value of app sealed with any key of keyring.
+// Legacy plaintext is returned as is only when keyring allows it
+func (k *Keyring) Open(appID int32, value string) (string, error) {
+	if !IsSealed(value) {
+		if !k.allowPlaintext {
+			return "", ErrPlaintext
+		}
+
+		return value, nil
+	}
+
+	id, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	master, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	aad := associatedData(appID)
+	dataKey, err := open(master, wrapped, aad)
+	if err != nil {
+		return "", ErrDecryption
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, ciphertext, aad)
+	if err != nil {
+		return "", ErrDecryption
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsReseal reports whether value is plaintext or sealed with non-primary key
+func (k *Keyring) NeedsReseal(value string) bool {
+	if !IsSealed(value) {
+		return true
+	}
+
+	id, _, _, err := parse(value)
+
+	return err != nil || id != k.primary
+}
+
+func parse(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+
+	wrapped, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	ciphertext, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return parts[0], wrapped, ciphertext, nil
+}
+
+// associatedData binds sealed value to its app
+func associatedData(appID int32) []byte {
+	return []byte("app:" + strconv.FormatInt(int64(appID), 10))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal prepends random nonce to ciphertext
+func seal(aead cipher.AEAD, plaintext []byte, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte, aad []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], aad)
+}
@@ -0,0 +1,137 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var (
+	oldKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, keySize))
+	newKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, keySize))
+)
+
+func newKeyring(t *testing.T, primary string, keys map[string]string, allowPlaintext bool) *Keyring {
+	t.Helper()
+
+	k, err := NewKeyring(primary, keys, allowPlaintext)
+	require.NoError(t, err)
+
+	return k
+}
+
+func TestKeyring_roundTrip(t *testing.T) {
+	k := newKeyring(t, "k1", map[string]string{"k1": oldKey}, false)
+
+	sealed, err := k.Seal(1, "secret")
+	require.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, sealed, "secret")
+	assert.False(t, k.NeedsReseal(sealed))
+
+	opened, err := k.Open(1, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+
+	again, err := k.Seal(1, "secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+}
+
+func TestKeyring_rotation(t *testing.T) {
+	before := newKeyring(t, "k1", map[string]string{"k1": oldKey}, false)
+	sealed, err := before.Seal(1, "secret")
+	require.NoError(t, err)
+
+	after := newKeyring(t, "k2", map[string]string{"k1": oldKey, "k2": newKey}, false)
+	require.True(t, after.NeedsReseal(sealed))
+
+	opened, err := after.Open(1, sealed)
+	require.NoError(t, err)
+	resealed, err := after.Seal(1, opened)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(resealed, prefix+"k2:"))
+	assert.False(t, after.NeedsReseal(resealed))
+
+	retired := newKeyring(t, "k2", map[string]string{"k2": newKey}, false)
+	opened, err = retired.Open(1, resealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+}
+
+func TestKeyring_Open(t *testing.T) {
+	k := newKeyring(t, "k1", map[string]string{"k1": oldKey}, false)
+	sealed, err := k.Seal(1, "secret")
+	require.NoError(t, err)
+
+	// change a ciphertext char away from the end, the last one may only carry padding bits
+	tampered := []byte(sealed)
+	i := len(tampered) - 8
+	if tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		appID   int32
+		value   string
+		wantErr error
+	}{
+		{
+			name:    "unknown key",
+			keyring: newKeyring(t, "k2", map[string]string{"k2": newKey}, false),
+			appID:   1,
+			value:   sealed,
+			wantErr: ErrUnknownKey,
+		},
+		{name: "tampered ciphertext", keyring: k, appID: 1, value: string(tampered), wantErr: ErrDecryption},
+		{name: "other app", keyring: k, appID: 2, value: sealed, wantErr: ErrDecryption},
+		{name: "malformed", keyring: k, appID: 1, value: prefix + "k1:AAAA", wantErr: ErrMalformed},
+		{name: "plaintext", keyring: k, appID: 1, value: "secret", wantErr: ErrPlaintext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.keyring.Open(tt.appID, tt.value)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestKeyring_allowPlaintext(t *testing.T) {
+	k := newKeyring(t, "k1", map[string]string{"k1": oldKey}, true)
+
+	opened, err := k.Open(1, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+	assert.True(t, k.NeedsReseal("secret"))
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary string
+		keys    map[string]string
+		wantErr error
+	}{
+		{name: "no primary", primary: "k2", keys: map[string]string{"k1": oldKey}, wantErr: ErrNoPrimary},
+		{name: "short key", primary: "k1", keys: map[string]string{"k1": "AAAA"}, wantErr: ErrInvalidKey},
+		{name: "id with colon", primary: "k:1", keys: map[string]string{"k:1": oldKey}, wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.primary, tt.keys, false)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
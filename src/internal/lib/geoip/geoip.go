@@ -0,0 +1,150 @@
+package geoip
+
+import (
+	"domofon/internal/domain/models"
+	"errors"
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"math"
+	"net"
+	"time"
+)
+
+const (
+	earthRadiusKm = 6371.0
+)
+
+// Resolver looks up location of IP in local MaxMind-format databases, nothing leaves the host
+type Resolver struct {
+	city *maxminddb.Reader
+	asn  *maxminddb.Reader
+}
+
+// Open returns Resolver of city and ASN database files. Either path may be empty,
+// without both the resolver knows no locations
+func Open(cityPath string, asnPath string) (*Resolver, error) {
+	const op = "geoip.open"
+
+	r := &Resolver{}
+
+	var err error
+	if cityPath != "" {
+		if r.city, err = maxminddb.Open(cityPath); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+	}
+	if asnPath != "" {
+		if r.asn, err = maxminddb.Open(asnPath); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Resolver) Close() error {
+	var errs []error
+	if r.city != nil {
+		errs = append(errs, r.city.Close())
+	}
+	if r.asn != nil {
+		errs = append(errs, r.asn.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Lookup returns location of ip, zero location for unknown and private addresses
+func (r *Resolver) Lookup(ip string) models.GeoLocation {
+	var loc models.GeoLocation
+
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsPrivate() || addr.IsLoopback() {
+		return loc
+	}
+
+	// lookup errors mean corrupted record, location is only context so it's left unknown
+	if r.city != nil {
+		var rec cityRecord
+		if err := r.city.Lookup(addr, &rec); err == nil {
+			loc.Country = rec.Country.ISOCode
+			loc.City = rec.City.Names["en"]
+			if rec.Location.Latitude != nil && rec.Location.Longitude != nil {
+				loc.Latitude = *rec.Location.Latitude
+				loc.Longitude = *rec.Location.Longitude
+				loc.HasCoordinates = true
+			}
+		}
+	}
+	if r.asn != nil {
+		var rec asnRecord
+		if err := r.asn.Lookup(addr, &rec); err == nil {
+			loc.ASN = rec.Number
+			loc.ASOrg = rec.Organization
+		}
+	}
+
+	return loc
+}
+
+// TravelPolicy tells which moves between logins are impossible
+type TravelPolicy struct {
+	// MaxSpeed is fastest believable travel in km/h
+	MaxSpeed float64
+	// MinDistance in km is never impossible, GeoIP coordinates are that inaccurate
+	MinDistance float64
+}
+
+// Impossible reports whether getting from prev location at prevTime to cur at curTime
+// needs travel faster than MaxSpeed. Locations without coordinates are never impossible
+func (p TravelPolicy) Impossible(prev models.GeoLocation, prevTime time.Time, cur models.GeoLocation, curTime time.Time) bool {
+	if p.MaxSpeed <= 0 || !prev.HasCoordinates || !cur.HasCoordinates {
+		return false
+	}
+
+	distance := Distance(prev, cur)
+	if distance <= p.MinDistance {
+		return false
+	}
+
+	hours := curTime.Sub(prevTime).Hours()
+	if hours <= 0 {
+		return true
+	}
+
+	return distance/hours > p.MaxSpeed
+}
+
+// Distance returns great-circle distance between locations in km
+func Distance(a models.GeoLocation, b models.GeoLocation) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,48 @@
+package geoip
+
+import (
+	"domofon/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var (
+	moscow = models.GeoLocation{Country: "RU", Latitude: 55.7558, Longitude: 37.6173, HasCoordinates: true}
+	tver   = models.GeoLocation{Country: "RU", Latitude: 56.8587, Longitude: 35.9176, HasCoordinates: true}
+	sydney = models.GeoLocation{Country: "AU", Latitude: -33.8688, Longitude: 151.2093, HasCoordinates: true}
+)
+
+func TestDistance(t *testing.T) {
+	assert.InDelta(t, 14_490, Distance(moscow, sydney), 50)
+	assert.InDelta(t, Distance(moscow, sydney), Distance(sydney, moscow), 1e-9)
+	assert.Zero(t, Distance(moscow, moscow))
+}
+
+func TestTravelPolicy_Impossible(t *testing.T) {
+	policy := TravelPolicy{MaxSpeed: 1000, MinDistance: 200}
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		policy TravelPolicy
+		prev   models.GeoLocation
+		cur    models.GeoLocation
+		since  time.Duration
+		want   bool
+	}{
+		{name: "too fast", policy: policy, prev: moscow, cur: sydney, since: time.Hour, want: true},
+		{name: "enough time", policy: policy, prev: moscow, cur: sydney, since: 24 * time.Hour},
+		{name: "within inaccuracy", policy: policy, prev: moscow, cur: tver, since: time.Minute},
+		{name: "same instant", policy: policy, prev: moscow, cur: sydney, since: 0, want: true},
+		{name: "unknown coordinates", policy: policy, prev: models.GeoLocation{Country: "RU"}, cur: sydney},
+		{name: "disabled", policy: TravelPolicy{MinDistance: 200}, prev: moscow, cur: sydney, since: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Impossible(tt.prev, now.Add(-tt.since), tt.cur, now)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull    = errors.New("hashing queue is full")
+	ErrQueueTimeout = errors.New("hashing queue wait timed out")
+	ErrStopped      = errors.New("hasher stopped")
+)
+
+var (
+	queueDepth    = expvar.NewInt("hasher_queue_depth")
+	queueRejected = expvar.NewInt("hasher_queue_rejected_total")
+	queueTimeouts = expvar.NewInt("hasher_queue_timeouts_total")
+	queueWaitMs   = expvar.NewInt("hasher_queue_wait_ms_total")
+	processed     = expvar.NewInt("hasher_processed_total")
+)
+
+type job struct {
+	ctx      context.Context
+	enqueued time.Time
+	run      func() ([]byte, error)
+	result   chan result
+}
+
+type result struct {
+	hash []byte
+	err  error
+}
+
+// Pool runs bcrypt on a fixed set of workers,
+// so bursts of logins can't take every core from other requests
+type Pool struct {
+	jobs    chan job
+	maxWait time.Duration
+	cost    int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New returns started Pool. With workers <= 0 there is a worker per GOMAXPROCS,
+// the queue holds queuePerWorker jobs for each of them
+func New(workers int, queuePerWorker int, maxWait time.Duration, cost int) *Pool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if queuePerWorker <= 0 {
+		queuePerWorker = 1
+	}
+
+	p := &Pool{
+		jobs:    make(chan job, workers*queuePerWorker),
+		maxWait: maxWait,
+		cost:    cost,
+		stop:    make(chan struct{}),
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *Pool) Hash(ctx context.Context, pass []byte) ([]byte, error) {
+	const op = "hasher.hash"
+
+	hash, err := p.do(ctx, func() ([]byte, error) {
+		return bcrypt.GenerateFromPassword(pass, p.cost)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return hash, nil
+}
+
+// Compare returns bcrypt.ErrMismatchedHashAndPassword when pass doesn't match hash
+func (p *Pool) Compare(ctx context.Context, hash []byte, pass []byte) error {
+	const op = "hasher.compare"
+
+	_, err := p.do(ctx, func() ([]byte, error) {
+		return nil, bcrypt.CompareHashAndPassword(hash, pass)
+	})
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// Depth returns number of jobs waiting for a worker
+func (p *Pool) Depth() int {
+	return len(p.jobs)
+}
+
+// Stop waits for workers to finish jobs already taken from the queue
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
+
+func (p *Pool) do(ctx context.Context, run func() ([]byte, error)) ([]byte, error) {
+	j := job{
+		ctx:      ctx,
+		enqueued: time.Now(),
+		run:      run,
+		result:   make(chan result, 1),
+	}
+
+	select {
+	case <-p.stop:
+		return nil, ErrStopped
+	default:
+	}
+
+	select {
+	case p.jobs <- j:
+		queueDepth.Add(1)
+	default:
+		queueRejected.Add(1)
+		return nil, ErrQueueFull
+	}
+
+	select {
+	case res := <-j.result:
+		return res.hash, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.stop:
+		return nil, ErrStopped
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case j := <-p.jobs:
+			queueDepth.Add(-1)
+			p.handle(j)
+		}
+	}
+}
+
+func (p *Pool) handle(j job) {
+	wait := time.Since(j.enqueued)
+	queueWaitMs.Add(wait.Milliseconds())
+
+	// caller is gone, hashing would only burn cpu
+	if err := j.ctx.Err(); err != nil {
+		j.result <- result{err: err}
+		return
+	}
+	if p.maxWait > 0 && wait > p.maxWait {
+		queueTimeouts.Add(1)
+		j.result <- result{err: ErrQueueTimeout}
+		return
+	}
+
+	hash, err := j.run()
+	processed.Add(1)
+	j.result <- result{hash: hash, err: err}
+}
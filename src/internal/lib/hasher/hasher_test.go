@@ -0,0 +1,161 @@
+package hasher
+
+import (
+	"context"
+	"expvar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+// occupy blocks the only worker of p until returned func is called
+func occupy(t *testing.T, p *Pool) func() {
+	t.Helper()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _ = p.do(context.Background(), func() ([]byte, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("worker didn't take the job")
+	}
+
+	return func() { close(release) }
+}
+
+// enqueue starts job in background and waits until it's queued
+func enqueue(t *testing.T, ctx context.Context, p *Pool) <-chan error {
+	t.Helper()
+
+	depth := p.Depth()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.do(ctx, func() ([]byte, error) { return nil, nil })
+		errs <- err
+	}()
+
+	require.Eventually(t, func() bool { return p.Depth() > depth }, time.Second, time.Millisecond)
+
+	return errs
+}
+
+func TestPool_loadShedding(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		run     func(t *testing.T, p *Pool, release func()) error
+		wantErr error
+		counter *expvar.Int
+	}{
+		{
+			name: "queue full",
+			run: func(t *testing.T, p *Pool, release func()) error {
+				queued := enqueue(t, context.Background(), p)
+
+				_, err := p.do(context.Background(), func() ([]byte, error) { return nil, nil })
+				release()
+				<-queued
+
+				return err
+			},
+			wantErr: ErrQueueFull,
+			counter: queueRejected,
+		},
+		{
+			name:    "queue timeout",
+			maxWait: 10 * time.Millisecond,
+			run: func(t *testing.T, p *Pool, release func()) error {
+				queued := enqueue(t, context.Background(), p)
+				time.Sleep(30 * time.Millisecond)
+				release()
+
+				return <-queued
+			},
+			wantErr: ErrQueueTimeout,
+			counter: queueTimeouts,
+		},
+		{
+			name: "context canceled",
+			run: func(t *testing.T, p *Pool, release func()) error {
+				defer release()
+				ctx, cancel := context.WithCancel(context.Background())
+				queued := enqueue(t, ctx, p)
+				cancel()
+
+				return <-queued
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(1, 1, tt.maxWait, bcrypt.MinCost)
+			defer p.Stop()
+
+			var before int64
+			if tt.counter != nil {
+				before = tt.counter.Value()
+			}
+
+			err := tt.run(t, p, occupy(t, p))
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.counter != nil {
+				assert.Equal(t, before+1, tt.counter.Value())
+			}
+		})
+	}
+}
+
+func TestPool_hashCompare(t *testing.T) {
+	p := New(2, 1, time.Second, bcrypt.MinCost)
+	defer p.Stop()
+
+	before := processed.Value()
+
+	hash, err := p.Hash(context.Background(), []byte("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		pass    string
+		wantErr error
+	}{
+		{name: "match", pass: "secret"},
+		{name: "mismatch", pass: "wrong", wantErr: bcrypt.ErrMismatchedHashAndPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Compare(context.Background(), hash, []byte(tt.pass))
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	assert.Equal(t, before+3, processed.Value())
+	assert.Zero(t, p.Depth())
+}
+
+func TestPool_stopped(t *testing.T) {
+	p := New(1, 1, 0, bcrypt.MinCost)
+	p.Stop()
+
+	_, err := p.Hash(context.Background(), []byte("secret"))
+
+	assert.ErrorIs(t, err, ErrStopped)
+}
@@ -1,18 +1,74 @@
 package jwt
 
 import (
+	"crypto/rand"
 	"domofon/internal/domain/models"
+	"encoding/base64"
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"strconv"
 	"time"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.Id
-	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
-	claims["app"] = app.Id
+var ErrInvalidClaims = errors.New("invalid token claims")
+
+const (
+	// accessTokenType is typ header of ClaimsV2 tokens
+	accessTokenType = "at+jwt"
+	jtiLen          = 16
+)
+
+// reservedClaims are set only by NewToken, even when settings leave them out
+var reservedClaims = map[string]bool{
+	"iss":       true,
+	"sub":       true,
+	"aud":       true,
+	"exp":       true,
+	"nbf":       true,
+	"iat":       true,
+	"jti":       true,
+	"client_id": true,
+	"cnf":       true,
+	"sid":       true,
+	"acr":       true,
+	"amr":       true,
+	"uid":       true,
+	"email":     true,
+	"app":       true,
+	"org":       true,
+	"roles":     true,
+	"admin":     true,
+}
+
+// IsReserved reports whether claim can't be set by static or policy claims
+func IsReserved(claim string) bool {
+	return reservedClaims[claim]
+}
+
+// NewToken returns signed token of user for app, roles are names of user roles in app.
+// ClaimsV2 tokens have at+jwt type
+func NewToken(
+	user models.User,
+	app models.App,
+	roles []models.Role,
+	extra map[string]any,
+	settings models.TokenSettings,
+) (string, error) {
+	claims, err := AccessClaims(user, app, roles, extra, settings)
+	if err != nil {
+		return "", err
+	}
+
+	return Sign(claims, app, settings)
+}
+
+// Sign returns token of claims built by AccessClaims signed with app secret
+func Sign(claims map[string]any, app models.App, settings models.TokenSettings) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(claims))
+	if settings.Claims.Version == models.ClaimsV2 {
+		token.Header["typ"] = accessTokenType
+	}
 
 	tokenStr, err := token.SignedString([]byte(app.Secret))
 	if err != nil {
@@ -21,3 +77,340 @@ func NewToken(user models.User, app models.App, duration time.Duration) (string,
 
 	return tokenStr, nil
 }
+
+// AccessClaims returns claims of access token whatever format it's issued in,
+// times are unix seconds. Settings must have defaults applied,
+// extra claims override static ones of settings.
+// ClaimsV2 tokens identify app by client_id and have it as audience,
+// ClaimsV1 keep uid and app claims for consumers not migrated yet
+func AccessClaims(
+	user models.User,
+	app models.App,
+	roles []models.Role,
+	extra map[string]any,
+	settings models.TokenSettings,
+) (map[string]any, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]any)
+	for k, v := range settings.Claims.Static {
+		claims[k] = v
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	for k := range reservedClaims {
+		delete(claims, k)
+	}
+
+	now := time.Now()
+	if settings.Issuer != "" {
+		claims["iss"] = settings.Issuer
+	}
+	claims["sub"] = strconv.FormatInt(user.Id, 10)
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(settings.AccessTTL).Unix()
+	claims["jti"] = jti
+	claims["org"] = user.OrgId
+
+	switch settings.Claims.Version {
+	case models.ClaimsV2:
+		clientID := strconv.Itoa(int(app.Id))
+		claims["client_id"] = clientID
+		claims["aud"] = append([]string{clientID}, settings.Claims.Audience...)
+	default:
+		claims["uid"] = user.Id
+		claims["app"] = app.Id
+		if len(settings.Claims.Audience) > 0 {
+			claims["aud"] = settings.Claims.Audience
+		}
+	}
+
+	if enabled(settings.Claims.Email) {
+		claims["email"] = user.Email
+	}
+	if enabled(settings.Claims.Roles) {
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			names = append(names, role.Name)
+		}
+		claims["roles"] = names
+	}
+	if enabled(settings.Claims.Admin) {
+		claims["admin"] = user.IsAdmin
+	}
+
+	return claims, nil
+}
+
+func enabled(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+func newJTI() (string, error) {
+	b := make([]byte, jtiLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewInvitationToken returns token of invitation signed with key,
+// nonce makes the token single-use once its hash is stored with the invitation
+func NewInvitationToken(invitationID int64, nonce string, expiresAt time.Time, key []byte) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+	claims["inv"] = invitationID
+	claims["nonce"] = nonce
+	claims["exp"] = expiresAt.Unix()
+
+	return token.SignedString(key)
+}
+
+// ParseInvitationToken verifies signature and expiration of invitation token
+func ParseInvitationToken(tokenStr string, key []byte) (int64, string, error) {
+	token, err := jwt.Parse(
+		tokenStr,
+		func(token *jwt.Token) (interface{}, error) {
+			return key, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, "", err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	id, ok := claims["inv"].(float64)
+	if !ok {
+		return 0, "", ErrInvalidClaims
+	}
+	nonce, ok := claims["nonce"].(string)
+	if !ok || nonce == "" {
+		return 0, "", ErrInvalidClaims
+	}
+
+	return int64(id), nonce, nil
+}
+
+// Claims identify user of a token issued by NewToken
+type Claims struct {
+	UserID int64
+	AppID  int32
+	OrgID  int32
+	// JKT is thumbprint of DPoP key the token is bound to
+	JKT string
+	// X5T is thumbprint of TLS client certificate the token is bound to
+	X5T string
+	// SessionID is session the token was issued on, empty for tokens issued before sessions
+	SessionID string
+	// ACR is assurance level of login the token was issued for, models.ACRStepUpRequired
+	// when its risk asked to step up. Empty for tokens issued before acr
+	ACR string
+	// Expiry is exp claim of the token
+	Expiry time.Time
+	// Audience is aud claim, ClaimsV2 tokens always name their app in it
+	Audience []string
+}
+
+// ParseToken verifies token issued by NewToken, secrets returns every secret
+// of app from the token still accepted for verification.
+// Tokens with iss claim must be issued by issuer, ClaimsV2 ones must have their app as audience
+func ParseToken(tokenStr string, issuer string, secrets func(appID int32) ([]string, error)) (Claims, error) {
+	var res Claims
+
+	token, err := jwt.Parse(
+		tokenStr,
+		func(token *jwt.Token) (interface{}, error) {
+			appID, err := tokenAppID(token)
+			if err != nil {
+				return nil, err
+			}
+			res.AppID = appID
+
+			list, err := secrets(res.AppID)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := make([]jwt.VerificationKey, 0, len(list))
+			for _, secret := range list {
+				keys = append(keys, []byte(secret))
+			}
+
+			return jwt.VerificationKeySet{Keys: keys}, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	appID := res.AppID
+	if res, err = ClaimsOf(token.Claims.(jwt.MapClaims), issuer); err != nil {
+		return Claims{}, err
+	}
+	if res.AppID != appID {
+		return Claims{}, ErrInvalidClaims
+	}
+
+	return res, nil
+}
+
+// ClaimsOf returns identity from verified claims built by AccessClaims.
+// Claims with iss must be issued by issuer, ClaimsV2 ones must have their app as audience
+func ClaimsOf(claims map[string]any, issuer string) (Claims, error) {
+	var res Claims
+
+	mapClaims := jwt.MapClaims(claims)
+	if iss, ok := claims["iss"]; ok && iss != issuer {
+		return Claims{}, jwt.ErrTokenInvalidIssuer
+	}
+
+	if clientID, ok := claims["client_id"].(string); ok {
+		if issuer != "" && claims["iss"] != issuer {
+			return Claims{}, jwt.ErrTokenInvalidIssuer
+		}
+
+		appID, err := strconv.ParseInt(clientID, 10, 32)
+		if err != nil {
+			return Claims{}, ErrInvalidClaims
+		}
+		res.AppID = int32(appID)
+
+		aud, err := mapClaims.GetAudience()
+		if err != nil || !slices.Contains(aud, clientID) {
+			return Claims{}, jwt.ErrTokenInvalidAudience
+		}
+
+		sub, err := mapClaims.GetSubject()
+		if err != nil {
+			return Claims{}, ErrInvalidClaims
+		}
+		if res.UserID, err = strconv.ParseInt(sub, 10, 64); err != nil {
+			return Claims{}, ErrInvalidClaims
+		}
+	} else {
+		uid, ok := claims["uid"].(float64)
+		if !ok {
+			return Claims{}, ErrInvalidClaims
+		}
+		app, ok := claims["app"].(float64)
+		if !ok {
+			return Claims{}, ErrInvalidClaims
+		}
+		res.UserID = int64(uid)
+		res.AppID = int32(app)
+	}
+
+	org, ok := claims["org"].(float64)
+	if !ok {
+		return Claims{}, ErrInvalidClaims
+	}
+	res.OrgID = int32(org)
+	res.JKT = ConfirmationJKT(claims)
+	res.X5T = ConfirmationX5T(claims)
+	res.SessionID = SessionID(claims)
+	res.ACR = ACR(claims)
+	res.Expiry = expiry(claims)
+	res.Audience = Audience(claims)
+
+	return res, nil
+}
+
+// ConfirmationJKT returns DPoP key thumbprint from cnf claim
+func ConfirmationJKT(claims map[string]any) string {
+	c, _ := claims["cnf"].(map[string]any)
+	jkt, _ := c["jkt"].(string)
+
+	return jkt
+}
+
+// Audience returns aud claim whether it's single string or a list
+func Audience(claims map[string]any) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []string:
+		return aud
+	case []any:
+		res := make([]string, 0, len(aud))
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+
+	return nil
+}
+
+// expiry returns exp claim, unix seconds in JWT and RFC 3339 string in PASETO
+func expiry(claims map[string]any) time.Time {
+	switch exp := claims["exp"].(type) {
+	case float64:
+		return time.Unix(int64(exp), 0)
+	case int64:
+		return time.Unix(exp, 0)
+	case string:
+		t, _ := time.Parse(time.RFC3339, exp)
+		return t
+	}
+
+	return time.Time{}
+}
+
+// ACR returns acr claim
+func ACR(claims map[string]any) string {
+	acr, _ := claims["acr"].(string)
+	return acr
+}
+
+// SessionID returns sid claim
+func SessionID(claims map[string]any) string {
+	sid, _ := claims["sid"].(string)
+	return sid
+}
+
+// ConfirmationX5T returns client certificate thumbprint from cnf claim
+func ConfirmationX5T(claims map[string]any) string {
+	c, _ := claims["cnf"].(map[string]any)
+	x5t, _ := c["x5t#S256"].(string)
+
+	return x5t
+}
+
+// tokenAppID returns app from client_id of ClaimsV2 token or app claim of ClaimsV1 one
+func tokenAppID(token *jwt.Token) (int32, error) {
+	claims := token.Claims.(jwt.MapClaims)
+
+	if token.Header["typ"] == accessTokenType {
+		clientID, ok := claims["client_id"].(string)
+		if !ok {
+			return 0, ErrInvalidClaims
+		}
+
+		appID, err := strconv.ParseInt(clientID, 10, 32)
+		if err != nil {
+			return 0, ErrInvalidClaims
+		}
+
+		return int32(appID), nil
+	}
+
+	appID, ok := claims["app"].(float64)
+	if !ok {
+		return 0, ErrInvalidClaims
+	}
+
+	return int32(appID), nil
+}
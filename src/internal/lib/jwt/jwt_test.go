@@ -0,0 +1,276 @@
+package jwt
+
+import (
+	"domofon/internal/domain/models"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer = "domofon"
+	testSecret = "app-secret"
+)
+
+var (
+	testUser = models.User{Id: 42, OrgId: 3, Email: "resident@domofon.test", IsAdmin: true}
+	testApp  = models.App{Id: 7, OrgId: 3, Secret: testSecret}
+)
+
+func flag(v bool) *bool {
+	return &v
+}
+
+func TestAccessClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		extra    map[string]any
+		settings models.TokenSettings
+		want     map[string]any
+		absent   []string
+	}{
+		{
+			name:     "v1 keeps uid and app",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV1}},
+			want:     map[string]any{"uid": int64(42), "app": int32(7), "org": int32(3), "sub": "42"},
+			absent:   []string{"client_id", "aud", "email", "roles", "admin", "iss"},
+		},
+		{
+			name: "v2 has client_id as first audience",
+			settings: models.TokenSettings{
+				Issuer: testIssuer,
+				Claims: models.ClaimSettings{Version: models.ClaimsV2, Audience: []string{"doors"}},
+			},
+			want:   map[string]any{"client_id": "7", "aud": []string{"7", "doors"}, "iss": testIssuer},
+			absent: []string{"uid", "app"},
+		},
+		{
+			name: "selected claims",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{
+				Email: flag(true),
+				Roles: flag(true),
+				Admin: flag(false),
+			}},
+			want:   map[string]any{"email": testUser.Email, "roles": []string{"resident"}},
+			absent: []string{"admin"},
+		},
+		{
+			name:  "extra overrides static, neither overrides reserved",
+			extra: map[string]any{"building_id": "7", "uid": 1, "sub": "1"},
+			settings: models.TokenSettings{Claims: models.ClaimSettings{
+				Static: map[string]any{"building_id": "1", "tenant": "acme", "org": 1},
+			}},
+			want: map[string]any{"building_id": "7", "tenant": "acme", "uid": int64(42), "sub": "42", "org": int32(3)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.AccessTTL = time.Hour
+
+			claims, err := AccessClaims(testUser, testApp, []models.Role{{Name: "resident"}}, tt.extra, tt.settings)
+			require.NoError(t, err)
+
+			for k, v := range tt.want {
+				assert.Equal(t, v, claims[k], k)
+			}
+			for _, k := range tt.absent {
+				assert.NotContains(t, claims, k)
+			}
+			assert.NotEmpty(t, claims["jti"])
+			assert.Equal(t, claims["iat"].(int64)+3600, claims["exp"])
+		})
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.TokenSettings
+		issuer   string
+		secrets  []string
+		edit     func(claims map[string]any)
+		wantAud  []string
+		wantErr  error
+	}{
+		{
+			name:     "v1",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV1}},
+			secrets:  []string{testSecret},
+		},
+		{
+			name: "v1 with audience",
+			settings: models.TokenSettings{
+				Claims: models.ClaimSettings{Version: models.ClaimsV1, Audience: []string{"doors"}},
+			},
+			secrets: []string{testSecret},
+			wantAud: []string{"doors"},
+		},
+		{
+			name:     "v2",
+			settings: models.TokenSettings{Issuer: testIssuer, Claims: models.ClaimSettings{Version: models.ClaimsV2}},
+			issuer:   testIssuer,
+			secrets:  []string{testSecret},
+			wantAud:  []string{"7"},
+		},
+		{
+			name:     "rotated secret still in grace",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV2}},
+			secrets:  []string{"new-secret", testSecret},
+			wantAud:  []string{"7"},
+		},
+		{
+			name:     "secret no longer accepted",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV2}},
+			secrets:  []string{"new-secret"},
+			wantErr:  jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:     "other issuer",
+			settings: models.TokenSettings{Issuer: "someone", Claims: models.ClaimSettings{Version: models.ClaimsV2}},
+			issuer:   testIssuer,
+			secrets:  []string{testSecret},
+			wantErr:  jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name:     "v2 without its app in audience",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV2}},
+			secrets:  []string{testSecret},
+			edit:     func(claims map[string]any) { claims["aud"] = []string{"doors"} },
+			wantErr:  jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:     "expired",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV1}},
+			secrets:  []string{testSecret},
+			edit:     func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr:  jwt.ErrTokenExpired,
+		},
+		{
+			name:     "without org",
+			settings: models.TokenSettings{Claims: models.ClaimSettings{Version: models.ClaimsV1}},
+			secrets:  []string{testSecret},
+			edit:     func(claims map[string]any) { delete(claims, "org") },
+			wantErr:  ErrInvalidClaims,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.AccessTTL = time.Hour
+
+			claims, err := AccessClaims(testUser, testApp, nil, nil, tt.settings)
+			require.NoError(t, err)
+			claims["sid"] = "session"
+			claims["cnf"] = map[string]any{"jkt": "key-thumbprint"}
+			if tt.edit != nil {
+				tt.edit(claims)
+			}
+
+			token, err := Sign(claims, testApp, tt.settings)
+			require.NoError(t, err)
+
+			got, err := ParseToken(token, tt.issuer, func(appID int32) ([]string, error) {
+				if appID != testApp.Id {
+					return nil, errors.New("unknown app")
+				}
+				return tt.secrets, nil
+			})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, Claims{
+				UserID:    testUser.Id,
+				AppID:     testApp.Id,
+				OrgID:     testUser.OrgId,
+				JKT:       "key-thumbprint",
+				SessionID: "session",
+				Expiry:    time.Unix(claims["exp"].(int64), 0),
+				Audience:  tt.wantAud,
+			}, got)
+		})
+	}
+}
+
+func TestConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		wantJKT string
+		wantX5T string
+	}{
+		{
+			name:   "no cnf",
+			claims: map[string]any{},
+		},
+		{
+			name:    "dpop key",
+			claims:  map[string]any{"cnf": map[string]any{"jkt": "a"}},
+			wantJKT: "a",
+		},
+		{
+			name:    "client certificate",
+			claims:  map[string]any{"cnf": map[string]any{"x5t#S256": "b"}},
+			wantX5T: "b",
+		},
+		{
+			name:   "malformed cnf",
+			claims: map[string]any{"cnf": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantJKT, ConfirmationJKT(tt.claims))
+			assert.Equal(t, tt.wantX5T, ConfirmationX5T(tt.claims))
+		})
+	}
+}
+
+func TestInvitationToken(t *testing.T) {
+	key := []byte("invitation-key")
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		parseKey  []byte
+		wantErr   error
+	}{
+		{
+			name:      "valid",
+			expiresAt: time.Now().Add(time.Hour),
+			parseKey:  key,
+		},
+		{
+			name:      "other key",
+			expiresAt: time.Now().Add(time.Hour),
+			parseKey:  []byte("other-key"),
+			wantErr:   jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:      "expired",
+			expiresAt: time.Now().Add(-time.Minute),
+			parseKey:  key,
+			wantErr:   jwt.ErrTokenExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := NewInvitationToken(5, "nonce", tt.expiresAt, key)
+			require.NoError(t, err)
+
+			id, nonce, err := ParseInvitationToken(token, tt.parseKey)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), id)
+			assert.Equal(t, "nonce", nonce)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package sl
+
+import "log/slog"
+
+// Err wraps error into slog attribute
+func Err(err error) slog.Attr {
+	return slog.Attr{
+		Key:   "error",
+		Value: slog.StringValue(err.Error()),
+	}
+}
@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+type Sender struct {
+	log  *slog.Logger
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSender returns new instance of mail Sender.
+// With empty host messages are only written to the log
+func NewSender(
+	log *slog.Logger,
+	host string,
+	port int,
+	username string,
+	password string,
+	from string,
+) *Sender {
+	s := &Sender{log: log, from: from}
+	if host == "" {
+		return s
+	}
+
+	s.addr = net.JoinHostPort(host, strconv.Itoa(port))
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s
+}
+
+func (s *Sender) Send(ctx context.Context, to string, subject string, body string) error {
+	const op = "mail.send"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if s.addr == "" {
+		s.log.Info(
+			"mail delivery disabled, message dropped",
+			slog.String("op", op),
+			slog.String("to", to),
+			slog.String("subject", subject),
+		)
+		return nil
+	}
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{to}, message(s.from, to, subject, body)); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func message(from string, to string, subject string, body string) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + header(from) + "\r\n")
+	b.WriteString("To: " + header(to) + "\r\n")
+	b.WriteString("Subject: " + header(subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+
+	return []byte(b.String())
+}
+
+// header strips line breaks so values can't inject extra headers
+func header(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"context"
+	"domofon/internal/lib/logger/sl"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	sendTimeout = 30 * time.Second
+)
+
+var (
+	ErrQueueFull = errors.New("mail queue is full")
+)
+
+type queued struct {
+	to      string
+	subject string
+	body    string
+}
+
+type sender interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
+
+// Queue sends mail in background, so requests never wait for SMTP
+type Queue struct {
+	log    *slog.Logger
+	sender sender
+	queue  chan queued
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewQueue returns started Queue holding up to size messages
+func NewQueue(log *slog.Logger, sender sender, size int) *Queue {
+	if size <= 0 {
+		size = 1
+	}
+
+	q := &Queue{
+		log:    log,
+		sender: sender,
+		queue:  make(chan queued, size),
+		stop:   make(chan struct{}),
+	}
+
+	q.wg.Add(1)
+	go q.work()
+
+	return q
+}
+
+// Enqueue schedules message, it fails instead of blocking when queue is full
+func (q *Queue) Enqueue(to string, subject string, body string) error {
+	const op = "mail.enqueue"
+
+	select {
+	case q.queue <- queued{to: to, subject: subject, body: body}:
+		return nil
+	default:
+		return fmt.Errorf("%s %w", op, ErrQueueFull)
+	}
+}
+
+// Stop stops sending, queued messages are dropped
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+	})
+	q.wg.Wait()
+
+	if dropped := len(q.queue); dropped > 0 {
+		q.log.Warn("mail queue stopped with pending messages", slog.Int("dropped", dropped))
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	const op = "mail.queue"
+
+	log := q.log.With(slog.String("op", op))
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case m := <-q.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			if err := q.sender.Send(ctx, m.to, m.subject, m.body); err != nil {
+				log.Error("failed sending queued mail", sl.Err(err))
+			}
+			cancel()
+		}
+	}
+}
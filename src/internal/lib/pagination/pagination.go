@@ -0,0 +1,52 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// PageSize returns requested page size bounded by MaxPageSize, DefaultPageSize when it isn't requested
+func PageSize(requested int) int {
+	if requested <= 0 {
+		return DefaultPageSize
+	}
+
+	return min(requested, MaxPageSize)
+}
+
+// Page returns page of rows fetched with one extra row, the extra row tells
+// there is a next page and cursor to it is id of the last row of the page.
+// Cursor is empty when there are no more rows
+func Page[T any](rows []T, pageSize int, id func(T) int64) ([]T, string) {
+	if len(rows) <= pageSize {
+		return rows, ""
+	}
+
+	rows = rows[:pageSize]
+
+	return rows, EncodeCursor(id(rows[len(rows)-1]))
+}
+
+// EncodeCursor returns opaque cursor of row id
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodeCursor returns row id of cursor, 0 for empty cursor
+func DecodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(string(raw), 10, 64)
+}
@@ -0,0 +1,53 @@
+package pagination
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested int
+		want      int
+	}{
+		{name: "not requested", requested: 0, want: DefaultPageSize},
+		{name: "negative", requested: -1, want: DefaultPageSize},
+		{name: "within bound", requested: 10, want: 10},
+		{name: "above bound", requested: MaxPageSize + 1, want: MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PageSize(tt.requested))
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	id := func(v int64) int64 { return v }
+
+	rows, next := Page([]int64{9, 8, 7}, 2, id)
+	assert.Equal(t, []int64{9, 8}, rows)
+
+	after, err := DecodeCursor(next)
+	require.NoError(t, err)
+	assert.EqualValues(t, 8, after)
+
+	rows, next = Page([]int64{9, 8}, 2, id)
+	assert.Equal(t, []int64{9, 8}, rows)
+	assert.Empty(t, next)
+}
+
+func TestDecodeCursor(t *testing.T) {
+	id, err := DecodeCursor("")
+	require.NoError(t, err)
+	assert.Zero(t, id)
+
+	_, err = DecodeCursor("not base64!")
+	assert.Error(t, err)
+
+	_, err = DecodeCursor(EncodeCursor(42)[:1])
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/requestmeta"
+	"errors"
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	costLimit         = 10_000
+	interruptInterval = 100
+	// maxPrograms bounds cache, dry runs may compile arbitrary expressions
+	maxPrograms = 1024
+)
+
+var (
+	ErrInvalidPolicy = errors.New("invalid policy")
+)
+
+// Input is what expressions see as user, app and request variables
+type Input struct {
+	User    models.User
+	App     models.App
+	Request requestmeta.Meta
+	Time    time.Time
+}
+
+// Engine compiles CEL expressions once and evaluates them against Input
+type Engine struct {
+	env      *cel.Env
+	mu       sync.Mutex
+	programs map[string]cel.Program
+}
+
+func NewEngine() (*Engine, error) {
+	const op = "policy.newEngine"
+
+	env, err := cel.NewEnv(
+		cel.Variable("user", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("app", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return &Engine{env: env, programs: make(map[string]cel.Program)}, nil
+}
+
+// ValidateLogin checks expr compiles to bool
+func (e *Engine) ValidateLogin(expr string) error {
+	_, err := e.program(expr, cel.BoolType)
+	return err
+}
+
+// ValidateClaims checks expr compiles to map
+func (e *Engine) ValidateClaims(expr string) error {
+	_, err := e.program(expr, cel.MapType(cel.StringType, cel.DynType))
+	return err
+}
+
+// Allow evaluates login expression, empty expression allows everyone
+func (e *Engine) Allow(ctx context.Context, expr string, in Input) (bool, error) {
+	const op = "policy.allow"
+
+	if expr == "" {
+		return true, nil
+	}
+
+	prg, err := e.program(expr, cel.BoolType)
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	out, _, err := prg.ContextEval(ctx, vars(in))
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("%s %w: result is %s", op, ErrInvalidPolicy, out.Type())
+	}
+
+	return allowed, nil
+}
+
+// Claims evaluates claims expression, empty expression adds nothing
+func (e *Engine) Claims(ctx context.Context, expr string, in Input) (map[string]any, error) {
+	const op = "policy.claims"
+
+	if expr == "" {
+		return nil, nil
+	}
+
+	prg, err := e.program(expr, cel.MapType(cel.StringType, cel.DynType))
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	out, _, err := prg.ContextEval(ctx, vars(in))
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	native, err := out.ConvertToNative(reflect.TypeOf(map[string]any{}))
+	if err != nil {
+		return nil, fmt.Errorf("%s %w: %w", op, ErrInvalidPolicy, err)
+	}
+
+	return native.(map[string]any), nil
+}
+
+func (e *Engine) program(expr string, want *cel.Type) (cel.Program, error) {
+	key := want.String() + "\x00" + expr
+
+	e.mu.Lock()
+	prg, ok := e.programs[key]
+	e.mu.Unlock()
+	if ok {
+		return prg, nil
+	}
+
+	prg, err := e.compile(expr, want)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	if len(e.programs) >= maxPrograms {
+		e.programs = make(map[string]cel.Program)
+	}
+	e.programs[key] = prg
+	e.mu.Unlock()
+
+	return prg, nil
+}
+
+func (e *Engine) compile(expr string, want *cel.Type) (cel.Program, error) {
+	ast, iss := e.env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, iss.Err())
+	}
+	if !ast.OutputType().IsAssignableType(want) && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("%w: result is %s, want %s", ErrInvalidPolicy, ast.OutputType(), want)
+	}
+
+	prg, err := e.env.Program(
+		ast,
+		cel.CostLimit(costLimit),
+		cel.InterruptCheckFrequency(interruptInterval),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	return prg, nil
+}
+
+func vars(in Input) map[string]any {
+	profile := in.User.Profile
+	if profile == nil {
+		profile = map[string]any{}
+	}
+
+	return map[string]any{
+		"user": map[string]any{
+			"id":             in.User.Id,
+			"email":          in.User.Email,
+			"is_admin":       in.User.IsAdmin,
+			"email_verified": in.User.EmailVerified,
+			"profile":        profile,
+		},
+		"app": map[string]any{
+			"id":   int64(in.App.Id),
+			"name": in.App.Name,
+		},
+		"request": map[string]any{
+			"ip":         in.Request.IP,
+			"user_agent": in.Request.UserAgent,
+			"time":       in.Time,
+		},
+	}
+}
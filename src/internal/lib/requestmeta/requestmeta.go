@@ -0,0 +1,95 @@
+package requestmeta
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/cnf"
+	"encoding/base64"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"slices"
+	"strconv"
+)
+
+const (
+	userAgentKey = "user-agent"
+	deviceIDKey  = "x-device-id"
+	appIDKey     = "x-app-id"
+)
+
+// Meta describes client of the current gRPC request
+type Meta struct {
+	IP        string
+	UserAgent string
+	// DeviceID is optional identifier client app keeps for its installation
+	DeviceID string
+	// AppID is app the client acts for, requests without app_id field carry it in metadata
+	AppID int32
+	// ClientCert is TLS certificate the client authenticated the connection with
+	ClientCert *x509.Certificate
+	// ClientCertVerified is set when ClientCert chains to trusted client CA
+	ClientCertVerified bool
+}
+
+// Fingerprint identifies device of client by its device id and user agent.
+// IP isn't part of it, mobile clients change addresses all the time
+func (m Meta) Fingerprint() string {
+	sum := sha256.Sum256([]byte(m.DeviceID + "\x00" + m.UserAgent))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// ClientCertMatches reports whether request came over mTLS with certificate of app.
+// Subject is trusted only for certificates verified against client CA,
+// self-signed ones match by thumbprint
+func (m Meta) ClientCertMatches(auth models.TLSClientAuth) bool {
+	if m.ClientCert == nil {
+		return false
+	}
+
+	if auth.SubjectDN != "" && m.ClientCertVerified && m.ClientCert.Subject.String() == auth.SubjectDN {
+		return true
+	}
+
+	return slices.Contains(auth.Thumbprints, cnf.Thumbprint(m.ClientCert))
+}
+
+// FromContext returns client ip and certificate from gRPC peer, user agent and app from metadata
+func FromContext(ctx context.Context) Meta {
+	var meta Meta
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			host, _, err := net.SplitHostPort(p.Addr.String())
+			if err != nil {
+				host = p.Addr.String()
+			}
+			meta.IP = host
+		}
+
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			meta.ClientCert = info.State.PeerCertificates[0]
+			meta.ClientCertVerified = len(info.State.VerifiedChains) > 0
+		}
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get(userAgentKey); len(ua) > 0 {
+			meta.UserAgent = ua[0]
+		}
+		if id := md.Get(deviceIDKey); len(id) > 0 {
+			meta.DeviceID = id[0]
+		}
+		if id := md.Get(appIDKey); len(id) > 0 {
+			// malformed id is left empty, handlers refuse the request for missing app
+			if appID, err := strconv.ParseInt(id[0], 10, 32); err == nil {
+				meta.AppID = int32(appID)
+			}
+		}
+	}
+
+	return meta
+}
@@ -0,0 +1,81 @@
+package requestmeta
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/cnf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func selfSigned(t *testing.T, name string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestMeta_ClientCertMatches(t *testing.T) {
+	cert := selfSigned(t, "resident")
+	other := selfSigned(t, "resident")
+
+	tests := []struct {
+		name string
+		meta Meta
+		auth models.TLSClientAuth
+		want bool
+	}{
+		{
+			name: "no certificate",
+			auth: models.TLSClientAuth{Thumbprints: []string{cnf.Thumbprint(cert)}},
+		},
+		{
+			name: "thumbprint",
+			meta: Meta{ClientCert: cert},
+			auth: models.TLSClientAuth{Thumbprints: []string{cnf.Thumbprint(cert)}},
+			want: true,
+		},
+		{
+			name: "different certificate",
+			meta: Meta{ClientCert: other},
+			auth: models.TLSClientAuth{Thumbprints: []string{cnf.Thumbprint(cert)}},
+		},
+		{
+			name: "verified subject",
+			meta: Meta{ClientCert: cert, ClientCertVerified: true},
+			auth: models.TLSClientAuth{SubjectDN: "CN=resident"},
+			want: true,
+		},
+		{
+			name: "unverified subject",
+			meta: Meta{ClientCert: cert},
+			auth: models.TLSClientAuth{SubjectDN: "CN=resident"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.meta.ClientCertMatches(tt.auth))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package risk
+
+import (
+	"domofon/internal/domain/models"
+	"net"
+)
+
+const (
+	// MaxScore is score of the riskiest login
+	MaxScore = 100
+
+	failureWeight    = 10
+	maxFailureWeight = 30
+	deviceWeight     = 20
+	networkWeight    = 15
+	countryWeight    = 20
+	travelWeight     = 40
+	hourWeight       = 10
+
+	ipv4NetworkBits = 24
+	ipv6NetworkBits = 48
+)
+
+// Assessment is risk score of login and signals raising it, in order they were checked
+type Assessment struct {
+	Score   int
+	Signals []string
+}
+
+func (a *Assessment) add(signal string, weight int) {
+	a.Signals = append(a.Signals, signal)
+	a.Score = min(a.Score+weight, MaxScore)
+}
+
+// Assess scores login of event against familiarity of user with it.
+// Unknown device, network and country of event are never unfamiliar,
+// neither is anything on the first login of user
+func Assess(event models.LoginEvent, f models.LoginFamiliarity) Assessment {
+	var res Assessment
+
+	if f.RecentFailures > 0 {
+		res.add(models.RiskFailedAttempts, min(f.RecentFailures*failureWeight, maxFailureWeight))
+	}
+	if event.ImpossibleTravel {
+		res.add(models.RiskImpossibleTravel, travelWeight)
+	}
+	if f.FirstLogin {
+		return res
+	}
+
+	if event.Device != "" && !f.KnownDevice {
+		res.add(models.RiskNewDevice, deviceWeight)
+	}
+	if event.Network != "" && !f.KnownNetwork {
+		res.add(models.RiskNewNetwork, networkWeight)
+	}
+	if event.Geo.Country != "" && !f.KnownCountry {
+		res.add(models.RiskNewCountry, countryWeight)
+	}
+	if !f.KnownHour {
+		res.add(models.RiskUnusualHour, hourWeight)
+	}
+
+	return res
+}
+
+// Network returns address range of ip in CIDR notation, /24 for IPv4 and /48 for IPv6.
+// Empty for invalid ip
+func Network(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	mask := net.CIDRMask(ipv6NetworkBits, 8*net.IPv6len)
+	if v4 := addr.To4(); v4 != nil {
+		addr, mask = v4, net.CIDRMask(ipv4NetworkBits, 8*net.IPv4len)
+	}
+
+	return (&net.IPNet{IP: addr.Mask(mask), Mask: mask}).String()
+}
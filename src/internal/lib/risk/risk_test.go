@@ -0,0 +1,103 @@
+package risk
+
+import (
+	"domofon/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAssess(t *testing.T) {
+	familiar := models.LoginFamiliarity{KnownDevice: true, KnownNetwork: true, KnownCountry: true, KnownHour: true}
+	event := models.LoginEvent{Device: "device", Network: "10.0.0.0/24", Geo: models.GeoLocation{Country: "RU"}}
+
+	tests := []struct {
+		name        string
+		event       models.LoginEvent
+		familiarity models.LoginFamiliarity
+		wantScore   int
+		wantSignals []string
+	}{
+		{
+			name:        "familiar",
+			event:       event,
+			familiarity: familiar,
+			wantScore:   0,
+		},
+		{
+			name:        "first login",
+			event:       event,
+			familiarity: models.LoginFamiliarity{FirstLogin: true},
+			wantScore:   0,
+		},
+		{
+			name:        "unfamiliar",
+			event:       event,
+			familiarity: models.LoginFamiliarity{},
+			wantScore:   65,
+			wantSignals: []string{
+				models.RiskNewDevice,
+				models.RiskNewNetwork,
+				models.RiskNewCountry,
+				models.RiskUnusualHour,
+			},
+		},
+		{
+			name:        "unknown location is never unfamiliar",
+			event:       models.LoginEvent{},
+			familiarity: models.LoginFamiliarity{KnownHour: true},
+			wantScore:   0,
+		},
+		{
+			name:  "failures are capped",
+			event: event,
+			familiarity: models.LoginFamiliarity{
+				RecentFailures: 5,
+				KnownDevice:    true,
+				KnownNetwork:   true,
+				KnownCountry:   true,
+				KnownHour:      true,
+			},
+			wantScore:   30,
+			wantSignals: []string{models.RiskFailedAttempts},
+		},
+		{
+			name:        "score is capped",
+			event:       models.LoginEvent{Device: "device", Network: "10.0.0.0/24", ImpossibleTravel: true},
+			familiarity: models.LoginFamiliarity{RecentFailures: 3, KnownCountry: true},
+			wantScore:   MaxScore,
+			wantSignals: []string{
+				models.RiskFailedAttempts,
+				models.RiskImpossibleTravel,
+				models.RiskNewDevice,
+				models.RiskNewNetwork,
+				models.RiskUnusualHour,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Assess(tt.event, tt.familiarity)
+
+			assert.Equal(t, tt.wantScore, got.Score)
+			assert.Equal(t, tt.wantSignals, got.Signals)
+		})
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "192.168.1.77", want: "192.168.1.0/24"},
+		{ip: "2001:db8:abcd:12::1", want: "2001:db8:abcd::/48"},
+		{ip: "not an ip", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, Network(tt.ip))
+		})
+	}
+}
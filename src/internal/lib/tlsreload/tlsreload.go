@@ -0,0 +1,235 @@
+package tlsreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"domofon/internal/lib/logger/sl"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoClientCA = errors.New("client certificate verification requires CA file")
+	ErrInvalidCA  = errors.New("no certificates in CA file")
+	ErrClientAuth = errors.New("unknown client auth mode")
+)
+
+var clientAuthModes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require_any":        tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// ParseClientAuth returns client auth type of mode name used in config
+func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
+	const op = "tlsreload.parseClientAuth"
+
+	clientAuth, ok := clientAuthModes[mode]
+	if !ok {
+		return 0, fmt.Errorf("%s %w: %q", op, ErrClientAuth, mode)
+	}
+
+	return clientAuth, nil
+}
+
+// Reloader serves certificate and client CA read from files,
+// files are checked every interval and reloaded once changed
+type Reloader struct {
+	log        *slog.Logger
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+	interval   time.Duration
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New loads files and starts watching them, caFile may be empty
+// unless clientAuth verifies client certificates
+func New(
+	log *slog.Logger,
+	certFile string,
+	keyFile string,
+	caFile string,
+	clientAuth tls.ClientAuthType,
+	interval time.Duration,
+) (*Reloader, error) {
+	const op = "tlsreload.new"
+
+	if caFile == "" && clientAuth >= tls.VerifyClientCertIfGiven {
+		return nil, fmt.Errorf("%s %w", op, ErrNoClientCA)
+	}
+
+	r := &Reloader{
+		log:        log,
+		certFile:   certFile,
+		keyFile:    keyFile,
+		caFile:     caFile,
+		clientAuth: clientAuth,
+		interval:   interval,
+		stop:       make(chan struct{}),
+	}
+
+	if err := r.load(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	if interval > 0 {
+		r.wg.Add(1)
+		go r.watch()
+	}
+
+	return r, nil
+}
+
+// TLSConfig returns server config always using the latest loaded files
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.configForClient,
+	}
+}
+
+// Stop stops watching files
+func (r *Reloader) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	r.wg.Wait()
+}
+
+func (r *Reloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.cert},
+		ClientAuth:   r.clientAuth,
+		ClientCAs:    r.clientCA,
+	}, nil
+}
+
+func (r *Reloader) watch() {
+	defer r.wg.Done()
+
+	const op = "tlsreload.watch"
+
+	log := r.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := r.changed()
+		if err != nil {
+			log.Error("failed checking tls files", sl.Err(err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		// half written files fail to load, the previous ones stay in use until next check
+		if err := r.load(); err != nil {
+			log.Error("failed reloading tls files", sl.Err(err))
+			continue
+		}
+
+		log.Info("tls files reloaded")
+	}
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+
+	return files
+}
+
+func (r *Reloader) changed() (bool, error) {
+	times, err := modTimes(r.files())
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range times {
+		if !times[i].Equal(r.modTimes[i]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *Reloader) load() error {
+	times, err := modTimes(r.files())
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrInvalidCA
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = times
+	r.mu.Unlock()
+
+	return nil
+}
+
+func modTimes(files []string) ([]time.Time, error) {
+	times := make([]time.Time, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, info.ModTime())
+	}
+
+	return times, nil
+}
@@ -0,0 +1,205 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type keyPair struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func (p keyPair) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{p.cert.Raw}, PrivateKey: p.key, Leaf: p.cert}
+}
+
+// newKeyPair returns certificate of name signed by parent, self-signed without one
+func newKeyPair(t *testing.T, name string, serial int64, parent *keyPair) keyPair {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return keyPair{cert: cert, key: key}
+}
+
+// writeFiles writes certificate and key of pair as PEM, mod time is moved to at
+func writeFiles(t *testing.T, dir string, pair keyPair, at time.Time) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(pair.key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writePEM(t, certFile, "CERTIFICATE", pair.cert.Raw, at)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER, at)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file string, blockType string, der []byte, at time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	require.NoError(t, os.Chtimes(file, at, at))
+}
+
+// serve accepts TLS connections of r on a local port until the test ends
+func serve(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", r.TLSConfig())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err == nil {
+					_, _ = conn.Write([]byte{1})
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// dial connects with client certificate, nil presents none. The server refuses a client
+// certificate after the TLS 1.3 handshake, so the first read tells whether it was accepted
+func dial(addr string, roots *x509.CertPool, client *tls.Certificate) (*x509.Certificate, error) {
+	config := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if client != nil {
+		config.Certificates = []tls.Certificate{*client}
+	}
+
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err = conn.Read(make([]byte, 1)); err != nil {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestReloader_clientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newKeyPair(t, "domofon ca", 1, nil)
+	server := newKeyPair(t, "localhost", 2, &ca)
+	client := newKeyPair(t, "client", 3, &ca).tls()
+	stranger := newKeyPair(t, "client", 4, nil).tls()
+
+	certFile, keyFile := writeFiles(t, dir, server, time.Now())
+	caFile := filepath.Join(dir, "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", ca.cert.Raw, time.Now())
+
+	r, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), certFile, keyFile, caFile, tls.RequireAndVerifyClientCert, 0)
+	require.NoError(t, err)
+	t.Cleanup(r.Stop)
+
+	addr := serve(t, r)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name    string
+		client  *tls.Certificate
+		wantErr bool
+	}{
+		{name: "certificate of client ca", client: &client},
+		{name: "missing certificate", wantErr: true},
+		{name: "certificate of other ca", client: &stranger, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := dial(addr, roots, tt.client)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, server.cert.SerialNumber, cert.SerialNumber)
+		})
+	}
+}
+
+func TestReloader_reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newKeyPair(t, "domofon ca", 1, nil)
+	before := newKeyPair(t, "localhost", 2, &ca)
+	after := newKeyPair(t, "localhost", 3, &ca)
+
+	certFile, keyFile := writeFiles(t, dir, before, time.Now().Add(-time.Minute))
+
+	r, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), certFile, keyFile, "", tls.NoClientCert, 10*time.Millisecond)
+	require.NoError(t, err)
+	t.Cleanup(r.Stop)
+
+	addr := serve(t, r)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	cert, err := dial(addr, roots, nil)
+	require.NoError(t, err)
+	require.Equal(t, before.cert.SerialNumber, cert.SerialNumber)
+
+	writeFiles(t, dir, after, time.Now())
+
+	require.Eventually(t, func() bool {
+		cert, err := dial(addr, roots, nil)
+		return err == nil && cert.SerialNumber.Cmp(after.cert.SerialNumber) == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestNew_clientCARequired(t *testing.T) {
+	_, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), "tls.crt", "tls.key", "", tls.RequireAndVerifyClientCert, 0)
+
+	assert.ErrorIs(t, err, ErrNoClientCA)
+}
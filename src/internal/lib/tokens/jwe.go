@@ -0,0 +1,93 @@
+package tokens
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/go-jose/go-jose/v3"
+)
+
+const (
+	minRSABits = 2048
+)
+
+var (
+	ErrInvalidEncryptionKey = errors.New("invalid token encryption key")
+)
+
+// encryptionAlgs are key management algorithms by key type, the first one is default
+var encryptionAlgs = map[string][]jose.KeyAlgorithm{
+	"RSA": {jose.RSA_OAEP_256, jose.RSA_OAEP},
+	"EC":  {jose.ECDH_ES, jose.ECDH_ES_A256KW},
+}
+
+// ValidateEncryptionKey checks jwk is public RSA or EC key usable with alg,
+// empty alg picks default of the key type
+func ValidateEncryptionKey(jwk string, alg string) error {
+	_, _, err := encryptionKey(jwk, alg)
+	return err
+}
+
+// encrypt wraps signed token into JWE readable only by holder of private key of jwk
+func encrypt(signed string, jwk string, alg string) (string, error) {
+	key, keyAlg, err := encryptionKey(jwk, alg)
+	if err != nil {
+		return "", err
+	}
+
+	encrypter, err := jose.NewEncrypter(
+		jose.A256GCM,
+		jose.Recipient{Algorithm: keyAlg, Key: key.Key, KeyID: key.KeyID},
+		(&jose.EncrypterOptions{}).WithType("JWT").WithContentType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := encrypter.Encrypt([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+
+	return obj.CompactSerialize()
+}
+
+func encryptionKey(jwk string, alg string) (jose.JSONWebKey, jose.KeyAlgorithm, error) {
+	var key jose.JSONWebKey
+	if err := key.UnmarshalJSON([]byte(jwk)); err != nil {
+		return jose.JSONWebKey{}, "", fmt.Errorf("%w: %w", ErrInvalidEncryptionKey, err)
+	}
+	if !key.IsPublic() {
+		return jose.JSONWebKey{}, "", fmt.Errorf("%w: key must be public", ErrInvalidEncryptionKey)
+	}
+
+	var kty string
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return jose.JSONWebKey{}, "", fmt.Errorf("%w: rsa key is shorter than %d bits", ErrInvalidEncryptionKey, minRSABits)
+		}
+		kty = "RSA"
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P224() {
+			return jose.JSONWebKey{}, "", fmt.Errorf("%w: unsupported curve", ErrInvalidEncryptionKey)
+		}
+		kty = "EC"
+	default:
+		return jose.JSONWebKey{}, "", fmt.Errorf("%w: key must be RSA or EC", ErrInvalidEncryptionKey)
+	}
+
+	algs := encryptionAlgs[kty]
+	if alg == "" {
+		return key, algs[0], nil
+	}
+	for _, a := range algs {
+		if string(a) == alg {
+			return key, a, nil
+		}
+	}
+
+	return jose.JSONWebKey{}, "", fmt.Errorf("%w: %s can't be used with %s key", ErrInvalidEncryptionKey, alg, kty)
+}
@@ -0,0 +1,246 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/storage"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	opaquePrefix = "at_"
+	opaqueLen    = 32
+	// maxCached bounds cache of opaque tokens
+	maxCached = 10_000
+)
+
+type TokenStore interface {
+	SaveAccessToken(ctx context.Context, token models.AccessToken, tokenHash []byte) (int64, error)
+	AccessToken(ctx context.Context, tokenHash []byte) (models.AccessToken, error)
+	RevokeAccessToken(ctx context.Context, tokenHash []byte) error
+}
+
+// Introspection describes token to resource servers like RFC 7662 response
+type Introspection struct {
+	Active bool
+	// AppID is app the token was issued to
+	AppID  int32
+	Claims map[string]any
+}
+
+// IsAudience reports whether token is meant for app, it was issued to the app or names it in aud
+func (in Introspection) IsAudience(appID int32) bool {
+	return in.AppID == appID || slices.Contains(jwt.Audience(in.Claims), strconv.Itoa(int(appID)))
+}
+
+// Limited returns introspection with only exp and aud claims, all an app the token isn't meant for may learn
+func (in Introspection) Limited() Introspection {
+	res := Introspection{Active: in.Active}
+	if !in.Active {
+		return res
+	}
+
+	res.Claims = make(map[string]any)
+	for _, name := range []string{"exp", "aud"} {
+		if v, ok := in.Claims[name]; ok {
+			res.Claims[name] = v
+		}
+	}
+
+	return res
+}
+
+// opaqueCache keeps token state for ttl, so revocation on other instances
+// takes effect here at most ttl later
+type opaqueCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]cachedToken
+}
+
+type cachedToken struct {
+	token    models.AccessToken
+	cachedAt time.Time
+}
+
+func newOpaqueCache(ttl time.Duration) *opaqueCache {
+	return &opaqueCache{ttl: ttl, entries: make(map[[sha256.Size]byte]cachedToken)}
+}
+
+func (c *opaqueCache) get(key [sha256.Size]byte) (models.AccessToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return models.AccessToken{}, false
+	}
+	if time.Since(entry.cachedAt) >= c.ttl {
+		delete(c.entries, key)
+		return models.AccessToken{}, false
+	}
+
+	return entry.token, true
+}
+
+func (c *opaqueCache) put(key [sha256.Size]byte, token models.AccessToken) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCached {
+		c.entries = make(map[[sha256.Size]byte]cachedToken)
+	}
+	c.entries[key] = cachedToken{token: token, cachedAt: time.Now()}
+}
+
+func (c *opaqueCache) delete(key [sha256.Size]byte) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// issueOpaque stores claims and returns random reference to them
+func (i *Issuer) issueOpaque(
+	ctx context.Context,
+	user models.User,
+	app models.App,
+	claims map[string]any,
+	settings models.TokenSettings,
+) (string, error) {
+	b := make([]byte, opaqueLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ref := opaquePrefix + base64.RawURLEncoding.EncodeToString(b)
+	hash := sha256.Sum256([]byte(ref))
+
+	token := models.AccessToken{
+		OrgId:     user.OrgId,
+		AppId:     app.Id,
+		UserId:    user.Id,
+		Claims:    claims,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(settings.AccessTTL),
+	}
+
+	var err error
+	if token.Id, err = i.tokenStore.SaveAccessToken(ctx, token, hash[:]); err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+// opaqueToken returns active state of reference, cache is tried before storage
+func (i *Issuer) opaqueToken(ctx context.Context, ref string) (models.AccessToken, error) {
+	hash := sha256.Sum256([]byte(ref))
+
+	token, ok := i.cache.get(hash)
+	if !ok {
+		var err error
+		token, err = i.tokenStore.AccessToken(ctx, hash[:])
+		if err != nil {
+			if errors.Is(err, storage.ErrTokenNotFound) {
+				return models.AccessToken{}, ErrInvalidToken
+			}
+
+			return models.AccessToken{}, storeError{err: err}
+		}
+		i.cache.put(hash, token)
+	}
+
+	if !token.Active(time.Now()) {
+		return models.AccessToken{}, ErrInvalidToken
+	}
+
+	return token, nil
+}
+
+// Introspect resolves token of any format, invalid tokens are reported inactive.
+// Self-contained tokens are described only by user, app, organization, session, acr, exp and aud
+func (i *Issuer) Introspect(ctx context.Context, token string) (Introspection, error) {
+	if strings.HasPrefix(token, opaquePrefix) {
+		state, err := i.opaqueToken(ctx, token)
+		if err == nil {
+			err = i.checkSession(ctx, jwt.Claims{
+				UserID:    state.UserId,
+				AppID:     state.AppId,
+				SessionID: jwt.SessionID(state.Claims),
+			})
+		}
+		if err != nil {
+			if errors.Is(err, ErrInvalidToken) {
+				return Introspection{}, nil
+			}
+
+			return Introspection{}, err
+		}
+
+		return Introspection{Active: true, AppID: state.AppId, Claims: state.Claims}, nil
+	}
+
+	claims, err := i.Parse(ctx, token)
+	if err != nil {
+		// storage failures and tokens Domofon can't read aren't a verdict on the token
+		if errors.Is(err, ErrFormatUnavailable) || errors.Is(err, ErrEncrypted) || IsStorageError(err) {
+			return Introspection{}, err
+		}
+
+		return Introspection{}, nil
+	}
+
+	res := Introspection{
+		Active: true,
+		AppID:  claims.AppID,
+		Claims: map[string]any{
+			"uid": claims.UserID,
+			"app": claims.AppID,
+			"org": claims.OrgID,
+			"exp": claims.Expiry.Unix(),
+		},
+	}
+	if claims.SessionID != "" {
+		res.Claims["sid"] = claims.SessionID
+	}
+	if claims.ACR != "" {
+		res.Claims["acr"] = claims.ACR
+	}
+	if len(claims.Audience) > 0 {
+		res.Claims["aud"] = claims.Audience
+	}
+
+	return res, nil
+}
+
+// Revoke makes opaque token inactive, self-contained tokens can't be revoked
+func (i *Issuer) Revoke(ctx context.Context, token string) error {
+	if !strings.HasPrefix(token, opaquePrefix) {
+		return ErrNotRevocable
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	i.cache.delete(hash)
+
+	if err := i.tokenStore.RevokeAccessToken(ctx, hash[:]); err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return ErrInvalidToken
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package tokens
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/storage"
+	"errors"
+	"time"
+)
+
+const (
+	// touchInterval limits writes of session last seen time to one per interval
+	touchInterval = time.Minute
+)
+
+type SessionStore interface {
+	Session(ctx context.Context, sessionID string) (models.Session, error)
+	TouchSession(ctx context.Context, sessionID string) error
+}
+
+// checkSession rejects tokens of revoked sessions and marks the session seen.
+// Tokens without sid were issued before sessions and stay valid until exp
+func (i *Issuer) checkSession(ctx context.Context, claims jwt.Claims) error {
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	session, err := i.sessions.Session(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return ErrInvalidToken
+		}
+
+		return storeError{err: err}
+	}
+
+	if !session.Active(time.Now()) || session.UserId != claims.UserID || session.AppId != claims.AppID {
+		return ErrInvalidToken
+	}
+
+	if time.Since(session.LastSeenAt) >= touchInterval {
+		// last seen time is informational, failing to update it doesn't fail the token
+		_ = i.sessions.TouchSession(ctx, session.Id)
+	}
+
+	return nil
+}
@@ -0,0 +1,342 @@
+package tokens
+
+import (
+	"aidanwoods.dev/go-paseto"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/cnf"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pasetoPublicPrefix = "v4.public."
+	pasetoLocalPrefix  = "v4.local."
+	// localKeyInfo separates v4.local keys from other uses of app secret
+	localKeyInfo = "domofon paseto v4.local"
+)
+
+var (
+	ErrFormatUnavailable = errors.New("token format isn't configured")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrNotRevocable      = errors.New("only opaque tokens can be revoked")
+	ErrNotEncryptable    = errors.New("only jwt tokens can be encrypted")
+	// ErrEncrypted is JWE token, only its app holds the key decrypting it
+	ErrEncrypted = errors.New("encrypted token is readable only by its app")
+)
+
+// timeClaims are unix seconds in jwt and RFC 3339 strings in paseto
+var timeClaims = []string{"exp", "iat", "nbf"}
+
+type AppSecretProvider interface {
+	AppSecrets(ctx context.Context, appID int32) ([]string, error)
+}
+
+// Issuer issues access tokens in format selected by app and verifies them
+type Issuer struct {
+	issuer      string
+	appSecrets  AppSecretProvider
+	tokenStore  TokenStore
+	sessions    SessionStore
+	cache       *opaqueCache
+	pasetoKey   paseto.V4AsymmetricSecretKey
+	pasetoKeyOk bool
+}
+
+// NewIssuer returns Issuer, pasetoSeed is hex encoded Ed25519 seed signing v4.public tokens.
+// Without the seed apps can't use v4.public format.
+// State of opaque tokens is cached for cacheTTL, sessions are never cached
+func NewIssuer(
+	issuer string,
+	appSecrets AppSecretProvider,
+	tokenStore TokenStore,
+	sessions SessionStore,
+	pasetoSeed string,
+	cacheTTL time.Duration,
+) (*Issuer, error) {
+	i := &Issuer{
+		issuer:     issuer,
+		appSecrets: appSecrets,
+		tokenStore: tokenStore,
+		sessions:   sessions,
+		cache:      newOpaqueCache(cacheTTL),
+	}
+
+	if pasetoSeed != "" {
+		key, err := paseto.NewV4AsymmetricSecretKeyFromSeed(pasetoSeed)
+		if err != nil {
+			return nil, err
+		}
+		i.pasetoKey = key
+		i.pasetoKeyOk = true
+	}
+
+	return i, nil
+}
+
+// PublicKey returns hex encoded key verifying v4.public tokens, empty without one
+func (i *Issuer) PublicKey() string {
+	if !i.pasetoKeyOk {
+		return ""
+	}
+
+	return i.pasetoKey.Public().ExportHex()
+}
+
+// Issue returns access token of user for app issued for the login auth describes, settings must have defaults applied
+func (i *Issuer) Issue(
+	ctx context.Context,
+	auth models.Authentication,
+	user models.User,
+	app models.App,
+	roles []models.Role,
+	extra map[string]any,
+	settings models.TokenSettings,
+) (string, error) {
+	// app format may come from defaults, encrypted app must never get a plain token
+	if settings.EncryptionKey != "" && settings.Format != models.TokenFormatJWT {
+		return "", ErrNotEncryptable
+	}
+
+	claims, err := jwt.AccessClaims(user, app, roles, extra, settings)
+	if err != nil {
+		return "", err
+	}
+	if c := cnf.FromContext(ctx).Claim(); c != nil {
+		claims["cnf"] = c
+	}
+	if auth.SessionID != "" {
+		claims["sid"] = auth.SessionID
+	}
+	if auth.ACR != "" {
+		claims["acr"] = auth.ACR
+	}
+	if len(auth.AMR) > 0 {
+		claims["amr"] = auth.AMR
+	}
+
+	switch settings.Format {
+	case models.TokenFormatPasetoPublic:
+		return i.issuePublic(claims)
+	case models.TokenFormatPasetoLocal:
+		return i.issueLocal(claims, app)
+	case models.TokenFormatOpaque:
+		return i.issueOpaque(ctx, user, app, claims, settings)
+	}
+
+	signed, err := jwt.Sign(claims, app, settings)
+	if err != nil || settings.EncryptionKey == "" {
+		return signed, err
+	}
+
+	return encrypt(signed, settings.EncryptionKey, settings.EncryptionAlg)
+}
+
+func (i *Issuer) issuePublic(claims map[string]any) (string, error) {
+	if !i.pasetoKeyOk {
+		return "", ErrFormatUnavailable
+	}
+
+	token, err := paseto.MakeToken(pasetoClaims(claims), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return token.V4Sign(i.pasetoKey, nil), nil
+}
+
+func (i *Issuer) issueLocal(claims map[string]any, app models.App) (string, error) {
+	// footer isn't encrypted, it only tells which app key decrypts the token
+	footer, err := json.Marshal(localFooter{Kid: strconv.Itoa(int(app.Id))})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := paseto.MakeToken(pasetoClaims(claims), footer)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := localKey(app.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	return token.V4Encrypt(key, nil), nil
+}
+
+// pasetoClaims converts unix times of claims into RFC 3339 strings
+func pasetoClaims(claims map[string]any) map[string]any {
+	for _, name := range timeClaims {
+		if v, ok := claims[name].(int64); ok {
+			claims[name] = time.Unix(v, 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	return claims
+}
+
+// Parse verifies access token of any format issued by Issue, tokens of revoked sessions are invalid
+func (i *Issuer) Parse(ctx context.Context, token string) (jwt.Claims, error) {
+	claims, err := i.parse(ctx, token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if err = i.checkSession(ctx, claims); err != nil {
+		return jwt.Claims{}, err
+	}
+
+	return claims, nil
+}
+
+func (i *Issuer) parse(ctx context.Context, token string) (jwt.Claims, error) {
+	switch {
+	case isEncrypted(token):
+		return jwt.Claims{}, ErrEncrypted
+	case strings.HasPrefix(token, pasetoPublicPrefix):
+		return i.parsePublic(token)
+	case strings.HasPrefix(token, pasetoLocalPrefix):
+		return i.parseLocal(ctx, token)
+	case strings.HasPrefix(token, opaquePrefix):
+		state, err := i.opaqueToken(ctx, token)
+		if err != nil {
+			return jwt.Claims{}, err
+		}
+
+		return jwt.Claims{
+			UserID:    state.UserId,
+			AppID:     state.AppId,
+			OrgID:     state.OrgId,
+			JKT:       jwt.ConfirmationJKT(state.Claims),
+			X5T:       jwt.ConfirmationX5T(state.Claims),
+			SessionID: jwt.SessionID(state.Claims),
+			ACR:       jwt.ACR(state.Claims),
+			Expiry:    state.ExpiresAt,
+			Audience:  jwt.Audience(state.Claims),
+		}, nil
+	}
+
+	return jwt.ParseToken(token, i.issuer, func(appID int32) ([]string, error) {
+		return i.secrets(ctx, appID)
+	})
+}
+
+// isEncrypted reports whether token is compact JWE, it has five parts where JWS has three
+func isEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// storeError is failure to load keys, it says nothing about the token
+type storeError struct {
+	err error
+}
+
+func (e storeError) Error() string {
+	return e.err.Error()
+}
+
+func (e storeError) Unwrap() error {
+	return e.err
+}
+
+// IsStorageError reports whether err of Parse is failure to load keys or token state
+// rather than a verdict on the token
+func IsStorageError(err error) bool {
+	var target storeError
+	return errors.As(err, &target)
+}
+
+// secrets returns secrets of app, unknown app invalidates token
+func (i *Issuer) secrets(ctx context.Context, appID int32) ([]string, error) {
+	secrets, err := i.appSecrets.AppSecrets(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return nil, ErrInvalidToken
+		}
+
+		return nil, storeError{err: err}
+	}
+
+	return secrets, nil
+}
+
+func (i *Issuer) parsePublic(token string) (jwt.Claims, error) {
+	if !i.pasetoKeyOk {
+		return jwt.Claims{}, ErrFormatUnavailable
+	}
+
+	parsed, err := paseto.NewParserForValidNow().ParseV4Public(i.pasetoKey.Public(), token, nil)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	return jwt.ClaimsOf(parsed.Claims(), i.issuer)
+}
+
+func (i *Issuer) parseLocal(ctx context.Context, token string) (jwt.Claims, error) {
+	parser := paseto.NewParserForValidNow()
+
+	rawFooter, err := parser.UnsafeParseFooter(paseto.V4Local, token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	var footer localFooter
+	if err = json.Unmarshal(rawFooter, &footer); err != nil {
+		return jwt.Claims{}, ErrInvalidToken
+	}
+	appID, err := strconv.ParseInt(footer.Kid, 10, 32)
+	if err != nil {
+		return jwt.Claims{}, ErrInvalidToken
+	}
+
+	secrets, err := i.secrets(ctx, int32(appID))
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	// secrets in rotation grace period still decrypt tokens issued before it
+	for _, secret := range secrets {
+		key, err := localKey(secret)
+		if err != nil {
+			return jwt.Claims{}, err
+		}
+
+		parsed, err := parser.ParseV4Local(key, token, nil)
+		if err != nil {
+			continue
+		}
+
+		claims, err := jwt.ClaimsOf(parsed.Claims(), i.issuer)
+		if err != nil {
+			return jwt.Claims{}, err
+		}
+		if claims.AppID != int32(appID) {
+			return jwt.Claims{}, ErrInvalidToken
+		}
+
+		return claims, nil
+	}
+
+	return jwt.Claims{}, ErrInvalidToken
+}
+
+type localFooter struct {
+	Kid string `json:"kid"`
+}
+
+// localKey derives v4.local key of app from its secret
+func localKey(secret string) (paseto.V4SymmetricKey, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(localKeyInfo))
+
+	return paseto.V4SymmetricKeyFromBytes(mac.Sum(nil))
+}
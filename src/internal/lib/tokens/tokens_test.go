@@ -0,0 +1,286 @@
+package tokens
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/storage"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer = "domofon"
+	testSecret = "app-secret"
+	// testSeed is hex encoded Ed25519 seed of v4.public tokens
+	testSeed = "0101010101010101010101010101010101010101010101010101010101010101"
+)
+
+type fakeSecrets struct{}
+
+func (fakeSecrets) AppSecrets(context.Context, int32) ([]string, error) {
+	return []string{testSecret}, nil
+}
+
+// appSecrets are secrets by app, primary one goes first
+type appSecrets map[int32][]string
+
+func (s appSecrets) AppSecrets(_ context.Context, appID int32) ([]string, error) {
+	secrets, ok := s[appID]
+	if !ok {
+		return nil, storage.ErrAppNotFound
+	}
+
+	return secrets, nil
+}
+
+func pasetoSettings(format string) models.TokenSettings {
+	return models.TokenSettings{
+		Format:    format,
+		AccessTTL: time.Hour,
+		Issuer:    testIssuer,
+		Claims:    models.ClaimSettings{Version: models.ClaimsV2},
+	}
+}
+
+func publicJWK(t *testing.T, key any) string {
+	t.Helper()
+
+	raw, err := json.Marshal(jose.JSONWebKey{Key: key})
+	require.NoError(t, err)
+
+	return string(raw)
+}
+
+func TestIssuer_encryptedRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		jwk        string
+		alg        string
+		privateKey any
+	}{
+		{
+			name:       "rsa default alg",
+			jwk:        publicJWK(t, &rsaKey.PublicKey),
+			privateKey: rsaKey,
+		},
+		{
+			name:       "rsa oaep",
+			jwk:        publicJWK(t, &rsaKey.PublicKey),
+			alg:        string(jose.RSA_OAEP),
+			privateKey: rsaKey,
+		},
+		{
+			name:       "ec ecdh-es",
+			jwk:        publicJWK(t, &ecKey.PublicKey),
+			privateKey: ecKey,
+		},
+		{
+			name:       "ec ecdh-es with key wrap",
+			jwk:        publicJWK(t, &ecKey.PublicKey),
+			alg:        string(jose.ECDH_ES_A256KW),
+			privateKey: ecKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewIssuer(testIssuer, fakeSecrets{}, nil, nil, "", 0)
+			require.NoError(t, err)
+
+			user := models.User{Id: 42, OrgId: 3, Email: "resident@domofon.test"}
+			app := models.App{Id: 7, OrgId: 3, Secret: testSecret}
+			settings := models.TokenSettings{
+				Format:        models.TokenFormatJWT,
+				AccessTTL:     time.Hour,
+				Issuer:        testIssuer,
+				Claims:        models.ClaimSettings{Version: models.ClaimsV2},
+				EncryptionKey: tt.jwk,
+				EncryptionAlg: tt.alg,
+			}
+
+			token, err := i.Issue(context.Background(), models.Authentication{}, user, app, nil, nil, settings)
+			require.NoError(t, err)
+
+			// Domofon can't read the token, it says so rather than calling it invalid
+			_, err = i.Parse(context.Background(), token)
+			require.ErrorIs(t, err, ErrEncrypted)
+			_, err = i.Introspect(context.Background(), token)
+			require.ErrorIs(t, err, ErrEncrypted)
+
+			// the app decrypts it with its private key and verifies the nested token
+			obj, err := jose.ParseEncrypted(token)
+			require.NoError(t, err)
+			assert.Equal(t, "JWT", obj.Header.ExtraHeaders[jose.HeaderContentType])
+
+			signed, err := obj.Decrypt(tt.privateKey)
+			require.NoError(t, err)
+
+			claims, err := jwt.ParseToken(string(signed), testIssuer, func(int32) ([]string, error) {
+				return []string{testSecret}, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, user.Id, claims.UserID)
+			assert.Equal(t, app.Id, claims.AppID)
+			assert.Equal(t, []string{"7"}, claims.Audience)
+		})
+	}
+}
+
+func TestIssuer_Issue_notEncryptable(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	i, err := NewIssuer(testIssuer, fakeSecrets{}, nil, nil, "", 0)
+	require.NoError(t, err)
+
+	_, err = i.Issue(context.Background(), models.Authentication{}, models.User{Id: 1}, models.App{Id: 1, Secret: testSecret}, nil, nil,
+		models.TokenSettings{
+			Format:        models.TokenFormatOpaque,
+			AccessTTL:     time.Hour,
+			EncryptionKey: publicJWK(t, &rsaKey.PublicKey),
+		})
+	require.ErrorIs(t, err, ErrNotEncryptable)
+}
+
+func TestIssuer_pasetoRoundTrip(t *testing.T) {
+	i, err := NewIssuer(testIssuer, appSecrets{7: {testSecret}}, nil, nil, testSeed, 0)
+	require.NoError(t, err)
+
+	user := models.User{Id: 42, OrgId: 3, Email: "resident@domofon.test"}
+	app := models.App{Id: 7, OrgId: 3, Secret: testSecret}
+	auth := models.Authentication{ACR: models.ACRPassword, AMR: []string{models.AMRPassword}}
+
+	tests := []struct {
+		name       string
+		format     string
+		wantPrefix string
+	}{
+		{name: "v4.public", format: models.TokenFormatPasetoPublic, wantPrefix: pasetoPublicPrefix},
+		{name: "v4.local", format: models.TokenFormatPasetoLocal, wantPrefix: pasetoLocalPrefix},
+		{name: "jwt", format: models.TokenFormatJWT, wantPrefix: "eyJ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := i.Issue(context.Background(), auth, user, app, nil, nil, pasetoSettings(tt.format))
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(token, tt.wantPrefix), token)
+
+			claims, err := i.Parse(context.Background(), token)
+			require.NoError(t, err)
+			assert.Equal(t, user.Id, claims.UserID)
+			assert.Equal(t, app.Id, claims.AppID)
+			assert.Equal(t, models.ACRPassword, claims.ACR)
+			assert.Equal(t, []string{"7"}, claims.Audience)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), claims.Expiry, time.Minute)
+		})
+	}
+}
+
+func TestIssuer_pasetoPublic_noSeed(t *testing.T) {
+	i, err := NewIssuer(testIssuer, fakeSecrets{}, nil, nil, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, i.PublicKey())
+
+	_, err = i.Issue(context.Background(), models.Authentication{}, models.User{Id: 1}, models.App{Id: 1, Secret: testSecret},
+		nil, nil, pasetoSettings(models.TokenFormatPasetoPublic))
+	require.ErrorIs(t, err, ErrFormatUnavailable)
+}
+
+func TestIssuer_pasetoLocal_rotatedSecret(t *testing.T) {
+	user := models.User{Id: 42, OrgId: 3}
+	app := models.App{Id: 7, OrgId: 3, Secret: "old-secret"}
+	settings := pasetoSettings(models.TokenFormatPasetoLocal)
+
+	before, err := NewIssuer(testIssuer, appSecrets{7: {"old-secret"}}, nil, nil, "", 0)
+	require.NoError(t, err)
+	token, err := before.Issue(context.Background(), models.Authentication{}, user, app, nil, nil, settings)
+	require.NoError(t, err)
+
+	// previous secret in grace period still decrypts the token
+	grace, err := NewIssuer(testIssuer, appSecrets{7: {"new-secret", "old-secret"}}, nil, nil, "", 0)
+	require.NoError(t, err)
+	claims, err := grace.Parse(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, claims.UserID)
+
+	expired, err := NewIssuer(testIssuer, appSecrets{7: {"new-secret"}}, nil, nil, "", 0)
+	require.NoError(t, err)
+	_, err = expired.Parse(context.Background(), token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestIssuer_pasetoLocal_otherApp(t *testing.T) {
+	i, err := NewIssuer(testIssuer, appSecrets{7: {"secret-7"}, 8: {"secret-8"}}, nil, nil, "", 0)
+	require.NoError(t, err)
+
+	user := models.User{Id: 42, OrgId: 3}
+	claims, err := jwt.AccessClaims(user, models.App{Id: 8, OrgId: 3}, nil, nil, pasetoSettings(models.TokenFormatPasetoLocal))
+	require.NoError(t, err)
+
+	// token of app 8 encrypted with key of app 7 and footer naming app 7
+	token, err := i.issueLocal(claims, models.App{Id: 7, OrgId: 3, Secret: "secret-7"})
+	require.NoError(t, err)
+	_, err = i.Parse(context.Background(), token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// footer naming app 7 on token encrypted with key of app 8
+	token, err = i.Issue(context.Background(), models.Authentication{}, user, models.App{Id: 8, OrgId: 3, Secret: "secret-8"},
+		nil, nil, pasetoSettings(models.TokenFormatPasetoLocal))
+	require.NoError(t, err)
+	_, err = i.Parse(context.Background(), strings.Replace(token, footerOf(t, "8"), footerOf(t, "7"), 1))
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// footerOf returns encoded v4.local footer naming app
+func footerOf(t *testing.T, appID string) string {
+	t.Helper()
+
+	raw, err := json.Marshal(localFooter{Kid: appID})
+	require.NoError(t, err)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func TestIntrospection_audience(t *testing.T) {
+	in := Introspection{
+		Active: true,
+		AppID:  7,
+		Claims: map[string]any{"uid": int64(42), "exp": int64(1700000000), "aud": []any{"7", "9"}},
+	}
+
+	tests := []struct {
+		name  string
+		appID int32
+		want  bool
+	}{
+		{name: "issued to app", appID: 7, want: true},
+		{name: "app in aud", appID: 9, want: true},
+		{name: "other app", appID: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, in.IsAudience(tt.appID))
+		})
+	}
+
+	assert.Equal(t, Introspection{
+		Active: true,
+		Claims: map[string]any{"exp": int64(1700000000), "aud": []any{"7", "9"}},
+	}, in.Limited())
+	assert.Equal(t, Introspection{}, Introspection{}.Limited())
+}
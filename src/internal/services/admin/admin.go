@@ -0,0 +1,210 @@
+package admin
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/pagination"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Admin manages users of organization on behalf of its admins
+type Admin struct {
+	log          *slog.Logger
+	userProvider UserProvider
+	userManager  UserManager
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, orgID int32, userID int64) (models.User, error)
+	Users(ctx context.Context, orgID int32, filter models.UserFilter, afterID int64, limit int) ([]models.User, error)
+}
+
+type UserManager interface {
+	UpdateUser(ctx context.Context, orgID int32, userID int64, upd models.UserUpdate) error
+	SetUserDisabled(ctx context.Context, orgID int32, userID int64, disabled bool) error
+	SetAdmin(ctx context.Context, orgID int32, userID int64, isAdmin bool) error
+	DeleteUser(ctx context.Context, orgID int32, userID int64) error
+}
+
+// NewAdmin returns new instance of Admin service
+func NewAdmin(
+	log *slog.Logger,
+	userProvider UserProvider,
+	userManager UserManager,
+) *Admin {
+	return &Admin{
+		log:          log,
+		userProvider: userProvider,
+		userManager:  userManager,
+	}
+}
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUserExists    = errors.New("user already exists")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrSelfLockout   = errors.New("admin can't disable, demote or delete themselves")
+)
+
+// ListUsers returns page of users and cursor of the next page, empty when there are no more
+func (a *Admin) ListUsers(
+	ctx context.Context,
+	orgID int,
+	filter models.UserFilter,
+	cursor string,
+	pageSize int,
+) ([]models.User, string, error) {
+	const op = "admin.listUsers"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+	)
+
+	afterID, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		log.Warn("invalid cursor", sl.Err(err))
+		return nil, "", fmt.Errorf("%s %w", op, ErrInvalidCursor)
+	}
+
+	pageSize = pagination.PageSize(pageSize)
+
+	// one extra row tells whether there is a next page
+	users, err := a.userProvider.Users(ctx, int32(orgID), filter, afterID, pageSize+1)
+	if err != nil {
+		log.Error("failed listing users", sl.Err(err))
+		return nil, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	users, next := pagination.Page(users, pageSize, func(u models.User) int64 { return u.Id })
+
+	return users, next, nil
+}
+
+func (a *Admin) GetUser(ctx context.Context, orgID int, userID int64) (models.User, error) {
+	const op = "admin.getUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	user, err := a.userProvider.UserByID(ctx, int32(orgID), userID)
+	if err != nil {
+		return models.User{}, a.storageError(log, op, err)
+	}
+
+	return user, nil
+}
+
+func (a *Admin) UpdateUser(ctx context.Context, orgID int, userID int64, upd models.UserUpdate) error {
+	const op = "admin.updateUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("updating user")
+
+	if err := a.userManager.UpdateUser(ctx, int32(orgID), userID, upd); err != nil {
+		return a.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+// DisableUser blocks login of user, disabled false enables user back.
+// Admin adminID can't disable themselves
+func (a *Admin) DisableUser(ctx context.Context, orgID int, adminID int64, userID int64, disabled bool) error {
+	const op = "admin.disableUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+		slog.Bool("disabled", disabled),
+	)
+
+	log.Info("changing user disabled state")
+
+	if disabled && adminID == userID {
+		log.Warn("admin tried to disable themselves")
+		return fmt.Errorf("%s %w", op, ErrSelfLockout)
+	}
+
+	if err := a.userManager.SetUserDisabled(ctx, int32(orgID), userID, disabled); err != nil {
+		return a.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+// SetAdmin grants or takes away admin flag, admin adminID can't demote themselves
+func (a *Admin) SetAdmin(ctx context.Context, orgID int, adminID int64, userID int64, isAdmin bool) error {
+	const op = "admin.setAdmin"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+		slog.Bool("is_admin", isAdmin),
+	)
+
+	log.Info("changing admin flag")
+
+	if !isAdmin && adminID == userID {
+		log.Warn("admin tried to demote themselves")
+		return fmt.Errorf("%s %w", op, ErrSelfLockout)
+	}
+
+	if err := a.userManager.SetAdmin(ctx, int32(orgID), userID, isAdmin); err != nil {
+		return a.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes user, admin adminID can't delete themselves
+func (a *Admin) DeleteUser(ctx context.Context, orgID int, adminID int64, userID int64) error {
+	const op = "admin.deleteUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("deleting user")
+
+	if adminID == userID {
+		log.Warn("admin tried to delete themselves")
+		return fmt.Errorf("%s %w", op, ErrSelfLockout)
+	}
+
+	if err := a.userManager.DeleteUser(ctx, int32(orgID), userID); err != nil {
+		return a.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (a *Admin) storageError(log *slog.Logger, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		log.Warn("user not found")
+		return fmt.Errorf("%s %w", op, ErrUserNotFound)
+	case errors.Is(err, storage.ErrUserExists):
+		log.Warn("user already exists")
+		return fmt.Errorf("%s %w", op, ErrUserExists)
+	}
+
+	log.Error("storage failed", sl.Err(err))
+	return fmt.Errorf("%s %w", op, err)
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type fakeManager struct {
+	UserManager
+	calls int
+}
+
+func (f *fakeManager) SetUserDisabled(context.Context, int32, int64, bool) error {
+	f.calls++
+	return nil
+}
+
+func (f *fakeManager) SetAdmin(context.Context, int32, int64, bool) error {
+	f.calls++
+	return nil
+}
+
+func (f *fakeManager) DeleteUser(context.Context, int32, int64) error {
+	f.calls++
+	return nil
+}
+
+func TestAdmin_selfLockout(t *testing.T) {
+	const adminID = 7
+
+	tests := []struct {
+		name    string
+		call    func(a *Admin) error
+		wantErr error
+	}{
+		{
+			name:    "disable themselves",
+			call:    func(a *Admin) error { return a.DisableUser(context.Background(), 1, adminID, adminID, true) },
+			wantErr: ErrSelfLockout,
+		},
+		{
+			name: "enable themselves",
+			call: func(a *Admin) error { return a.DisableUser(context.Background(), 1, adminID, adminID, false) },
+		},
+		{
+			name: "disable other user",
+			call: func(a *Admin) error { return a.DisableUser(context.Background(), 1, adminID, 8, true) },
+		},
+		{
+			name:    "demote themselves",
+			call:    func(a *Admin) error { return a.SetAdmin(context.Background(), 1, adminID, adminID, false) },
+			wantErr: ErrSelfLockout,
+		},
+		{
+			name: "demote other admin",
+			call: func(a *Admin) error { return a.SetAdmin(context.Background(), 1, adminID, 8, false) },
+		},
+		{
+			name:    "delete themselves",
+			call:    func(a *Admin) error { return a.DeleteUser(context.Background(), 1, adminID, adminID) },
+			wantErr: ErrSelfLockout,
+		},
+		{
+			name: "delete other user",
+			call: func(a *Admin) error { return a.DeleteUser(context.Background(), 1, adminID, 8) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeManager{}
+			a := NewAdmin(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, manager)
+
+			err := tt.call(a)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, manager.calls)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 1, manager.calls)
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package apps
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/risk"
+	"domofon/internal/lib/tokens"
+	"domofon/internal/storage"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+const (
+	secretLen = 32
+)
+
+// Apps manages client applications of organization
+type Apps struct {
+	log         *slog.Logger
+	appSaver    AppSaver
+	appProvider AppProvider
+	secretGrace time.Duration
+}
+
+type AppSaver interface {
+	SaveApp(ctx context.Context, app models.App) (int32, error)
+	UpdateApp(ctx context.Context, orgID int32, appID int32, upd models.AppUpdate) error
+	DeleteApp(ctx context.Context, orgID int32, appID int32) error
+	RotateAppSecret(ctx context.Context, orgID int32, appID int32, secret string, graceUntil time.Time) error
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int32) (models.App, error)
+	Apps(ctx context.Context, orgID int32) ([]models.App, error)
+}
+
+// NewApps returns new instance of Apps service.
+// Tokens signed with rotated secret stay valid for secretGrace
+func NewApps(
+	log *slog.Logger,
+	appSaver AppSaver,
+	appProvider AppProvider,
+	secretGrace time.Duration,
+) *Apps {
+	return &Apps{
+		log:         log,
+		appSaver:    appSaver,
+		appProvider: appProvider,
+		secretGrace: secretGrace,
+	}
+}
+
+var (
+	ErrAppExists          = errors.New("application already exists")
+	ErrAppNotFound        = errors.New("application not found")
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+	ErrInvalidGrantType   = errors.New("invalid grant type")
+	ErrInvalidTokenTTL    = errors.New("invalid token ttl")
+	ErrReservedClaim      = errors.New("static claim is reserved")
+	ErrInvalidClaimsVer   = errors.New("invalid claims version")
+	ErrInvalidTokenFormat = errors.New("invalid token format")
+	ErrEncryptionFormat   = errors.New("only jwt tokens can be encrypted")
+	ErrInvalidEncryption  = errors.New("invalid token encryption key")
+	ErrInvalidClientAuth  = errors.New("invalid tls client auth")
+	ErrInvalidSessionTTL  = errors.New("invalid session timeout")
+	ErrInvalidSessionMax  = errors.New("invalid session limit")
+	ErrInvalidRiskScore   = errors.New("invalid risk score")
+)
+
+var knownGrantTypes = map[string]bool{
+	models.GrantPassword:     true,
+	models.GrantRefreshToken: true,
+}
+
+// CreateApp creates app with generated secret. It's the only time the secret is returned
+func (a *Apps) CreateApp(
+	ctx context.Context,
+	orgID int,
+	name string,
+	redirectURIs []string,
+	grantTypes []string,
+	settings models.TokenSettings,
+	sessions models.SessionSettings,
+	riskSettings models.RiskSettings,
+	clientAuth *models.TLSClientAuth,
+) (models.App, error) {
+	const op = "apps.createApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("name", name),
+	)
+
+	log.Info("creating app")
+
+	if len(grantTypes) == 0 {
+		grantTypes = []string{models.GrantPassword}
+	}
+	if clientAuth != nil && clientAuth.IsZero() {
+		clientAuth = nil
+	}
+	if err := validate(redirectURIs, grantTypes, &settings, &sessions, &riskSettings, clientAuth); err != nil {
+		log.Warn("invalid app settings", sl.Err(err))
+		return models.App{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		log.Error("failed generating secret", sl.Err(err))
+		return models.App{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	app := models.App{
+		OrgId:         int32(orgID),
+		Name:          name,
+		Secret:        secret,
+		RedirectURIs:  redirectURIs,
+		GrantTypes:    grantTypes,
+		Tokens:        settings,
+		Sessions:      sessions,
+		Risk:          riskSettings,
+		TLSClientAuth: clientAuth,
+	}
+
+	app.Id, err = a.appSaver.SaveApp(ctx, app)
+	if err != nil {
+		return models.App{}, a.storageError(log, op, err)
+	}
+
+	return app, nil
+}
+
+// GetApp returns app of organization without its secret
+func (a *Apps) GetApp(ctx context.Context, orgID int, appID int) (models.App, error) {
+	const op = "apps.getApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("app_id", appID),
+	)
+
+	app, err := a.appProvider.App(ctx, int32(appID))
+	if err == nil && app.OrgId != int32(orgID) {
+		err = storage.ErrAppNotFound
+	}
+	if err != nil {
+		return models.App{}, a.storageError(log, op, err)
+	}
+
+	app.Secret = ""
+
+	return app, nil
+}
+
+// ListApps returns apps of organization without their secrets
+func (a *Apps) ListApps(ctx context.Context, orgID int) ([]models.App, error) {
+	const op = "apps.listApps"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+	)
+
+	list, err := a.appProvider.Apps(ctx, int32(orgID))
+	if err != nil {
+		return nil, a.storageError(log, op, err)
+	}
+
+	for i := range list {
+		list[i].Secret = ""
+	}
+
+	return list, nil
+}
+
+func (a *Apps) UpdateApp(ctx context.Context, orgID int, appID int, upd models.AppUpdate) error {
+	const op = "apps.updateApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("app_id", appID),
+	)
+
+	log.Info("updating app")
+
+	if err := validate(upd.RedirectURIs, upd.GrantTypes, upd.Tokens, upd.Sessions, upd.Risk, upd.TLSClientAuth); err != nil {
+		log.Warn("invalid app settings", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if err := a.appSaver.UpdateApp(ctx, int32(orgID), int32(appID), upd); err != nil {
+		return a.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (a *Apps) DeleteApp(ctx context.Context, orgID int, appID int) error {
+	const op = "apps.deleteApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("app_id", appID),
+	)
+
+	log.Info("deleting app")
+
+	if err := a.appSaver.DeleteApp(ctx, int32(orgID), int32(appID)); err != nil {
+		return a.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+// RotateAppSecret replaces primary secret of app and returns the new one.
+// Previous secret keeps verifying tokens until grace period ends
+func (a *Apps) RotateAppSecret(ctx context.Context, orgID int, appID int) (string, error) {
+	const op = "apps.rotateAppSecret"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("app_id", appID),
+	)
+
+	log.Info("rotating app secret")
+
+	secret, err := newSecret()
+	if err != nil {
+		log.Error("failed generating secret", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	graceUntil := time.Now().Add(a.secretGrace)
+	if err := a.appSaver.RotateAppSecret(ctx, int32(orgID), int32(appID), secret, graceUntil); err != nil {
+		return "", a.storageError(log, op, err)
+	}
+
+	return secret, nil
+}
+
+func (a *Apps) storageError(log *slog.Logger, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppExists):
+		log.Warn("app already exists")
+		return fmt.Errorf("%s %w", op, ErrAppExists)
+	case errors.Is(err, storage.ErrAppNotFound):
+		log.Warn("app not found")
+		return fmt.Errorf("%s %w", op, ErrAppNotFound)
+	}
+
+	log.Error("storage failed", sl.Err(err))
+	return fmt.Errorf("%s %w", op, err)
+}
+
+func validate(
+	redirectURIs []string,
+	grantTypes []string,
+	settings *models.TokenSettings,
+	sessions *models.SessionSettings,
+	riskSettings *models.RiskSettings,
+	clientAuth *models.TLSClientAuth,
+) error {
+	for _, raw := range redirectURIs {
+		u, err := url.Parse(raw)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("%w: %q", ErrInvalidRedirectURI, raw)
+		}
+	}
+
+	for _, g := range grantTypes {
+		if !knownGrantTypes[g] {
+			return fmt.Errorf("%w: %q", ErrInvalidGrantType, g)
+		}
+	}
+
+	if sessions != nil {
+		if models.Value(sessions.IdleTimeout) < 0 || models.Value(sessions.MaxLifetime) < 0 {
+			return ErrInvalidSessionTTL
+		}
+
+		switch sessions.LimitPolicy {
+		case "", models.SessionLimitReject, models.SessionLimitEvictOldest:
+		default:
+			return fmt.Errorf("%w: policy %q", ErrInvalidSessionMax, sessions.LimitPolicy)
+		}
+		if models.Value(sessions.MaxSessions) < 0 {
+			return ErrInvalidSessionMax
+		}
+	}
+
+	if riskSettings != nil {
+		step, deny := models.Value(riskSettings.StepUpScore), models.Value(riskSettings.DenyScore)
+		if step < 0 || step > risk.MaxScore || deny < 0 || deny > risk.MaxScore {
+			return ErrInvalidRiskScore
+		}
+		// step-up above deny would never be flagged
+		if step > 0 && deny > 0 && step >= deny {
+			return fmt.Errorf("%w: step-up %d isn't below deny %d", ErrInvalidRiskScore, step, deny)
+		}
+	}
+
+	if clientAuth != nil {
+		for _, t := range clientAuth.Thumbprints {
+			if b, err := base64.RawURLEncoding.DecodeString(t); err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("%w: thumbprint %q", ErrInvalidClientAuth, t)
+			}
+		}
+	}
+
+	if settings == nil {
+		return nil
+	}
+
+	if settings.AccessTTL < 0 {
+		return ErrInvalidTokenTTL
+	}
+
+	switch settings.Format {
+	case "", models.TokenFormatJWT, models.TokenFormatPasetoPublic, models.TokenFormatPasetoLocal,
+		models.TokenFormatOpaque:
+	default:
+		return ErrInvalidTokenFormat
+	}
+
+	if settings.EncryptionKey != "" {
+		if settings.Format != "" && settings.Format != models.TokenFormatJWT {
+			return ErrEncryptionFormat
+		}
+		if err := tokens.ValidateEncryptionKey(settings.EncryptionKey, settings.EncryptionAlg); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidEncryption, err)
+		}
+	}
+
+	switch settings.Claims.Version {
+	case 0, models.ClaimsV1, models.ClaimsV2:
+	default:
+		return ErrInvalidClaimsVer
+	}
+
+	for claim := range settings.Claims.Static {
+		if jwt.IsReserved(claim) {
+			return fmt.Errorf("%w: %q", ErrReservedClaim, claim)
+		}
+	}
+
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package apps
+
+import (
+	"context"
+	"domofon/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeSaver struct {
+	AppSaver
+	secret     string
+	graceUntil time.Time
+	err        error
+}
+
+func (f *fakeSaver) RotateAppSecret(_ context.Context, _ int32, _ int32, secret string, graceUntil time.Time) error {
+	f.secret, f.graceUntil = secret, graceUntil
+	return f.err
+}
+
+func TestApps_RotateAppSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		err     error
+		wantErr error
+	}{
+		{
+			name:  "previous secret kept for grace",
+			grace: time.Hour,
+		},
+		{
+			name:  "no grace",
+			grace: 0,
+		},
+		{
+			name:    "unknown app",
+			grace:   time.Hour,
+			err:     storage.ErrAppNotFound,
+			wantErr: ErrAppNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saver := &fakeSaver{err: tt.err}
+			a := NewApps(slog.New(slog.NewTextHandler(io.Discard, nil)), saver, nil, tt.grace)
+
+			before := time.Now()
+			secret, err := a.RotateAppSecret(context.Background(), 1, 2)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, secret)
+			assert.Equal(t, secret, saver.secret)
+			assert.WithinRange(t, saver.graceUntil, before.Add(tt.grace), time.Now().Add(tt.grace))
+		})
+	}
+}
@@ -2,9 +2,17 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
 	"domofon/internal/domain/models"
-	"domofon/internal/lib/jwt"
+	"domofon/internal/lib/cnf"
+	"domofon/internal/lib/geoip"
+	"domofon/internal/lib/hasher"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/policy"
+	"domofon/internal/lib/requestmeta"
+	"domofon/internal/lib/risk"
 	"domofon/internal/storage"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
@@ -12,42 +20,190 @@ import (
 	"time"
 )
 
+const (
+	sessionIDLen = 16
+)
+
 type Auth struct {
-	log          *slog.Logger
-	userSaver    UserSaver
-	userProvider UserProvider
-	appProvider  AppProvider
-	tokenTTL     time.Duration
+	log           *slog.Logger
+	userSaver     UserSaver
+	userProvider  UserProvider
+	appProvider   AppProvider
+	roleProvider  RoleProvider
+	groupProvider GroupProvider
+	sessionSaver  SessionSaver
+	loginHistory  LoginHistory
+	geo           GeoResolver
+	mailQueue     MailQueue
+	hasher        Hasher
+	policies      PolicyEvaluator
+	tokens        TokenIssuer
+	// tokenDefaults apply to apps without own token settings
+	tokenDefaults models.TokenSettings
+	// sessionDefaults apply to apps without own session settings
+	sessionDefaults models.SessionSettings
+	// riskDefaults apply to apps without own risk settings
+	riskDefaults models.RiskSettings
+	// failureWindow is how far back failed attempts raise risk of login
+	failureWindow time.Duration
+	travel        geoip.TravelPolicy
+	// challengeTravel counts impossible travel into risk score and flags the login for step-up,
+	// otherwise it's only recorded
+	challengeTravel bool
+	// dummyHash is compared against when user doesn't exist,
+	// so login takes the same time for known and unknown emails
+	dummyHash       []byte
+	enumerationSafe bool
 }
 
 type UserSaver interface {
-	SaveUser(ctx context.Context, email string, passHash []byte) (int64, error)
+	SaveUser(ctx context.Context, orgID int32, email string, passHash []byte) (int64, error)
 }
 
 type UserProvider interface {
-	User(ctx context.Context, email string) (models.User, error)
-	IsAdmin(ctx context.Context, userID int64) (bool, error)
+	User(ctx context.Context, orgID int32, email string) (models.User, error)
+	IsAdmin(ctx context.Context, orgID int32, userID int64) (bool, error)
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int32) (models.App, error)
 }
 
-// NewAuth returns new instance of Auth service
+type RoleProvider interface {
+	UserRoles(ctx context.Context, orgID int32, userID int64, appID int32) ([]models.Role, error)
+}
+
+type GroupProvider interface {
+	UserGroups(ctx context.Context, orgID int32, userID int64) ([]models.Group, error)
+}
+
+type SessionSaver interface {
+	SaveSession(ctx context.Context, session models.Session, maxSessions int, evictOldest bool) error
+}
+
+type LoginHistory interface {
+	SaveLoginEvent(ctx context.Context, event models.LoginEvent) (bool, error)
+	LastLocatedLogin(ctx context.Context, userID int64) (models.LoginEvent, error)
+	LoginFamiliarity(
+		ctx context.Context,
+		userID int64,
+		event models.LoginEvent,
+		failureWindow time.Duration,
+	) (models.LoginFamiliarity, error)
+}
+
+type GeoResolver interface {
+	Lookup(ip string) models.GeoLocation
+}
+
+type MailQueue interface {
+	Enqueue(to string, subject string, body string) error
+}
+
+type PolicyEvaluator interface {
+	Allow(ctx context.Context, expr string, in policy.Input) (bool, error)
+	Claims(ctx context.Context, expr string, in policy.Input) (map[string]any, error)
+}
+
+type TokenIssuer interface {
+	Issue(
+		ctx context.Context,
+		auth models.Authentication,
+		user models.User,
+		app models.App,
+		roles []models.Role,
+		extra map[string]any,
+		settings models.TokenSettings,
+	) (string, error)
+}
+
+type Hasher interface {
+	Hash(ctx context.Context, pass []byte) ([]byte, error)
+	Compare(ctx context.Context, hash []byte, pass []byte) error
+}
+
+// TokenOptions issue access tokens of logins
+type TokenOptions struct {
+	Issuer TokenIssuer
+	// Defaults apply to apps without own token settings
+	Defaults models.TokenSettings
+}
+
+// SessionOptions keep sessions of logins
+type SessionOptions struct {
+	Saver SessionSaver
+	// Defaults apply to apps without own session settings
+	Defaults models.SessionSettings
+}
+
+// RiskOptions score logins against login history of user
+type RiskOptions struct {
+	History LoginHistory
+	Geo     GeoResolver
+	// Defaults apply to apps without own risk settings
+	Defaults models.RiskSettings
+	// FailureWindow is how far back failed attempts raise risk of login
+	FailureWindow time.Duration
+	Travel        geoip.TravelPolicy
+	// ChallengeTravel counts impossible travel into risk score and flags the login for step-up,
+	// otherwise it's only recorded
+	ChallengeTravel bool
+}
+
+// NewAuth returns new instance of Auth service.
+// With enumerationSafe Register responds the same for new and existing emails
 func NewAuth(
 	log *slog.Logger,
 	userSaver UserSaver,
 	userProvider UserProvider,
 	appProvider AppProvider,
-	tokenTTL time.Duration,
+	roleProvider RoleProvider,
+	groupProvider GroupProvider,
+	mailQueue MailQueue,
+	hasher Hasher,
+	policies PolicyEvaluator,
+	tokens TokenOptions,
+	sessions SessionOptions,
+	risk RiskOptions,
+	enumerationSafe bool,
 ) *Auth {
 	return &Auth{
-		log:          log,
-		userSaver:    userSaver,
-		userProvider: userProvider,
-		appProvider:  appProvider,
-		tokenTTL:     tokenTTL,
+		log:             log,
+		userSaver:       userSaver,
+		userProvider:    userProvider,
+		appProvider:     appProvider,
+		roleProvider:    roleProvider,
+		groupProvider:   groupProvider,
+		sessionSaver:    sessions.Saver,
+		loginHistory:    risk.History,
+		geo:             risk.Geo,
+		mailQueue:       mailQueue,
+		hasher:          hasher,
+		policies:        policies,
+		tokens:          tokens.Issuer,
+		tokenDefaults:   tokens.Defaults,
+		sessionDefaults: sessions.Defaults,
+		riskDefaults:    risk.Defaults,
+		failureWindow:   risk.FailureWindow,
+		travel:          risk.Travel,
+		challengeTravel: risk.ChallengeTravel,
+		dummyHash:       mustDummyHash(),
+		enumerationSafe: enumerationSafe,
+	}
+}
+
+func mustDummyHash() []byte {
+	pass := make([]byte, 32)
+	if _, err := rand.Read(pass); err != nil {
+		panic(err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword(pass, bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
 	}
+
+	return hash
 }
 
 var (
@@ -55,6 +211,15 @@ var (
 	ErrInvalidApp         = errors.New("invalid application")
 	ErrUserExists         = errors.New("user already exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrBusy               = errors.New("service is busy")
+	ErrPolicyDenied       = errors.New("denied by app policy")
+	ErrInvalidOrg         = errors.New("invalid organization")
+	ErrUserDisabled       = errors.New("user disabled")
+	ErrGrantNotAllowed    = errors.New("grant type not allowed for app")
+	ErrDPoPRequired       = errors.New("dpop proof required by app")
+	ErrClientCertRequired = errors.New("client certificate of app required")
+	ErrSessionLimit       = errors.New("session limit reached")
+	ErrRiskDenied         = errors.New("login denied as too risky")
 )
 
 func (a *Auth) Login(ctx context.Context, pass string, email string, appID int) (string, error) {
@@ -67,90 +232,499 @@ func (a *Auth) Login(ctx context.Context, pass string, email string, appID int)
 
 	log.Info("attempting to login user")
 
-	user, err := a.userProvider.User(ctx, email)
+	app, err := a.appProvider.App(ctx, int32(appID))
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Error("app not found")
+			return "", fmt.Errorf("%s %w", op, ErrInvalidApp)
+		}
+
+		log.Error("failed getting app by app_id", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	meta := requestmeta.FromContext(ctx)
+	event := models.LoginEvent{
+		OrgId:     app.OrgId,
+		AppId:     app.Id,
+		Email:     email,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Device:    meta.Fingerprint(),
+		Network:   risk.Network(meta.IP),
+		Geo:       a.geo.Lookup(meta.IP),
+	}
+
+	token, err := a.login(ctx, log, op, app, pass, email, &event)
+	a.recordLogin(ctx, event, err)
+
+	return token, err
+}
+
+// login authenticates user in app, event gets user once the email matches one
+func (a *Auth) login(
+	ctx context.Context,
+	log *slog.Logger,
+	op string,
+	app models.App,
+	pass string,
+	email string,
+	event *models.LoginEvent,
+) (string, error) {
+	if !app.AllowsGrant(models.GrantPassword) {
+		log.Warn("password grant not allowed for app")
+		return "", fmt.Errorf("%s %w", op, ErrGrantNotAllowed)
+	}
+
+	if app.Tokens.RequireDPoP && cnf.FromContext(ctx).JKT == "" {
+		log.Warn("login without dpop proof")
+		return "", fmt.Errorf("%s %w", op, ErrDPoPRequired)
+	}
+
+	if app.TLSClientAuth != nil && !requestmeta.FromContext(ctx).ClientCertMatches(*app.TLSClientAuth) {
+		log.Warn("login without client certificate of app")
+		return "", fmt.Errorf("%s %w", op, ErrClientCertRequired)
+	}
+
+	// users are looked up only in organization of the app
+	user, err := a.userProvider.User(ctx, app.OrgId, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
-			log.Warn("user not found", err)
+			// unknown email must fail the same way as a wrong password, overload included
+			err := a.hasher.Compare(ctx, a.dummyHash, []byte(pass))
+			if err != nil && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return "", a.hashingError(log, op, err)
+			}
+
+			log.Warn("user not found")
 			return "", fmt.Errorf("%s %w", op, ErrInvalidCredentials)
 		}
 
-		log.Error("failed getting user by email", err)
+		log.Error("failed getting user by email", sl.Err(err))
 		return "", fmt.Errorf("%s %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(pass)); err != nil {
-		log.Error("invalid password", err)
+	event.UserId = &user.Id
+
+	if err := a.hasher.Compare(ctx, user.PassHash, []byte(pass)); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return "", a.hashingError(log, op, err)
+		}
+
+		log.Warn("invalid password")
 		return "", fmt.Errorf("%s %w", op, ErrInvalidCredentials)
 	}
 
-	app, err := a.appProvider.App(ctx, int32(appID))
+	if user.DisabledAt != nil {
+		log.Warn("user disabled")
+		return "", fmt.Errorf("%s %w", op, ErrUserDisabled)
+	}
+
+	if err := a.assessRisk(ctx, log, user, app, event); err != nil {
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	in := policy.Input{
+		User:    user,
+		App:     app,
+		Request: requestmeta.FromContext(ctx),
+		Time:    time.Now(),
+	}
+
+	allowed, err := a.policies.Allow(ctx, app.LoginPolicy, in)
 	if err != nil {
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Error("app not found")
-			return "", fmt.Errorf("%s %w", op, ErrInvalidApp)
+		log.Error("failed evaluating login policy", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+	if !allowed {
+		log.Warn("login denied by app policy")
+		return "", fmt.Errorf("%s %w", op, ErrPolicyDenied)
+	}
+
+	extra, err := a.policies.Claims(ctx, app.ClaimsPolicy, in)
+	if err != nil {
+		log.Error("failed evaluating claims policy", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	if app.GroupsClaim {
+		groups, err := a.groupProvider.UserGroups(ctx, app.OrgId, user.Id)
+		if err != nil {
+			log.Error("failed getting user groups", sl.Err(err))
+			return "", fmt.Errorf("%s %w", op, err)
 		}
 
-		log.Error("failed getting app by app_id", err)
+		names := make([]string, 0, len(groups))
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+		if extra == nil {
+			extra = make(map[string]any)
+		}
+		extra["groups"] = names
+	}
+
+	roles, err := a.roleProvider.UserRoles(ctx, user.OrgId, user.Id, app.Id)
+	if err != nil {
+		log.Error("failed getting user roles", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		log.Error("failed generating session id", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	auth := models.Authentication{SessionID: sessionID, ACR: models.ACRPassword, AMR: []string{models.AMRPassword}}
+	if event.StepUp {
+		auth.ACR = models.ACRStepUpRequired
+	}
+
+	token, err := a.tokens.Issue(ctx, auth, user, app, roles, extra, app.Tokens.WithDefaults(a.tokenDefaults))
+	if err != nil {
+		log.Error("failed generating token", sl.Err(err))
 		return "", fmt.Errorf("%s %w", op, err)
 	}
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+	// token of unsaved session is never accepted, so it's saved only once issuing succeeded
+	sessions := app.Sessions.WithDefaults(a.sessionDefaults)
+	maxSessions := models.Value(sessions.MaxSessions)
+	err = a.sessionSaver.SaveSession(
+		ctx,
+		newSession(sessionID, user, app, in, sessions),
+		maxSessions,
+		sessions.LimitPolicy == models.SessionLimitEvictOldest,
+	)
 	if err != nil {
-		log.Error("failed generating token", err)
+		if errors.Is(err, storage.ErrSessionLimit) {
+			log.Warn("session limit reached", slog.Int("max_sessions", maxSessions))
+			return "", fmt.Errorf("%s %w", op, ErrSessionLimit)
+		}
+
+		log.Error("failed saving session", sl.Err(err))
 		return "", fmt.Errorf("%s %w", op, err)
 	}
 
 	return token, nil
 }
 
-func (a *Auth) Register(ctx context.Context, pass string, email string) (int64, error) {
+// assessRisk scores login of user into event and decides on it by risk settings of app.
+// Impossible travel is only flagged on event, with challengeTravel it also raises the score
+// and puts the login to step-up. Step-up is flagged on event and tokens of the login get acr 0,
+// only deny score refuses login
+func (a *Auth) assessRisk(
+	ctx context.Context,
+	log *slog.Logger,
+	user models.User,
+	app models.App,
+	event *models.LoginEvent,
+) error {
+	if err := a.checkTravel(ctx, log, user, event); err != nil {
+		return err
+	}
+
+	familiarity, err := a.loginHistory.LoginFamiliarity(ctx, user.Id, *event, a.failureWindow)
+	if err != nil {
+		log.Error("failed comparing login with history", sl.Err(err))
+		return err
+	}
+
+	scored := *event
+	if !a.challengeTravel {
+		scored.ImpossibleTravel = false
+	}
+
+	assessment := risk.Assess(scored, familiarity)
+	event.RiskScore, event.RiskSignals = assessment.Score, assessment.Signals
+
+	settings := app.Risk.WithDefaults(a.riskDefaults)
+	stepUpScore, denyScore := models.Value(settings.StepUpScore), models.Value(settings.DenyScore)
+	log = log.With(
+		slog.Int("risk_score", assessment.Score),
+		slog.Any("risk_signals", assessment.Signals),
+	)
+
+	switch {
+	case denyScore > 0 && assessment.Score >= denyScore:
+		log.Warn("login denied by risk score")
+		return ErrRiskDenied
+	case stepUpScore > 0 && assessment.Score >= stepUpScore:
+		event.StepUp = true
+		log.Warn("login flagged for step-up")
+		return nil
+	case scored.ImpossibleTravel:
+		event.StepUp = true
+		log.Warn("login flagged for step-up by impossible travel")
+		return nil
+	}
+
+	log.Info("login risk assessed")
+
+	return nil
+}
+
+// checkTravel flags login located too far from the previous one to get there since it
+func (a *Auth) checkTravel(ctx context.Context, log *slog.Logger, user models.User, event *models.LoginEvent) error {
+	if !event.Geo.HasCoordinates {
+		return nil
+	}
+
+	prev, err := a.loginHistory.LastLocatedLogin(ctx, user.Id)
+	if err != nil {
+		if errors.Is(err, storage.ErrLoginEventNotFound) {
+			return nil
+		}
+
+		log.Error("failed getting previous login", sl.Err(err))
+		return err
+	}
+
+	if !a.travel.Impossible(prev.Geo, prev.CreatedAt, event.Geo, time.Now()) {
+		return nil
+	}
+
+	event.ImpossibleTravel = true
+	log.Warn(
+		"impossible travel since previous login",
+		slog.String("from", prev.Geo.Country),
+		slog.String("to", event.Geo.Country),
+		slog.Float64("distance_km", geoip.Distance(prev.Geo, event.Geo)),
+		slog.Duration("since", time.Since(prev.CreatedAt)),
+	)
+
+	return nil
+}
+
+// recordLogin saves attempt ended with err into login history and tells user about login from new device.
+// Failures not caused by the attempt itself, like storage errors, aren't recorded
+func (a *Auth) recordLogin(ctx context.Context, event models.LoginEvent, err error) {
+	const op = "auth.recordLogin"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(event.AppId)),
+	)
+
+	event.Success = err == nil
+	if err != nil {
+		event.Reason = loginFailureReason(err, event)
+		if event.Reason == "" {
+			return
+		}
+	}
+
+	newDevice, err := a.loginHistory.SaveLoginEvent(ctx, event)
+	if err != nil {
+		log.Error("failed saving login event", sl.Err(err))
+		return
+	}
+
+	if newDevice {
+		a.notifyNewDevice(log, event)
+	}
+}
+
+func loginFailureReason(err error, event models.LoginEvent) string {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		if event.UserId == nil {
+			return models.LoginUnknownUser
+		}
+		return models.LoginInvalidPassword
+	case errors.Is(err, ErrUserDisabled):
+		return models.LoginUserDisabled
+	case errors.Is(err, ErrGrantNotAllowed):
+		return models.LoginGrantNotAllowed
+	case errors.Is(err, ErrDPoPRequired):
+		return models.LoginDPoPRequired
+	case errors.Is(err, ErrClientCertRequired):
+		return models.LoginClientCertRequired
+	case errors.Is(err, ErrPolicyDenied):
+		return models.LoginPolicyDenied
+	case errors.Is(err, ErrSessionLimit):
+		return models.LoginSessionLimit
+	case errors.Is(err, ErrRiskDenied):
+		return models.LoginRiskDenied
+	}
+
+	return ""
+}
+
+// notifyNewDevice queues mail about login from device user never logged in from
+func (a *Auth) notifyNewDevice(log *slog.Logger, event models.LoginEvent) {
+	body := fmt.Sprintf(
+		"Your Domofon account was just signed in to from a new device.\n\n"+
+			"IP address: %s\nDevice: %s\n\n"+
+			"If it was you, no action is needed. "+
+			"Otherwise change your password and sign out of your other sessions.",
+		event.IP,
+		event.UserAgent,
+	)
+
+	if err := a.mailQueue.Enqueue(event.Email, "New sign-in to your Domofon account", body); err != nil {
+		log.Warn("failed queueing new device notification", sl.Err(err))
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, sessionIDLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// newSession returns session of login described by in, limited by settings of its app
+func newSession(
+	id string,
+	user models.User,
+	app models.App,
+	in policy.Input,
+	settings models.SessionSettings,
+) models.Session {
+	session := models.Session{
+		Id:          id,
+		OrgId:       app.OrgId,
+		AppId:       app.Id,
+		UserId:      user.Id,
+		IP:          in.Request.IP,
+		UserAgent:   in.Request.UserAgent,
+		CreatedAt:   in.Time,
+		LastSeenAt:  in.Time,
+		IdleTimeout: models.Value(settings.IdleTimeout),
+	}
+	if maxLifetime := models.Value(settings.MaxLifetime); maxLifetime > 0 {
+		expiresAt := in.Time.Add(maxLifetime)
+		session.ExpiresAt = &expiresAt
+	}
+
+	return session
+}
+
+// Register creates user in organization of app
+func (a *Auth) Register(ctx context.Context, pass string, email string, appID int) (int64, error) {
 	const op = "auth.register"
 
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("email", email),
+		slog.Int("app_id", appID),
 	)
 
 	log.Info("registering user")
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	orgID, err := a.appOrg(ctx, log, appID)
 	if err != nil {
-		log.Error("failed generating password hash", err)
 		return 0, fmt.Errorf("%s %w", op, err)
 	}
 
-	id, err := a.userSaver.SaveUser(ctx, email, hash)
+	hash, err := a.hasher.Hash(ctx, []byte(pass))
+	if err != nil {
+		return 0, a.hashingError(log, op, err)
+	}
+
+	id, err := a.userSaver.SaveUser(ctx, orgID, email, hash)
 	if err != nil {
+		if errors.Is(err, storage.ErrOrgNotFound) {
+			log.Warn("organization not found")
+			return 0, fmt.Errorf("%s %w", op, ErrInvalidOrg)
+		}
 		if errors.Is(err, storage.ErrUserExists) {
+			if a.enumerationSafe {
+				log.Warn("user already exists, notifying owner")
+				a.notifyAccountExists(email)
+				return 0, nil
+			}
+
 			log.Error("user already exists")
 			return 0, fmt.Errorf("%s %w", op, ErrUserExists)
 		}
 
-		log.Error("failed saving new user", err)
+		log.Error("failed saving new user", sl.Err(err))
 		return 0, fmt.Errorf("%s %w", op, err)
 	}
 
+	if a.enumerationSafe {
+		// id is hidden, otherwise the response tells new and existing emails apart
+		return 0, nil
+	}
+
 	return id, nil
 }
 
-func (a *Auth) IsAdmin(ctx context.Context, userID int) (bool, error) {
+// hashingError logs err and turns hasher overload into ErrBusy
+func (a *Auth) hashingError(log *slog.Logger, op string, err error) error {
+	if errors.Is(err, hasher.ErrQueueFull) || errors.Is(err, hasher.ErrQueueTimeout) {
+		log.Warn("hashing rejected", sl.Err(err))
+		return fmt.Errorf("%s %w", op, ErrBusy)
+	}
+
+	log.Error("failed hashing password", sl.Err(err))
+	return fmt.Errorf("%s %w", op, err)
+}
+
+// notifyAccountExists tells the owner someone tried to register with their email.
+// Mail is queued so the response time doesn't depend on it
+func (a *Auth) notifyAccountExists(email string) {
+	const op = "auth.notifyAccountExists"
+
+	log := a.log.With(slog.String("op", op))
+
+	err := a.mailQueue.Enqueue(
+		email,
+		"Domofon registration attempt",
+		"Someone tried to create a Domofon account with this email address. "+
+			"You already have an account, so nothing was changed. "+
+			"If it was you, sign in with your existing password.",
+	)
+	if err != nil {
+		log.Warn("failed queueing notification", sl.Err(err))
+	}
+}
+
+// IsAdmin reports whether user of organization of app is admin
+func (a *Auth) IsAdmin(ctx context.Context, appID int, userID int) (bool, error) {
 	const op = "auth.isAdmin"
 
 	log := a.log.With(
 		slog.String("op", op),
+		slog.Int("app_id", appID),
 		slog.Int("user_id", userID),
 	)
 
-	result, err := a.userProvider.IsAdmin(ctx, int64(userID))
+	orgID, err := a.appOrg(ctx, log, appID)
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	result, err := a.userProvider.IsAdmin(ctx, orgID, int64(userID))
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			log.Error("user not found")
 			return result, fmt.Errorf("%s %w", op, ErrUserNotFound)
 		}
 
-		log.Error("failed check admin status", err)
+		log.Error("failed check admin status", sl.Err(err))
 		return result, fmt.Errorf("%s %w", op, err)
 	}
 
 	return result, nil
 }
+
+// appOrg returns organization of app, unknown app is ErrInvalidApp
+func (a *Auth) appOrg(ctx context.Context, log *slog.Logger, appID int) (int32, error) {
+	app, err := a.appProvider.App(ctx, int32(appID))
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found")
+			return 0, ErrInvalidApp
+		}
+
+		log.Error("failed getting app by app_id", sl.Err(err))
+		return 0, err
+	}
+
+	return app.OrgId, nil
+}
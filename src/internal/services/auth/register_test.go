@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type fakeUsers struct {
+	UserSaver
+	emails map[string]int64
+	orgs   map[string]int32
+}
+
+func (f *fakeUsers) SaveUser(_ context.Context, orgID int32, email string, _ []byte) (int64, error) {
+	if _, ok := f.emails[email]; ok {
+		return 0, storage.ErrUserExists
+	}
+	f.orgs[email] = orgID
+
+	f.emails[email] = int64(len(f.emails) + 1)
+
+	return f.emails[email], nil
+}
+
+// fakeApps has app 1 of organization 1 and app 2 of organization 5
+type fakeApps struct{}
+
+func (fakeApps) App(_ context.Context, appID int32) (models.App, error) {
+	switch appID {
+	case 1:
+		return models.App{Id: 1, OrgId: 1}, nil
+	case 2:
+		return models.App{Id: 2, OrgId: 5}, nil
+	}
+
+	return models.App{}, storage.ErrAppNotFound
+}
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(_ context.Context, pass []byte) ([]byte, error) {
+	return pass, nil
+}
+
+func (fakeHasher) Compare(context.Context, []byte, []byte) error {
+	return nil
+}
+
+type fakeQueue struct {
+	sent []string
+}
+
+func (f *fakeQueue) Enqueue(to string, _ string, _ string) error {
+	f.sent = append(f.sent, to)
+	return nil
+}
+
+func newRegisterAuth(enumerationSafe bool) (*Auth, *fakeQueue) {
+	queue := &fakeQueue{}
+	a := &Auth{
+		log:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		userSaver:       &fakeUsers{emails: map[string]int64{"taken@domofon.test": 1}, orgs: map[string]int32{}},
+		appProvider:     fakeApps{},
+		hasher:          fakeHasher{},
+		mailQueue:       queue,
+		enumerationSafe: enumerationSafe,
+	}
+
+	return a, queue
+}
+
+func TestRegister_enumerationSafe(t *testing.T) {
+	a, queue := newRegisterAuth(true)
+	ctx := context.Background()
+
+	newID, newErr := a.Register(ctx, "password", "new@domofon.test", 1)
+	takenID, takenErr := a.Register(ctx, "password", "taken@domofon.test", 1)
+
+	require.NoError(t, newErr)
+	require.NoError(t, takenErr)
+	assert.Equal(t, newID, takenID, "response tells new and existing emails apart")
+	assert.Equal(t, []string{"taken@domofon.test"}, queue.sent, "only owner of existing account is notified")
+}
+
+func TestRegister_existingEmail(t *testing.T) {
+	a, queue := newRegisterAuth(false)
+
+	id, err := a.Register(context.Background(), "password", "taken@domofon.test", 1)
+
+	assert.ErrorIs(t, err, ErrUserExists)
+	assert.Zero(t, id)
+	assert.Empty(t, queue.sent)
+}
+
+func TestRegister_appOrg(t *testing.T) {
+	a, _ := newRegisterAuth(false)
+
+	_, err := a.Register(context.Background(), "password", "new@domofon.test", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), a.userSaver.(*fakeUsers).orgs["new@domofon.test"])
+
+	_, err = a.Register(context.Background(), "password", "other@domofon.test", 3)
+	assert.ErrorIs(t, err, ErrInvalidApp)
+}
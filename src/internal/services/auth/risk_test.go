@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/geoip"
+	"domofon/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+var (
+	moscow = models.GeoLocation{Country: "RU", Latitude: 55.7558, Longitude: 37.6173, HasCoordinates: true}
+	sydney = models.GeoLocation{Country: "AU", Latitude: -33.8688, Longitude: 151.2093, HasCoordinates: true}
+)
+
+type fakeHistory struct {
+	LoginHistory
+	last        *models.LoginEvent
+	familiarity models.LoginFamiliarity
+}
+
+func (f *fakeHistory) LastLocatedLogin(context.Context, int64) (models.LoginEvent, error) {
+	if f.last == nil {
+		return models.LoginEvent{}, storage.ErrLoginEventNotFound
+	}
+
+	return *f.last, nil
+}
+
+func (f *fakeHistory) LoginFamiliarity(
+	context.Context,
+	int64,
+	models.LoginEvent,
+	time.Duration,
+) (models.LoginFamiliarity, error) {
+	return f.familiarity, nil
+}
+
+func newRiskAuth(history *fakeHistory, challengeTravel bool, stepUp int, deny int) *Auth {
+	return &Auth{
+		log:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		loginHistory:    history,
+		riskDefaults:    models.RiskSettings{StepUpScore: &stepUp, DenyScore: &deny},
+		travel:          geoip.TravelPolicy{MaxSpeed: 1000, MinDistance: 200},
+		challengeTravel: challengeTravel,
+	}
+}
+
+func TestAssessRisk_travel(t *testing.T) {
+	familiar := models.LoginFamiliarity{KnownDevice: true, KnownNetwork: true, KnownCountry: true, KnownHour: true}
+
+	tests := []struct {
+		name       string
+		challenge  bool
+		deny       int
+		wantScore  int
+		wantStepUp bool
+		wantErr    error
+	}{
+		{name: "flag only records", challenge: false, deny: 40, wantScore: 0},
+		{name: "challenge steps up", challenge: true, deny: 0, wantScore: 40, wantStepUp: true},
+		{name: "challenge scores", challenge: true, deny: 40, wantScore: 40, wantErr: ErrRiskDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := &fakeHistory{
+				last:        &models.LoginEvent{Geo: moscow, CreatedAt: time.Now().Add(-time.Hour)},
+				familiarity: familiar,
+			}
+			a := newRiskAuth(history, tt.challenge, 0, tt.deny)
+			event := models.LoginEvent{Geo: sydney}
+
+			err := a.assessRisk(context.Background(), a.log, models.User{Id: 1}, models.App{}, &event)
+
+			assert.True(t, event.ImpossibleTravel)
+			assert.Equal(t, tt.wantScore, event.RiskScore)
+			assert.Equal(t, tt.wantStepUp, event.StepUp)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAssessRisk_outcomes(t *testing.T) {
+	// new device, network and country at unusual hour score 65
+	event := models.LoginEvent{Device: "device", Network: "10.0.0.0/24", Geo: models.GeoLocation{Country: "RU"}}
+
+	tests := []struct {
+		name       string
+		stepUp     int
+		deny       int
+		wantStepUp bool
+		wantErr    error
+	}{
+		{name: "allow", stepUp: 70, deny: 90},
+		{name: "step-up is only flagged", stepUp: 60, deny: 90, wantStepUp: true},
+		{name: "deny", stepUp: 40, deny: 60, wantErr: ErrRiskDenied},
+		{name: "thresholds off", stepUp: 0, deny: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newRiskAuth(&fakeHistory{}, false, tt.stepUp, tt.deny)
+			event := event
+
+			err := a.assessRisk(context.Background(), a.log, models.User{Id: 1}, models.App{}, &event)
+
+			assert.Equal(t, tt.wantStepUp, event.StepUp)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				assert.Equal(t, 65, event.RiskScore)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
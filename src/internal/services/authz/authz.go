@@ -0,0 +1,458 @@
+package authz
+
+import (
+	"context"
+	"domofon/internal/config"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+
+	opUnion          = "union"
+	opThis           = "this"
+	opComputed       = "computed_userset"
+	opTupleToUserset = "tuple_to_userset"
+)
+
+// Authz answers relationship questions like "can user:5 open door:3",
+// relations are rewritten according to namespaces config
+type Authz struct {
+	log         *slog.Logger
+	tupleSaver  TupleSaver
+	tupleReader TupleReader
+	namespaces  map[string]config.Namespace
+	maxDepth    int
+}
+
+type TupleSaver interface {
+	WriteTuples(ctx context.Context, orgID int32, inserts []models.RelationTuple, deletes []models.RelationTuple) (int64, error)
+}
+
+type TupleReader interface {
+	Revision(ctx context.Context) (int64, error)
+	Subjects(ctx context.Context, orgID int32, namespace string, objectID string, relation string, rev int64) ([]models.Subject, error)
+	Objects(ctx context.Context, orgID int32, namespace string, rev int64, afterID string, limit int) ([]string, error)
+}
+
+// NewAuthz returns new instance of Authz service
+func NewAuthz(
+	log *slog.Logger,
+	tupleSaver TupleSaver,
+	tupleReader TupleReader,
+	namespaces *config.Namespaces,
+	maxDepth int,
+) *Authz {
+	return &Authz{
+		log:         log,
+		tupleSaver:  tupleSaver,
+		tupleReader: tupleReader,
+		namespaces:  namespaces.Namespaces,
+		maxDepth:    maxDepth,
+	}
+}
+
+var (
+	ErrInvalidTuple     = errors.New("invalid relation tuple")
+	ErrInvalidToken     = errors.New("invalid consistency token")
+	ErrUnknownNamespace = errors.New("unknown namespace")
+	ErrUnknownRelation  = errors.New("unknown relation")
+	ErrMaxDepth         = errors.New("max depth exceeded")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
+// WriteTuples applies inserts and deletes to tuples of organization atomically,
+// returned token makes later checks see the change
+func (a *Authz) WriteTuples(
+	ctx context.Context,
+	orgID int,
+	inserts []models.RelationTuple,
+	deletes []models.RelationTuple,
+) (string, error) {
+	const op = "authz.writeTuples"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("inserts", len(inserts)),
+		slog.Int("deletes", len(deletes)),
+	)
+
+	for _, t := range append(inserts[:len(inserts):len(inserts)], deletes...) {
+		if err := a.validate(t); err != nil {
+			log.Warn("invalid tuple", sl.Err(err))
+			return "", fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	rev, err := a.tupleSaver.WriteTuples(ctx, int32(orgID), inserts, deletes)
+	if err != nil {
+		log.Error("failed writing tuples", sl.Err(err))
+		return "", fmt.Errorf("%s %w", op, err)
+	}
+
+	return EncodeToken(rev), nil
+}
+
+// Check reports whether subject has relation to object at snapshot of token,
+// empty token means latest snapshot. Returns token of evaluated snapshot
+func (a *Authz) Check(
+	ctx context.Context,
+	orgID int,
+	tuple models.RelationTuple,
+	token string,
+) (bool, string, error) {
+	const op = "authz.check"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("tuple", tuple.String()),
+	)
+
+	rev, err := a.revision(ctx, token)
+	if err != nil {
+		log.Warn("failed resolving snapshot", sl.Err(err))
+		return false, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	c := &checker{authz: a, ctx: ctx, orgID: int32(orgID), rev: rev, visited: map[string]bool{}}
+	ok, err := c.check(tuple.Namespace, tuple.ObjectId, tuple.Relation, tuple.Subject, 0)
+	if err != nil {
+		log.Error("failed checking tuple", sl.Err(err))
+		return false, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	return ok, EncodeToken(rev), nil
+}
+
+// Expand returns tree of subjects having relation to object
+func (a *Authz) Expand(
+	ctx context.Context,
+	orgID int,
+	userset models.Subject,
+	token string,
+) (models.UsersetTree, string, error) {
+	const op = "authz.expand"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("userset", userset.String()),
+	)
+
+	rev, err := a.revision(ctx, token)
+	if err != nil {
+		log.Warn("failed resolving snapshot", sl.Err(err))
+		return models.UsersetTree{}, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	tree, err := a.expand(ctx, int32(orgID), userset, rev, 0)
+	if err != nil {
+		log.Error("failed expanding userset", sl.Err(err))
+		return models.UsersetTree{}, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	return tree, EncodeToken(rev), nil
+}
+
+// ListObjects returns ids of objects in namespace subject has relation to.
+// Every call checks at most pageSize candidates, so a page may hold fewer objects
+// and still have next cursor. Cursor is only meaningful with the returned token
+func (a *Authz) ListObjects(
+	ctx context.Context,
+	orgID int,
+	namespace string,
+	relation string,
+	subject models.Subject,
+	token string,
+	cursor string,
+	pageSize int,
+) ([]string, string, string, error) {
+	const op = "authz.listObjects"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("namespace", namespace),
+		slog.String("relation", relation),
+		slog.String("subject", subject.String()),
+	)
+
+	if _, err := a.relation(namespace, relation); err != nil {
+		log.Warn("invalid relation", sl.Err(err))
+		return nil, "", "", fmt.Errorf("%s %w", op, err)
+	}
+
+	afterID, err := decodeCursor(cursor)
+	if err != nil {
+		log.Warn("invalid cursor", sl.Err(err))
+		return nil, "", "", fmt.Errorf("%s %w", op, ErrInvalidCursor)
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+
+	rev, err := a.revision(ctx, token)
+	if err != nil {
+		log.Warn("failed resolving snapshot", sl.Err(err))
+		return nil, "", "", fmt.Errorf("%s %w", op, err)
+	}
+
+	candidates, err := a.tupleReader.Objects(ctx, int32(orgID), namespace, rev, afterID, pageSize+1)
+	if err != nil {
+		log.Error("failed listing objects", sl.Err(err))
+		return nil, "", "", fmt.Errorf("%s %w", op, err)
+	}
+
+	var next string
+	if len(candidates) > pageSize {
+		candidates = candidates[:pageSize]
+		next = encodeCursor(candidates[len(candidates)-1])
+	}
+
+	var objects []string
+	for _, id := range candidates {
+		// fresh checker per candidate, answers cached inside a cycle are only valid for its root
+		c := &checker{authz: a, ctx: ctx, orgID: int32(orgID), rev: rev, visited: map[string]bool{}}
+
+		ok, err := c.check(namespace, id, relation, subject, 0)
+		if err != nil {
+			log.Error("failed checking object", slog.String("object_id", id), sl.Err(err))
+			return nil, "", "", fmt.Errorf("%s %w", op, err)
+		}
+		if ok {
+			objects = append(objects, id)
+		}
+	}
+
+	return objects, next, EncodeToken(rev), nil
+}
+
+func (a *Authz) revision(ctx context.Context, token string) (int64, error) {
+	latest, err := a.tupleReader.Revision(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if token == "" {
+		return latest, nil
+	}
+
+	rev, err := DecodeToken(token)
+	if err != nil {
+		return 0, err
+	}
+	if rev > latest {
+		return 0, ErrInvalidToken
+	}
+
+	return rev, nil
+}
+
+func (a *Authz) relation(namespace string, relation string) (config.Relation, error) {
+	ns, ok := a.namespaces[namespace]
+	if !ok {
+		return config.Relation{}, fmt.Errorf("%w: %s", ErrUnknownNamespace, namespace)
+	}
+
+	rel, ok := ns.Relations[relation]
+	if !ok {
+		return config.Relation{}, fmt.Errorf("%w: %s#%s", ErrUnknownRelation, namespace, relation)
+	}
+
+	return rel, nil
+}
+
+func (a *Authz) validate(t models.RelationTuple) error {
+	if _, err := a.relation(t.Namespace, t.Relation); err != nil {
+		return err
+	}
+	if _, ok := a.namespaces[t.Subject.Namespace]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownNamespace, t.Subject.Namespace)
+	}
+	if t.Subject.Relation != "" {
+		if _, err := a.relation(t.Subject.Namespace, t.Subject.Relation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Authz) expand(
+	ctx context.Context,
+	orgID int32,
+	userset models.Subject,
+	rev int64,
+	depth int,
+) (models.UsersetTree, error) {
+	if depth > a.maxDepth {
+		return models.UsersetTree{}, ErrMaxDepth
+	}
+
+	rel, err := a.relation(userset.Namespace, userset.Relation)
+	if err != nil {
+		return models.UsersetTree{}, err
+	}
+
+	tree := models.UsersetTree{Operation: opUnion, Userset: userset}
+	for _, rewrite := range rewrites(rel) {
+		var child models.UsersetTree
+
+		switch {
+		case rewrite.This:
+			subjects, err := a.tupleReader.Subjects(ctx, orgID, userset.Namespace, userset.Id, userset.Relation, rev)
+			if err != nil {
+				return models.UsersetTree{}, err
+			}
+
+			child = models.UsersetTree{Operation: opThis, Userset: userset, Subjects: subjects}
+			for _, s := range subjects {
+				if s.Relation == "" {
+					continue
+				}
+
+				nested, err := a.expand(ctx, orgID, s, rev, depth+1)
+				if err != nil {
+					return models.UsersetTree{}, err
+				}
+				child.Children = append(child.Children, nested)
+			}
+		case rewrite.ComputedUserset != "":
+			computed := models.Subject{Namespace: userset.Namespace, Id: userset.Id, Relation: rewrite.ComputedUserset}
+
+			nested, err := a.expand(ctx, orgID, computed, rev, depth+1)
+			if err != nil {
+				return models.UsersetTree{}, err
+			}
+			child = models.UsersetTree{Operation: opComputed, Userset: computed, Children: []models.UsersetTree{nested}}
+		case rewrite.TupleToUserset != nil:
+			ttu := rewrite.TupleToUserset
+
+			objects, err := a.tupleReader.Subjects(ctx, orgID, userset.Namespace, userset.Id, ttu.Tupleset, rev)
+			if err != nil {
+				return models.UsersetTree{}, err
+			}
+
+			child = models.UsersetTree{Operation: opTupleToUserset, Userset: userset}
+			for _, o := range objects {
+				nested, err := a.expand(ctx, orgID, models.Subject{Namespace: o.Namespace, Id: o.Id, Relation: ttu.ComputedUserset}, rev, depth+1)
+				if err != nil {
+					return models.UsersetTree{}, err
+				}
+				child.Children = append(child.Children, nested)
+			}
+		default:
+			continue
+		}
+
+		tree.Children = append(tree.Children, child)
+	}
+
+	return tree, nil
+}
+
+// checker evaluates rewrites of a single snapshot and caches answers
+type checker struct {
+	authz   *Authz
+	ctx     context.Context
+	orgID   int32
+	rev     int64
+	visited map[string]bool
+}
+
+func (c *checker) check(namespace string, objectID string, relation string, subject models.Subject, depth int) (bool, error) {
+	if depth > c.authz.maxDepth {
+		return false, ErrMaxDepth
+	}
+
+	key := models.RelationTuple{Namespace: namespace, ObjectId: objectID, Relation: relation, Subject: subject}.String()
+	if res, ok := c.visited[key]; ok {
+		// false for branches still in progress, it breaks cycles between usersets
+		return res, nil
+	}
+	c.visited[key] = false
+
+	rel, err := c.authz.relation(namespace, relation)
+	if err != nil {
+		return false, err
+	}
+
+	for _, rewrite := range rewrites(rel) {
+		ok, err := c.rewrite(namespace, objectID, relation, rewrite, subject, depth)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			c.visited[key] = true
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *checker) rewrite(
+	namespace string,
+	objectID string,
+	relation string,
+	rewrite config.Rewrite,
+	subject models.Subject,
+	depth int,
+) (bool, error) {
+	switch {
+	case rewrite.This:
+		subjects, err := c.authz.tupleReader.Subjects(c.ctx, c.orgID, namespace, objectID, relation, c.rev)
+		if err != nil {
+			return false, err
+		}
+
+		for _, s := range subjects {
+			if s == subject {
+				return true, nil
+			}
+			if s.Relation == "" {
+				continue
+			}
+
+			ok, err := c.check(s.Namespace, s.Id, s.Relation, subject, depth+1)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+	case rewrite.ComputedUserset != "":
+		return c.check(namespace, objectID, rewrite.ComputedUserset, subject, depth+1)
+	case rewrite.TupleToUserset != nil:
+		ttu := rewrite.TupleToUserset
+
+		objects, err := c.authz.tupleReader.Subjects(c.ctx, c.orgID, namespace, objectID, ttu.Tupleset, c.rev)
+		if err != nil {
+			return false, err
+		}
+
+		for _, o := range objects {
+			ok, err := c.check(o.Namespace, o.Id, ttu.ComputedUserset, subject, depth+1)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// rewrites returns union of relation, relation without rewrites holds its own tuples
+func rewrites(rel config.Relation) []config.Rewrite {
+	if len(rel.Union) == 0 {
+		return []config.Rewrite{{This: true}}
+	}
+
+	return rel.Union
+}
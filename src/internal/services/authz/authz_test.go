@@ -0,0 +1,199 @@
+package authz
+
+import (
+	"context"
+	"domofon/internal/config"
+	"domofon/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+type fakeTuples struct {
+	TupleSaver
+	tuples []models.RelationTuple
+}
+
+func (f *fakeTuples) Revision(context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeTuples) Subjects(
+	_ context.Context,
+	_ int32,
+	namespace string,
+	objectID string,
+	relation string,
+	_ int64,
+) ([]models.Subject, error) {
+	var subjects []models.Subject
+	for _, t := range f.tuples {
+		if t.Namespace == namespace && t.ObjectId == objectID && t.Relation == relation {
+			subjects = append(subjects, t.Subject)
+		}
+	}
+
+	return subjects, nil
+}
+
+func (f *fakeTuples) Objects(
+	_ context.Context,
+	_ int32,
+	namespace string,
+	_ int64,
+	afterID string,
+	limit int,
+) ([]string, error) {
+	var objects []string
+	for _, t := range f.tuples {
+		if t.Namespace == namespace && t.ObjectId > afterID && !slices.Contains(objects, t.ObjectId) {
+			objects = append(objects, t.ObjectId)
+		}
+	}
+	slices.Sort(objects)
+
+	return objects[:min(limit, len(objects))], nil
+}
+
+func testNamespaces() *config.Namespaces {
+	return &config.Namespaces{Namespaces: map[string]config.Namespace{
+		"user": {},
+		"group": {Relations: map[string]config.Relation{
+			"member": {},
+		}},
+		"building": {Relations: map[string]config.Relation{
+			"manager": {},
+			"resident": {Union: []config.Rewrite{
+				{This: true},
+				{ComputedUserset: "manager"},
+			}},
+		}},
+		"door": {Relations: map[string]config.Relation{
+			"building": {},
+			"opener": {Union: []config.Rewrite{
+				{This: true},
+				{TupleToUserset: &config.TupleToUserset{Tupleset: "building", ComputedUserset: "resident"}},
+			}},
+		}},
+	}}
+}
+
+func newTestAuthz(t *testing.T, maxDepth int, tuples ...string) *Authz {
+	t.Helper()
+
+	reader := &fakeTuples{}
+	for _, s := range tuples {
+		tuple, err := ParseTuple(s)
+		require.NoError(t, err)
+		reader.tuples = append(reader.tuples, tuple)
+	}
+
+	return NewAuthz(slog.New(slog.NewTextHandler(io.Discard, nil)), reader, reader, testNamespaces(), maxDepth)
+}
+
+func TestAuthz_Check(t *testing.T) {
+	tuples := []string{
+		"building:7#manager@user:1",
+		"building:7#resident@user:2",
+		"building:7#resident@group:tenants#member",
+		"group:tenants#member@user:3",
+		"group:tenants#member@group:staff#member",
+		"group:staff#member@group:tenants#member",
+		"door:3#building@building:7",
+		"door:4#opener@user:4",
+	}
+
+	tests := []struct {
+		name     string
+		tuple    string
+		maxDepth int
+		want     bool
+		wantErr  error
+	}{
+		{name: "direct tuple", tuple: "door:4#opener@user:4", want: true},
+		{name: "computed userset", tuple: "building:7#resident@user:1", want: true},
+		{name: "tuple to userset", tuple: "door:3#opener@user:2", want: true},
+		{name: "nested userset", tuple: "door:3#opener@user:3", want: true},
+		{name: "no relation", tuple: "door:4#opener@user:2"},
+		{name: "cycle between usersets", tuple: "group:staff#member@user:9"},
+		{name: "max depth", tuple: "door:3#opener@user:3", maxDepth: 1, wantErr: ErrMaxDepth},
+		{name: "unknown relation", tuple: "door:3#closer@user:3", wantErr: ErrUnknownRelation},
+		{name: "unknown namespace", tuple: "gate:1#opener@user:3", wantErr: ErrUnknownNamespace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxDepth := tt.maxDepth
+			if maxDepth == 0 {
+				maxDepth = 25
+			}
+			a := newTestAuthz(t, maxDepth, tuples...)
+
+			tuple, err := ParseTuple(tt.tuple)
+			require.NoError(t, err)
+
+			got, token, err := a.Check(context.Background(), 1, tuple, "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, EncodeToken(1), token)
+		})
+	}
+}
+
+func TestAuthz_Check_snapshot(t *testing.T) {
+	a := newTestAuthz(t, 25, "door:4#opener@user:4")
+
+	tuple, err := ParseTuple("door:4#opener@user:4")
+	require.NoError(t, err)
+
+	_, _, err = a.Check(context.Background(), 1, tuple, EncodeToken(2))
+	assert.ErrorIs(t, err, ErrInvalidToken, "token from the future")
+
+	_, _, err = a.Check(context.Background(), 1, tuple, "not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthz_ListObjects_pages(t *testing.T) {
+	a := newTestAuthz(t, 25,
+		"door:1#opener@user:1",
+		"door:2#opener@user:2",
+		"door:3#building@building:7",
+		"door:4#opener@user:1",
+		"door:5#opener@user:1",
+		"building:7#resident@user:1",
+	)
+
+	subject := models.Subject{Namespace: "user", Id: "1"}
+
+	var (
+		objects []string
+		cursor  string
+		pages   int
+	)
+	for {
+		page, next, _, err := a.ListObjects(context.Background(), 1, "door", "opener", subject, "", cursor, 2)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page), 2)
+
+		objects = append(objects, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Equal(t, []string{"1", "3", "4", "5"}, objects)
+	assert.Equal(t, 3, pages)
+
+	_, _, _, err := a.ListObjects(context.Background(), 1, "door", "opener", subject, "", "%%%", 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
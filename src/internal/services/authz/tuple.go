@@ -0,0 +1,85 @@
+package authz
+
+import (
+	"domofon/internal/domain/models"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	tokenPrefix = "v1."
+)
+
+// ParseTuple parses "namespace:object#relation@subject"
+func ParseTuple(s string) (models.RelationTuple, error) {
+	object, subject, ok := strings.Cut(s, "@")
+	if !ok {
+		return models.RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidTuple, s)
+	}
+
+	userset, err := ParseSubject(object)
+	if err != nil || userset.Relation == "" {
+		return models.RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidTuple, s)
+	}
+
+	sub, err := ParseSubject(subject)
+	if err != nil {
+		return models.RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidTuple, s)
+	}
+
+	return models.RelationTuple{
+		Namespace: userset.Namespace,
+		ObjectId:  userset.Id,
+		Relation:  userset.Relation,
+		Subject:   sub,
+	}, nil
+}
+
+// ParseSubject parses "namespace:id" or "namespace:id#relation"
+func ParseSubject(s string) (models.Subject, error) {
+	object, relation, _ := strings.Cut(s, "#")
+	namespace, id, ok := strings.Cut(object, ":")
+	if !ok || namespace == "" || id == "" {
+		return models.Subject{}, fmt.Errorf("%w: %q", ErrInvalidTuple, s)
+	}
+	if strings.ContainsAny(relation, ":#@") {
+		return models.Subject{}, fmt.Errorf("%w: %q", ErrInvalidTuple, s)
+	}
+
+	return models.Subject{Namespace: namespace, Id: id, Relation: relation}, nil
+}
+
+func encodeCursor(objectID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(objectID))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
+
+// EncodeToken returns consistency token of revision
+func EncodeToken(rev int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenPrefix + strconv.FormatInt(rev, 10)))
+}
+
+// DecodeToken returns revision of consistency token
+func DecodeToken(token string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	rev, err := strconv.ParseInt(strings.TrimPrefix(string(raw), tokenPrefix), 10, 64)
+	if err != nil || !strings.HasPrefix(string(raw), tokenPrefix) || rev < 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return rev, nil
+}
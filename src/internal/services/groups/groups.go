@@ -0,0 +1,283 @@
+package groups
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Groups manages groups of users, groups may contain other groups
+type Groups struct {
+	log           *slog.Logger
+	groupSaver    GroupSaver
+	groupProvider GroupProvider
+}
+
+type GroupSaver interface {
+	SaveGroup(ctx context.Context, orgID int32, name string) (int32, error)
+	DeleteGroup(ctx context.Context, orgID int32, groupID int32) error
+	AddGroupUser(ctx context.Context, orgID int32, groupID int32, userID int64) error
+	RemoveGroupUser(ctx context.Context, orgID int32, groupID int32, userID int64) error
+	AddSubgroup(ctx context.Context, orgID int32, parentID int32, childID int32) error
+	RemoveSubgroup(ctx context.Context, orgID int32, parentID int32, childID int32) error
+	AssignGroupRole(ctx context.Context, orgID int32, groupID int32, appID int32, role string) error
+	RevokeGroupRole(ctx context.Context, orgID int32, groupID int32, appID int32, role string) error
+}
+
+type GroupProvider interface {
+	Groups(ctx context.Context, orgID int32) ([]models.Group, error)
+	UserGroups(ctx context.Context, orgID int32, userID int64) ([]models.Group, error)
+	GroupUsers(ctx context.Context, orgID int32, groupID int32) ([]int64, error)
+}
+
+// NewGroups returns new instance of Groups service
+func NewGroups(
+	log *slog.Logger,
+	groupSaver GroupSaver,
+	groupProvider GroupProvider,
+) *Groups {
+	return &Groups{
+		log:           log,
+		groupSaver:    groupSaver,
+		groupProvider: groupProvider,
+	}
+}
+
+var (
+	ErrGroupExists     = errors.New("group already exists")
+	ErrGroupNotFound   = errors.New("group not found")
+	ErrGroupCycle      = errors.New("group membership cycle")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrRoleNotAssigned = errors.New("role not assigned")
+)
+
+func (g *Groups) CreateGroup(ctx context.Context, orgID int, name string) (int32, error) {
+	const op = "groups.createGroup"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("name", name),
+	)
+
+	log.Info("creating group")
+
+	id, err := g.groupSaver.SaveGroup(ctx, int32(orgID), name)
+	if err != nil {
+		return 0, g.storageError(log, op, err)
+	}
+
+	return id, nil
+}
+
+func (g *Groups) DeleteGroup(ctx context.Context, orgID int, groupID int) error {
+	const op = "groups.deleteGroup"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("group_id", groupID),
+	)
+
+	log.Info("deleting group")
+
+	if err := g.groupSaver.DeleteGroup(ctx, int32(orgID), int32(groupID)); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (g *Groups) ListGroups(ctx context.Context, orgID int) ([]models.Group, error) {
+	const op = "groups.listGroups"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+	)
+
+	list, err := g.groupProvider.Groups(ctx, int32(orgID))
+	if err != nil {
+		return nil, g.storageError(log, op, err)
+	}
+
+	return list, nil
+}
+
+func (g *Groups) AddUser(ctx context.Context, orgID int, groupID int, userID int64) error {
+	const op = "groups.addUser"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("group_id", groupID),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("adding user to group")
+
+	if err := g.groupSaver.AddGroupUser(ctx, int32(orgID), int32(groupID), userID); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (g *Groups) RemoveUser(ctx context.Context, orgID int, groupID int, userID int64) error {
+	const op = "groups.removeUser"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("group_id", groupID),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("removing user from group")
+
+	if err := g.groupSaver.RemoveGroupUser(ctx, int32(orgID), int32(groupID), userID); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+// AddSubgroup makes members of child group members of parent group too
+func (g *Groups) AddSubgroup(ctx context.Context, orgID int, parentID int, childID int) error {
+	const op = "groups.addSubgroup"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("parent_id", parentID),
+		slog.Int("child_id", childID),
+	)
+
+	log.Info("adding subgroup")
+
+	if err := g.groupSaver.AddSubgroup(ctx, int32(orgID), int32(parentID), int32(childID)); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (g *Groups) RemoveSubgroup(ctx context.Context, orgID int, parentID int, childID int) error {
+	const op = "groups.removeSubgroup"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("parent_id", parentID),
+		slog.Int("child_id", childID),
+	)
+
+	log.Info("removing subgroup")
+
+	if err := g.groupSaver.RemoveSubgroup(ctx, int32(orgID), int32(parentID), int32(childID)); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+// UserGroups returns groups user belongs to, nested membership included
+func (g *Groups) UserGroups(ctx context.Context, orgID int, userID int64) ([]models.Group, error) {
+	const op = "groups.userGroups"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	list, err := g.groupProvider.UserGroups(ctx, int32(orgID), userID)
+	if err != nil {
+		return nil, g.storageError(log, op, err)
+	}
+
+	return list, nil
+}
+
+// GroupUsers returns ids of group members, nested membership included
+func (g *Groups) GroupUsers(ctx context.Context, orgID int, groupID int) ([]int64, error) {
+	const op = "groups.groupUsers"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("group_id", groupID),
+	)
+
+	ids, err := g.groupProvider.GroupUsers(ctx, int32(orgID), int32(groupID))
+	if err != nil {
+		return nil, g.storageError(log, op, err)
+	}
+
+	return ids, nil
+}
+
+// AssignRole grants role of app to all members of group
+func (g *Groups) AssignRole(ctx context.Context, orgID int, groupID int, appID int, role string) error {
+	const op = "groups.assignRole"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("group_id", groupID),
+		slog.Int("app_id", appID),
+		slog.String("role", role),
+	)
+
+	log.Info("assigning role to group")
+
+	if err := g.groupSaver.AssignGroupRole(ctx, int32(orgID), int32(groupID), int32(appID), role); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (g *Groups) RevokeRole(ctx context.Context, orgID int, groupID int, appID int, role string) error {
+	const op = "groups.revokeRole"
+
+	log := g.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("group_id", groupID),
+		slog.Int("app_id", appID),
+		slog.String("role", role),
+	)
+
+	log.Info("revoking role from group")
+
+	if err := g.groupSaver.RevokeGroupRole(ctx, int32(orgID), int32(groupID), int32(appID), role); err != nil {
+		return g.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (g *Groups) storageError(log *slog.Logger, op string, err error) error {
+	for _, e := range []struct{ from, to error }{
+		{storage.ErrGroupExists, ErrGroupExists},
+		{storage.ErrGroupNotFound, ErrGroupNotFound},
+		{storage.ErrGroupCycle, ErrGroupCycle},
+		{storage.ErrNotFound, ErrUserNotFound},
+		{storage.ErrRoleNotFound, ErrRoleNotFound},
+		{storage.ErrRoleNotAssigned, ErrRoleNotAssigned},
+	} {
+		if errors.Is(err, e.from) {
+			log.Warn(e.to.Error())
+			return fmt.Errorf("%s %w", op, e.to)
+		}
+	}
+
+	log.Error("storage failed", sl.Err(err))
+	return fmt.Errorf("%s %w", op, err)
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/pagination"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// History shows users their recent login attempts
+type History struct {
+	log           *slog.Logger
+	eventProvider LoginEventProvider
+}
+
+type LoginEventProvider interface {
+	LoginEvents(ctx context.Context, orgID int32, userID int64, beforeID int64, limit int) ([]models.LoginEvent, error)
+}
+
+// NewHistory returns new instance of History service
+func NewHistory(log *slog.Logger, eventProvider LoginEventProvider) *History {
+	return &History{
+		log:           log,
+		eventProvider: eventProvider,
+	}
+}
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// ListLoginEvents returns page of login attempts of user, latest first,
+// and cursor of the next page, empty when there are no more
+func (h *History) ListLoginEvents(
+	ctx context.Context,
+	orgID int,
+	userID int64,
+	cursor string,
+	pageSize int,
+) ([]models.LoginEvent, string, error) {
+	const op = "history.listLoginEvents"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	beforeID, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		log.Warn("invalid cursor", sl.Err(err))
+		return nil, "", fmt.Errorf("%s %w", op, ErrInvalidCursor)
+	}
+
+	pageSize = pagination.PageSize(pageSize)
+
+	// one extra row tells whether there is a next page
+	events, err := h.eventProvider.LoginEvents(ctx, int32(orgID), userID, beforeID, pageSize+1)
+	if err != nil {
+		log.Error("failed listing login events", sl.Err(err))
+		return nil, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	events, next := pagination.Page(events, pageSize, func(e models.LoginEvent) int64 { return e.Id })
+
+	return events, next, nil
+}
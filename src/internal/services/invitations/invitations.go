@@ -0,0 +1,264 @@
+package invitations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/hasher"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/storage"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	nonceLen = 32
+)
+
+type Invitations struct {
+	log                *slog.Logger
+	invitationSaver    InvitationSaver
+	invitationProvider InvitationProvider
+	roleProvider       RoleProvider
+	hasher             Hasher
+	mailQueue          MailQueue
+	signingKey         []byte
+	ttl                time.Duration
+}
+
+type InvitationSaver interface {
+	SaveInvitation(ctx context.Context, inv models.Invitation, nonceHash []byte) (int64, error)
+	RevokeInvitation(ctx context.Context, orgID int32, invitationID int64) error
+	AcceptInvitation(
+		ctx context.Context,
+		invitationID int64,
+		nonceHash []byte,
+		passHash []byte,
+	) (models.Invitation, int64, error)
+}
+
+type InvitationProvider interface {
+	Invitations(ctx context.Context, orgID int32) ([]models.Invitation, error)
+}
+
+type RoleProvider interface {
+	ValidateRoles(ctx context.Context, orgID int32, roles []models.InvitationRole) error
+}
+
+type Hasher interface {
+	Hash(ctx context.Context, pass []byte) ([]byte, error)
+}
+
+type MailQueue interface {
+	Enqueue(to string, subject string, body string) error
+}
+
+// NewInvitations returns new instance of Invitations service.
+// Invitation tokens are signed with signingKey and expire after ttl
+func NewInvitations(
+	log *slog.Logger,
+	invitationSaver InvitationSaver,
+	invitationProvider InvitationProvider,
+	roleProvider RoleProvider,
+	hasher Hasher,
+	mailQueue MailQueue,
+	signingKey []byte,
+	ttl time.Duration,
+) *Invitations {
+	return &Invitations{
+		log:                log,
+		invitationSaver:    invitationSaver,
+		invitationProvider: invitationProvider,
+		roleProvider:       roleProvider,
+		hasher:             hasher,
+		mailQueue:          mailQueue,
+		signingKey:         signingKey,
+		ttl:                ttl,
+	}
+}
+
+var (
+	ErrInvalidInvitation = errors.New("invalid invitation")
+	ErrPasswordRequired  = errors.New("password required for new account")
+	ErrRoleNotFound      = errors.New("invitation role not found")
+	ErrBusy              = errors.New("service is busy")
+)
+
+// CreateInvitation stores invitation to organization and mails its token to email.
+// Token is returned too, so admins can deliver it another way
+func (i *Invitations) CreateInvitation(
+	ctx context.Context,
+	orgID int,
+	email string,
+	roles []models.InvitationRole,
+) (int64, string, error) {
+	const op = "invitations.createInvitation"
+
+	log := i.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("email", email),
+	)
+
+	log.Info("creating invitation")
+
+	if err := i.roleProvider.ValidateRoles(ctx, int32(orgID), roles); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("invitation role not found", sl.Err(err))
+			return 0, "", fmt.Errorf("%s %w: %w", op, ErrRoleNotFound, err)
+		}
+
+		log.Error("failed validating roles", sl.Err(err))
+		return 0, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		log.Error("failed generating nonce", sl.Err(err))
+		return 0, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	expiresAt := time.Now().Add(i.ttl)
+	id, err := i.invitationSaver.SaveInvitation(ctx, models.Invitation{
+		OrgId:     int32(orgID),
+		Email:     email,
+		Roles:     roles,
+		ExpiresAt: expiresAt,
+	}, hashNonce(nonce))
+	if err != nil {
+		log.Error("failed saving invitation", sl.Err(err))
+		return 0, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	token, err := jwt.NewInvitationToken(id, nonce, expiresAt, i.signingKey)
+	if err != nil {
+		log.Error("failed signing invitation", sl.Err(err))
+		return 0, "", fmt.Errorf("%s %w", op, err)
+	}
+
+	err = i.mailQueue.Enqueue(
+		email,
+		"Domofon invitation",
+		"You have been invited to Domofon. Use this invitation code to accept it:\n\n"+token+
+			"\n\nThe invitation expires "+expiresAt.UTC().Format(time.RFC1123)+".",
+	)
+	if err != nil {
+		// token is still returned, the invitation isn't lost
+		log.Error("failed queueing invitation mail", sl.Err(err))
+	}
+
+	return id, token, nil
+}
+
+// AcceptInvitation consumes invitation token, creates invitee account with pass if there is none
+// in the organization yet and assigns invitation roles. Returns id of the user
+func (i *Invitations) AcceptInvitation(ctx context.Context, token string, pass string) (int64, error) {
+	const op = "invitations.acceptInvitation"
+
+	log := i.log.With(slog.String("op", op))
+
+	id, nonce, err := jwt.ParseInvitationToken(token, i.signingKey)
+	if err != nil {
+		log.Warn("invalid invitation token", sl.Err(err))
+		return 0, fmt.Errorf("%s %w", op, ErrInvalidInvitation)
+	}
+
+	log = log.With(slog.Int64("invitation_id", id))
+
+	// hashed before the account is looked up, so the response doesn't tell whether it exists
+	var passHash []byte
+	if pass != "" {
+		passHash, err = i.hasher.Hash(ctx, []byte(pass))
+		if err != nil {
+			if errors.Is(err, hasher.ErrQueueFull) || errors.Is(err, hasher.ErrQueueTimeout) {
+				log.Warn("hashing rejected", sl.Err(err))
+				return 0, fmt.Errorf("%s %w", op, ErrBusy)
+			}
+
+			log.Error("failed hashing password", sl.Err(err))
+			return 0, fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	_, userID, err := i.invitationSaver.AcceptInvitation(ctx, id, hashNonce(nonce), passHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvitationNotFound):
+			log.Warn("invitation used, revoked or expired")
+			return 0, fmt.Errorf("%s %w", op, ErrInvalidInvitation)
+		case errors.Is(err, storage.ErrNotFound):
+			log.Warn("password required for new account")
+			return 0, fmt.Errorf("%s %w", op, ErrPasswordRequired)
+		case errors.Is(err, storage.ErrRoleNotFound):
+			log.Warn("invitation role was removed", sl.Err(err))
+			return 0, fmt.Errorf("%s %w: %w", op, ErrRoleNotFound, err)
+		}
+
+		log.Error("failed accepting invitation", sl.Err(err))
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	log.Info("invitation accepted", slog.Int64("user_id", userID))
+
+	return userID, nil
+}
+
+func (i *Invitations) RevokeInvitation(ctx context.Context, orgID int, invitationID int64) error {
+	const op = "invitations.revokeInvitation"
+
+	log := i.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("invitation_id", invitationID),
+	)
+
+	log.Info("revoking invitation")
+
+	if err := i.invitationSaver.RevokeInvitation(ctx, int32(orgID), invitationID); err != nil {
+		if errors.Is(err, storage.ErrInvitationNotFound) {
+			log.Warn("invitation not found or not pending")
+			return fmt.Errorf("%s %w", op, ErrInvalidInvitation)
+		}
+
+		log.Error("failed revoking invitation", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func (i *Invitations) ListInvitations(ctx context.Context, orgID int) ([]models.Invitation, error) {
+	const op = "invitations.listInvitations"
+
+	log := i.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+	)
+
+	list, err := i.invitationProvider.Invitations(ctx, int32(orgID))
+	if err != nil {
+		log.Error("failed listing invitations", sl.Err(err))
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return list, nil
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, nonceLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashNonce(nonce string) []byte {
+	sum := sha256.Sum256([]byte(nonce))
+	return sum[:]
+}
@@ -0,0 +1,152 @@
+package orgs
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type Orgs struct {
+	log         *slog.Logger
+	orgSaver    OrgSaver
+	orgProvider OrgProvider
+}
+
+type OrgSaver interface {
+	SaveOrg(ctx context.Context, name string) (int32, error)
+	RenameOrg(ctx context.Context, orgID int32, name string) error
+	DeleteOrg(ctx context.Context, orgID int32) error
+}
+
+type OrgProvider interface {
+	Org(ctx context.Context, orgID int32) (models.Organization, error)
+	Orgs(ctx context.Context) ([]models.Organization, error)
+}
+
+// NewOrgs returns new instance of Orgs service
+func NewOrgs(
+	log *slog.Logger,
+	orgSaver OrgSaver,
+	orgProvider OrgProvider,
+) *Orgs {
+	return &Orgs{
+		log:         log,
+		orgSaver:    orgSaver,
+		orgProvider: orgProvider,
+	}
+}
+
+var (
+	ErrOrgExists   = errors.New("organization already exists")
+	ErrOrgNotFound = errors.New("organization not found")
+	ErrOrgNotEmpty = errors.New("organization has users, apps or relation tuples")
+	ErrDefaultOrg  = errors.New("default organization can't be deleted")
+)
+
+func (o *Orgs) CreateOrg(ctx context.Context, name string) (int32, error) {
+	const op = "orgs.createOrg"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("name", name),
+	)
+
+	log.Info("creating organization")
+
+	id, err := o.orgSaver.SaveOrg(ctx, name)
+	if err != nil {
+		return 0, o.storageError(log, op, err)
+	}
+
+	return id, nil
+}
+
+func (o *Orgs) Org(ctx context.Context, orgID int) (models.Organization, error) {
+	const op = "orgs.org"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+	)
+
+	org, err := o.orgProvider.Org(ctx, int32(orgID))
+	if err != nil {
+		return models.Organization{}, o.storageError(log, op, err)
+	}
+
+	return org, nil
+}
+
+func (o *Orgs) ListOrgs(ctx context.Context) ([]models.Organization, error) {
+	const op = "orgs.listOrgs"
+
+	log := o.log.With(slog.String("op", op))
+
+	list, err := o.orgProvider.Orgs(ctx)
+	if err != nil {
+		return nil, o.storageError(log, op, err)
+	}
+
+	return list, nil
+}
+
+func (o *Orgs) RenameOrg(ctx context.Context, orgID int, name string) error {
+	const op = "orgs.renameOrg"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.String("name", name),
+	)
+
+	log.Info("renaming organization")
+
+	if err := o.orgSaver.RenameOrg(ctx, int32(orgID), name); err != nil {
+		return o.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (o *Orgs) DeleteOrg(ctx context.Context, orgID int) error {
+	const op = "orgs.deleteOrg"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+	)
+
+	log.Info("deleting organization")
+
+	if orgID == models.DefaultOrgID {
+		log.Warn("default organization can't be deleted")
+		return fmt.Errorf("%s %w", op, ErrDefaultOrg)
+	}
+
+	if err := o.orgSaver.DeleteOrg(ctx, int32(orgID)); err != nil {
+		return o.storageError(log, op, err)
+	}
+
+	return nil
+}
+
+func (o *Orgs) storageError(log *slog.Logger, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrOrgExists):
+		log.Warn("organization already exists")
+		return fmt.Errorf("%s %w", op, ErrOrgExists)
+	case errors.Is(err, storage.ErrOrgNotFound):
+		log.Warn("organization not found")
+		return fmt.Errorf("%s %w", op, ErrOrgNotFound)
+	case errors.Is(err, storage.ErrOrgNotEmpty):
+		log.Warn("organization not empty")
+		return fmt.Errorf("%s %w", op, ErrOrgNotEmpty)
+	}
+
+	log.Error("storage failed", sl.Err(err))
+	return fmt.Errorf("%s %w", op, err)
+}
@@ -0,0 +1,198 @@
+package policy
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/policy"
+	"domofon/internal/lib/requestmeta"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Policy manages CEL policies of apps deciding login and extra token claims
+type Policy struct {
+	log          *slog.Logger
+	userProvider UserProvider
+	appProvider  AppProvider
+	appSaver     AppSaver
+	engine       Engine
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, orgID int32, userID int64) (models.User, error)
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int32) (models.App, error)
+}
+
+type AppSaver interface {
+	SaveAppPolicies(ctx context.Context, orgID int32, appID int32, loginPolicy string, claimsPolicy string) error
+}
+
+type Engine interface {
+	ValidateLogin(expr string) error
+	ValidateClaims(expr string) error
+	Allow(ctx context.Context, expr string, in policy.Input) (bool, error)
+	Claims(ctx context.Context, expr string, in policy.Input) (map[string]any, error)
+}
+
+// NewPolicy returns new instance of Policy service
+func NewPolicy(
+	log *slog.Logger,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	appSaver AppSaver,
+	engine Engine,
+) *Policy {
+	return &Policy{
+		log:          log,
+		userProvider: userProvider,
+		appProvider:  appProvider,
+		appSaver:     appSaver,
+		engine:       engine,
+	}
+}
+
+var (
+	ErrInvalidPolicy = errors.New("invalid policy")
+	ErrInvalidApp    = errors.New("invalid application")
+	ErrUserNotFound  = errors.New("user not found")
+)
+
+// DryRunResult is what Login would do with the policies
+type DryRunResult struct {
+	Allowed bool
+	Claims  map[string]any
+}
+
+// SetAppPolicies validates and saves policies of app in organization,
+// empty expression disables the policy
+func (p *Policy) SetAppPolicies(
+	ctx context.Context,
+	orgID int,
+	appID int,
+	loginPolicy string,
+	claimsPolicy string,
+) error {
+	const op = "policy.setAppPolicies"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("app_id", appID),
+	)
+
+	if err := p.validate(loginPolicy, claimsPolicy); err != nil {
+		log.Warn("invalid policy", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if err := p.appSaver.SaveAppPolicies(ctx, int32(orgID), int32(appID), loginPolicy, claimsPolicy); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found")
+			return fmt.Errorf("%s %w", op, ErrInvalidApp)
+		}
+
+		log.Error("failed saving policies", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// DryRun evaluates policies against user without issuing a token.
+// Empty expressions are taken from the app
+func (p *Policy) DryRun(
+	ctx context.Context,
+	orgID int,
+	appID int,
+	userID int64,
+	loginPolicy string,
+	claimsPolicy string,
+) (DryRunResult, error) {
+	const op = "policy.dryRun"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int("app_id", appID),
+		slog.Int64("user_id", userID),
+	)
+
+	app, err := p.appProvider.App(ctx, int32(appID))
+	if err == nil && app.OrgId != int32(orgID) {
+		err = storage.ErrAppNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found")
+			return DryRunResult{}, fmt.Errorf("%s %w", op, ErrInvalidApp)
+		}
+
+		log.Error("failed getting app", sl.Err(err))
+		return DryRunResult{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	user, err := p.userProvider.UserByID(ctx, int32(orgID), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Warn("user not found")
+			return DryRunResult{}, fmt.Errorf("%s %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed getting user", sl.Err(err))
+		return DryRunResult{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	if loginPolicy == "" {
+		loginPolicy = app.LoginPolicy
+	}
+	if claimsPolicy == "" {
+		claimsPolicy = app.ClaimsPolicy
+	}
+	if err := p.validate(loginPolicy, claimsPolicy); err != nil {
+		log.Warn("invalid policy", sl.Err(err))
+		return DryRunResult{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	in := policy.Input{
+		User:    user,
+		App:     app,
+		Request: requestmeta.FromContext(ctx),
+		Time:    time.Now(),
+	}
+
+	allowed, err := p.engine.Allow(ctx, loginPolicy, in)
+	if err != nil {
+		log.Warn("failed evaluating login policy", sl.Err(err))
+		return DryRunResult{}, fmt.Errorf("%s %w: %w", op, ErrInvalidPolicy, err)
+	}
+
+	claims, err := p.engine.Claims(ctx, claimsPolicy, in)
+	if err != nil {
+		log.Warn("failed evaluating claims policy", sl.Err(err))
+		return DryRunResult{}, fmt.Errorf("%s %w: %w", op, ErrInvalidPolicy, err)
+	}
+
+	return DryRunResult{Allowed: allowed, Claims: claims}, nil
+}
+
+func (p *Policy) validate(loginPolicy string, claimsPolicy string) error {
+	if loginPolicy != "" {
+		if err := p.engine.ValidateLogin(loginPolicy); err != nil {
+			return fmt.Errorf("%w: login: %w", ErrInvalidPolicy, err)
+		}
+	}
+	if claimsPolicy != "" {
+		if err := p.engine.ValidateClaims(claimsPolicy); err != nil {
+			return fmt.Errorf("%w: claims: %w", ErrInvalidPolicy, err)
+		}
+	}
+
+	return nil
+}
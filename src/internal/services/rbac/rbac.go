@@ -0,0 +1,150 @@
+package rbac
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type RBAC struct {
+	log          *slog.Logger
+	roleManager  RoleManager
+	roleProvider RoleProvider
+}
+
+type RoleManager interface {
+	AssignRole(ctx context.Context, orgID int32, userID int64, appID int32, role string) error
+	RevokeRole(ctx context.Context, orgID int32, userID int64, appID int32, role string) error
+}
+
+type RoleProvider interface {
+	UserRoles(ctx context.Context, orgID int32, userID int64, appID int32) ([]models.Role, error)
+	HasPermission(ctx context.Context, orgID int32, userID int64, appID int32, permission string) (bool, error)
+}
+
+// NewRBAC returns new instance of RBAC service
+func NewRBAC(
+	log *slog.Logger,
+	roleManager RoleManager,
+	roleProvider RoleProvider,
+) *RBAC {
+	return &RBAC{
+		log:          log,
+		roleManager:  roleManager,
+		roleProvider: roleProvider,
+	}
+}
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrRoleNotAssigned = errors.New("role not assigned")
+)
+
+// AssignRole grants role of app to user, both of organization
+func (r *RBAC) AssignRole(ctx context.Context, orgID int, userID int64, appID int, role string) error {
+	const op = "rbac.assignRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", appID),
+		slog.String("role", role),
+	)
+
+	log.Info("assigning role")
+
+	if err := r.roleManager.AssignRole(ctx, int32(orgID), userID, int32(appID), role); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.Warn("user not found")
+			return fmt.Errorf("%s %w", op, ErrUserNotFound)
+		case errors.Is(err, storage.ErrRoleNotFound):
+			log.Warn("role not found")
+			return fmt.Errorf("%s %w", op, ErrRoleNotFound)
+		}
+
+		log.Error("failed assigning role", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *RBAC) RevokeRole(ctx context.Context, orgID int, userID int64, appID int, role string) error {
+	const op = "rbac.revokeRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", appID),
+		slog.String("role", role),
+	)
+
+	log.Info("revoking role")
+
+	if err := r.roleManager.RevokeRole(ctx, int32(orgID), userID, int32(appID), role); err != nil {
+		if errors.Is(err, storage.ErrRoleNotAssigned) {
+			log.Warn("role not assigned")
+			return fmt.Errorf("%s %w", op, ErrRoleNotAssigned)
+		}
+
+		log.Error("failed revoking role", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// ListRoles returns roles of user in app of organization
+func (r *RBAC) ListRoles(ctx context.Context, orgID int, userID int64, appID int) ([]models.Role, error) {
+	const op = "rbac.listRoles"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", appID),
+	)
+
+	roles, err := r.roleProvider.UserRoles(ctx, int32(orgID), userID, int32(appID))
+	if err != nil {
+		log.Error("failed getting roles", sl.Err(err))
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// CheckPermission reports whether any role of user in app of organization grants permission
+func (r *RBAC) CheckPermission(
+	ctx context.Context,
+	orgID int,
+	userID int64,
+	appID int,
+	permission string,
+) (bool, error) {
+	const op = "rbac.checkPermission"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", appID),
+		slog.String("permission", permission),
+	)
+
+	allowed, err := r.roleProvider.HasPermission(ctx, int32(orgID), userID, int32(appID), permission)
+	if err != nil {
+		log.Error("failed checking permission", sl.Err(err))
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Sessions shows users where they're signed in and signs them out
+type Sessions struct {
+	log             *slog.Logger
+	sessionProvider SessionProvider
+	sessionRevoker  SessionRevoker
+}
+
+type SessionProvider interface {
+	Sessions(ctx context.Context, orgID int32, userID int64) ([]models.Session, error)
+}
+
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, orgID int32, userID int64, sessionID string) error
+}
+
+// NewSessions returns new instance of Sessions service
+func NewSessions(
+	log *slog.Logger,
+	sessionProvider SessionProvider,
+	sessionRevoker SessionRevoker,
+) *Sessions {
+	return &Sessions{
+		log:             log,
+		sessionProvider: sessionProvider,
+		sessionRevoker:  sessionRevoker,
+	}
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// ListSessions returns active sessions of user, recently seen first
+func (s *Sessions) ListSessions(ctx context.Context, orgID int, userID int64) ([]models.Session, error) {
+	const op = "sessions.listSessions"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	list, err := s.sessionProvider.Sessions(ctx, int32(orgID), userID)
+	if err != nil {
+		log.Error("failed listing sessions", sl.Err(err))
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return list, nil
+}
+
+// RevokeSession signs user out of session, tokens issued on it stop being accepted
+func (s *Sessions) RevokeSession(ctx context.Context, orgID int, userID int64, sessionID string) error {
+	const op = "sessions.revokeSession"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("org_id", orgID),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("revoking session")
+
+	if err := s.sessionRevoker.RevokeSession(ctx, int32(orgID), userID, sessionID); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			log.Warn("session not found")
+			return fmt.Errorf("%s %w", op, ErrSessionNotFound)
+		}
+
+		log.Error("failed revoking session", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
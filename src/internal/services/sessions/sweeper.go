@@ -0,0 +1,155 @@
+package sessions
+
+import (
+	"context"
+	"domofon/internal/lib/logger/sl"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	sweepTimeout = time.Minute
+	defaultBatch = 1000
+)
+
+type Purger interface {
+	DeleteEndedSessions(ctx context.Context, limit int) (int, error)
+	DeleteEndedAccessTokens(ctx context.Context, limit int) (int, error)
+	DeleteLoginEvents(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// Sweeper periodically deletes revoked and ended sessions and opaque access tokens,
+// and login events past retention. Deleting in batches keeps each statement short on large tables
+type Sweeper struct {
+	log       *slog.Logger
+	purger    Purger
+	interval  time.Duration
+	batch     int
+	retention time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewSweeper returns Sweeper deleting up to batch rows per statement every interval,
+// interval 0 disables sweeping. Login events are kept for retention, 0 keeps them forever
+func NewSweeper(
+	log *slog.Logger,
+	purger Purger,
+	interval time.Duration,
+	batch int,
+	retention time.Duration,
+) *Sweeper {
+	if batch <= 0 {
+		batch = defaultBatch
+	}
+
+	return &Sweeper{
+		log:       log,
+		purger:    purger,
+		interval:  interval,
+		batch:     batch,
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start runs sweeping in background until Stop
+func (s *Sweeper) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Stop stops sweeping and waits for the current sweep to finish
+func (s *Sweeper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+func (s *Sweeper) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+func (s *Sweeper) sweep() {
+	const op = "sessions.sweep"
+
+	log := s.log.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), sweepTimeout)
+	defer cancel()
+
+	type task struct {
+		what   string
+		delete func(ctx context.Context, limit int) (int, error)
+	}
+	tasks := []task{
+		{what: "ended sessions", delete: s.purger.DeleteEndedSessions},
+		{what: "ended access tokens", delete: s.purger.DeleteEndedAccessTokens},
+	}
+	if s.retention > 0 {
+		before := time.Now().Add(-s.retention)
+		tasks = append(tasks, task{what: "old login events", delete: func(ctx context.Context, limit int) (int, error) {
+			return s.purger.DeleteLoginEvents(ctx, before, limit)
+		}})
+	}
+
+	for _, p := range tasks {
+		if !s.purge(ctx, log.With(slog.String("what", p.what)), p.delete) {
+			return
+		}
+	}
+}
+
+// purge deletes in batches until a batch comes back short, false means Stop was called
+func (s *Sweeper) purge(
+	ctx context.Context,
+	log *slog.Logger,
+	deleteBatch func(ctx context.Context, limit int) (int, error),
+) bool {
+	var total int
+	for {
+		deleted, err := deleteBatch(ctx, s.batch)
+		if err != nil {
+			log.Error("failed deleting", sl.Err(err))
+			break
+		}
+		total += deleted
+
+		if deleted < s.batch {
+			break
+		}
+
+		select {
+		case <-s.stop:
+			log.Info("sweep interrupted by stop", slog.Int("deleted", total))
+			return false
+		default:
+		}
+	}
+
+	if total > 0 {
+		log.Info("deleted", slog.Int("deleted", total))
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakePurger struct {
+	sessions []int
+	tokens   []int
+	events   []int
+	before   time.Time
+	err      error
+	calls    []string
+}
+
+func (f *fakePurger) DeleteEndedSessions(_ context.Context, _ int) (int, error) {
+	f.calls = append(f.calls, "sessions")
+	return next(&f.sessions), f.err
+}
+
+func (f *fakePurger) DeleteEndedAccessTokens(_ context.Context, _ int) (int, error) {
+	f.calls = append(f.calls, "tokens")
+	return next(&f.tokens), nil
+}
+
+func (f *fakePurger) DeleteLoginEvents(_ context.Context, before time.Time, _ int) (int, error) {
+	f.calls = append(f.calls, "events")
+	f.before = before
+	return next(&f.events), nil
+}
+
+func next(batches *[]int) int {
+	if len(*batches) == 0 {
+		return 0
+	}
+
+	n := (*batches)[0]
+	*batches = (*batches)[1:]
+
+	return n
+}
+
+func TestSweeper_sweep(t *testing.T) {
+	tests := []struct {
+		name      string
+		purger    *fakePurger
+		retention time.Duration
+		wantCalls []string
+	}{
+		{
+			name:      "nothing to delete",
+			purger:    &fakePurger{},
+			wantCalls: []string{"sessions", "tokens"},
+		},
+		{
+			name:      "full batches repeat until short one",
+			purger:    &fakePurger{sessions: []int{2, 2, 1}, tokens: []int{2, 0}},
+			wantCalls: []string{"sessions", "sessions", "sessions", "tokens", "tokens"},
+		},
+		{
+			name:      "failed sessions don't stop tokens",
+			purger:    &fakePurger{sessions: []int{2}, err: errors.New("db down")},
+			wantCalls: []string{"sessions", "tokens"},
+		},
+		{
+			name:      "login events past retention",
+			purger:    &fakePurger{events: []int{2, 1}},
+			retention: time.Hour,
+			wantCalls: []string{"sessions", "tokens", "events", "events"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSweeper(slog.New(slog.NewTextHandler(io.Discard, nil)), tt.purger, 0, 2, tt.retention)
+
+			s.sweep()
+
+			assert.Equal(t, tt.wantCalls, tt.purger.calls)
+			if tt.retention > 0 {
+				assert.WithinDuration(t, time.Now().Add(-tt.retention), tt.purger.before, time.Second)
+			}
+		})
+	}
+}
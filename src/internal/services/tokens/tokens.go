@@ -0,0 +1,241 @@
+package tokens
+
+import (
+	"context"
+	"crypto/subtle"
+	"domofon/internal/domain/models"
+	"domofon/internal/lib/dpop"
+	"domofon/internal/lib/jwt"
+	"domofon/internal/lib/logger/sl"
+	"domofon/internal/lib/requestmeta"
+	"domofon/internal/lib/tokens"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Tokens resolves and revokes issued access tokens for resource servers
+type Tokens struct {
+	log         *slog.Logger
+	appProvider AppProvider
+	resolver    Resolver
+	verifier    ProofVerifier
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int32) (models.App, error)
+	AppSecrets(ctx context.Context, appID int32) ([]string, error)
+}
+
+type Resolver interface {
+	Parse(ctx context.Context, token string) (jwt.Claims, error)
+	Introspect(ctx context.Context, token string) (tokens.Introspection, error)
+	Revoke(ctx context.Context, token string) error
+}
+
+type ProofVerifier interface {
+	Verify(proof string, method string, uri string, accessToken string) (string, error)
+}
+
+// Client is app calling token endpoints as resource server. It authenticates with
+// its secret, or with TLS client certificate of the app when Secret is empty
+type Client struct {
+	AppID  int32
+	Secret string
+}
+
+// NewTokens returns new instance of Tokens service
+func NewTokens(log *slog.Logger, appProvider AppProvider, resolver Resolver, verifier ProofVerifier) *Tokens {
+	return &Tokens{
+		log:         log,
+		appProvider: appProvider,
+		resolver:    resolver,
+		verifier:    verifier,
+	}
+}
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrNotRevocable      = errors.New("only opaque tokens can be revoked")
+	ErrFormatUnavailable = errors.New("token format isn't configured")
+	ErrProofRequired     = errors.New("dpop proof required for bound token")
+	ErrInvalidProof      = errors.New("invalid dpop proof")
+	ErrUseNonce          = errors.New("use_dpop_nonce")
+	ErrBusy              = errors.New("too many dpop proofs")
+	ErrEncrypted         = errors.New("encrypted token is readable only by its app")
+	ErrInvalidClient     = errors.New("invalid client")
+)
+
+// Introspect describes token like RFC 7662 to authenticated client, invalid tokens are inactive
+// rather than an error. Clients the token isn't meant for learn only whether it's active, exp and aud
+func (t *Tokens) Introspect(ctx context.Context, client Client, token string) (tokens.Introspection, error) {
+	const op = "tokens.introspect"
+
+	log := t.log.With(
+		slog.String("op", op),
+		slog.Int("client_id", int(client.AppID)),
+	)
+
+	if err := t.authenticate(ctx, log, client); err != nil {
+		return tokens.Introspection{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	res, err := t.resolver.Introspect(ctx, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrFormatUnavailable):
+			log.Warn("token format isn't configured")
+			return tokens.Introspection{}, fmt.Errorf("%s %w", op, ErrFormatUnavailable)
+		case errors.Is(err, tokens.ErrEncrypted):
+			log.Warn("token is encrypted to its app")
+			return tokens.Introspection{}, fmt.Errorf("%s %w", op, ErrEncrypted)
+		}
+
+		log.Error("failed introspecting token", sl.Err(err))
+		return tokens.Introspection{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	if !res.IsAudience(client.AppID) {
+		return res.Limited(), nil
+	}
+
+	return res, nil
+}
+
+// Revoke makes opaque token inactive at once, self-contained tokens stay valid until exp.
+// Client can revoke only tokens meant for it, others are reported not found
+func (t *Tokens) Revoke(ctx context.Context, client Client, token string) error {
+	const op = "tokens.revoke"
+
+	log := t.log.With(
+		slog.String("op", op),
+		slog.Int("client_id", int(client.AppID)),
+	)
+
+	if err := t.authenticate(ctx, log, client); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	log.Info("revoking token")
+
+	res, err := t.resolver.Introspect(ctx, token)
+	if err != nil {
+		if errors.Is(err, tokens.ErrFormatUnavailable) || errors.Is(err, tokens.ErrEncrypted) {
+			log.Warn("token isn't revocable", sl.Err(err))
+			return fmt.Errorf("%s %w", op, ErrNotRevocable)
+		}
+
+		log.Error("failed introspecting token", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if !res.Active || !res.IsAudience(client.AppID) {
+		log.Warn("token not found or not meant for client")
+		return fmt.Errorf("%s %w", op, ErrInvalidToken)
+	}
+
+	if err := t.resolver.Revoke(ctx, token); err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrNotRevocable):
+			log.Warn("token isn't revocable")
+			return fmt.Errorf("%s %w", op, ErrNotRevocable)
+		case errors.Is(err, tokens.ErrInvalidToken):
+			log.Warn("token not found or revoked")
+			return fmt.Errorf("%s %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed revoking token", sl.Err(err))
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// Validate checks access token resource server got with request of method to uri,
+// tokens bound to DPoP key need proof of the request by that key.
+// Invalid tokens return ErrInvalidToken, every validation marks session of the token seen
+func (t *Tokens) Validate(ctx context.Context, token string, proof string, method string, uri string) (jwt.Claims, error) {
+	const op = "tokens.validate"
+
+	log := t.log.With(slog.String("op", op))
+
+	claims, err := t.resolver.Parse(ctx, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrFormatUnavailable):
+			log.Warn("token format isn't configured")
+			return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrFormatUnavailable)
+		case tokens.IsStorageError(err):
+			log.Error("failed parsing token", sl.Err(err))
+			return jwt.Claims{}, fmt.Errorf("%s %w", op, err)
+		}
+
+		return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrInvalidToken)
+	}
+
+	if claims.JKT == "" {
+		return claims, nil
+	}
+	if proof == "" {
+		log.Warn("no proof for bound token")
+		return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrProofRequired)
+	}
+
+	jkt, err := t.verifier.Verify(proof, method, uri, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, dpop.ErrUseNonce):
+			return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrUseNonce)
+		case errors.Is(err, dpop.ErrBusy):
+			log.Warn("dpop replay cache is full")
+			return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrBusy)
+		}
+
+		log.Warn("invalid dpop proof", sl.Err(err))
+		return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrInvalidProof)
+	}
+	if jkt != claims.JKT {
+		log.Warn("proof key isn't the token key")
+		return jwt.Claims{}, fmt.Errorf("%s %w", op, ErrInvalidProof)
+	}
+
+	return claims, nil
+}
+
+// authenticate checks secret of client or, without one, TLS client certificate of its app
+func (t *Tokens) authenticate(ctx context.Context, log *slog.Logger, client Client) error {
+	app, err := t.appProvider.App(ctx, client.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("client app not found")
+			return ErrInvalidClient
+		}
+
+		log.Error("failed getting client app", sl.Err(err))
+		return err
+	}
+
+	if client.Secret == "" {
+		if app.TLSClientAuth == nil || !requestmeta.FromContext(ctx).ClientCertMatches(*app.TLSClientAuth) {
+			log.Warn("no client secret or certificate of app")
+			return ErrInvalidClient
+		}
+
+		return nil
+	}
+
+	// secrets in rotation grace period still authenticate
+	secrets, err := t.appProvider.AppSecrets(ctx, client.AppID)
+	if err != nil {
+		log.Error("failed getting client secrets", sl.Err(err))
+		return err
+	}
+	for _, secret := range secrets {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(client.Secret)) == 1 {
+			return nil
+		}
+	}
+
+	log.Warn("invalid client secret")
+	return ErrInvalidClient
+}
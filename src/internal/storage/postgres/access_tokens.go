@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+func (s *Storage) SaveAccessToken(ctx context.Context, token models.AccessToken, tokenHash []byte) (int64, error) {
+	const op = "storage.postgres.saveAccessToken"
+
+	claims, err := json.Marshal(token.Claims)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	stmt, err := s.db.Prepare(`
+		insert into access_tokens (token_hash, org_id, app_id, user_id, claims, expires_at)
+		values ($1, $2, $3, $4, $5, $6)
+		returning id`)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var id int64
+	err = stmt.QueryRowContext(
+		ctx,
+		tokenHash,
+		token.OrgId,
+		token.AppId,
+		token.UserId,
+		claims,
+		token.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) AccessToken(ctx context.Context, tokenHash []byte) (models.AccessToken, error) {
+	const op = "storage.postgres.accessToken"
+
+	stmt, err := s.db.Prepare(`
+		select id, org_id, app_id, user_id, claims, created_at, expires_at, revoked_at
+		from access_tokens
+		where token_hash = $1`)
+	if err != nil {
+		return models.AccessToken{}, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var (
+		token  models.AccessToken
+		claims []byte
+	)
+	err = stmt.QueryRowContext(ctx, tokenHash).Scan(
+		&token.Id,
+		&token.OrgId,
+		&token.AppId,
+		&token.UserId,
+		&claims,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AccessToken{}, fmt.Errorf("%s %w", op, storage.ErrTokenNotFound)
+		}
+
+		return models.AccessToken{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	if err = json.Unmarshal(claims, &token.Claims); err != nil {
+		return models.AccessToken{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	return token, nil
+}
+
+func (s *Storage) RevokeAccessToken(ctx context.Context, tokenHash []byte) error {
+	const op = "storage.postgres.revokeAccessToken"
+
+	stmt, err := s.db.Prepare(`
+		update access_tokens set revoked_at = now()
+		where token_hash = $1
+		  and revoked_at is null`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, tokenHash)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrTokenNotFound)
+}
+
+// DeleteEndedAccessTokens deletes up to limit expired or revoked opaque tokens, returns number of deleted ones
+func (s *Storage) DeleteEndedAccessTokens(ctx context.Context, limit int) (int, error) {
+	const op = "storage.postgres.deleteEndedAccessTokens"
+
+	stmt, err := s.db.Prepare(`
+		delete
+		from access_tokens
+		where id in (select id
+		             from access_tokens
+		             where expires_at <= now()
+		                or revoked_at is not null
+		             limit $1 for update skip locked)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return int(deleted), nil
+}
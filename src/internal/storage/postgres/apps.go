@@ -0,0 +1,574 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+func (s *Storage) SaveApp(ctx context.Context, app models.App) (int32, error) {
+	const op = "storage.postgres.saveApp"
+
+	redirectURIs, err := json.Marshal(nonNil(app.RedirectURIs))
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	grantTypes, err := json.Marshal(nonNil(app.GrantTypes))
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	tokenSettings, err := encodeTokenSettings(app.Tokens)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	sessionSettings, err := encodeSessionSettings(app.Sessions)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	riskSettings, err := encodeRiskSettings(app.Risk)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	var tlsClientAuth []byte
+	if app.TLSClientAuth != nil {
+		if tlsClientAuth, err = encodeTLSClientAuth(*app.TLSClientAuth); err != nil {
+			return 0, fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// secret is sealed once the app id it's bound to is known
+	stmt, err := tx.PrepareContext(ctx, `
+		insert into apps (org_id, name, secret, redirect_uris, grant_types, token_settings, session_settings,
+		                  tls_client_auth, risk_settings)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		returning id`)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var id int32
+	err = stmt.QueryRowContext(
+		ctx,
+		app.OrgId,
+		app.Name,
+		"",
+		redirectURIs,
+		grantTypes,
+		tokenSettings,
+		sessionSettings,
+		tlsClientAuth,
+		riskSettings,
+	).Scan(&id)
+	if err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+			return 0, fmt.Errorf("%s %w", op, storage.ErrAppExists)
+		}
+		if errors.As(err, &postgresErr) && postgresErr.Code == ForeignKeyViolationErr {
+			return 0, fmt.Errorf("%s %w", op, storage.ErrOrgNotFound)
+		}
+
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	secret, err := s.sealSecret(id, app.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	if _, err = tx.ExecContext(ctx, "update apps set secret = $2 where id = $1", id, secret); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Apps(ctx context.Context, orgID int32) ([]models.App, error) {
+	const op = "storage.postgres.apps"
+
+	stmt, err := s.db.Prepare("select " + appColumns + " from apps where org_id = $1 order by id")
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		app, err := s.scanApp(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		apps = append(apps, app)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return apps, nil
+}
+
+func (s *Storage) UpdateApp(ctx context.Context, orgID int32, appID int32, upd models.AppUpdate) error {
+	const op = "storage.postgres.updateApp"
+
+	var redirectURIs, grantTypes, tokenSettings, sessionSettings, tlsClientAuth, riskSettings []byte
+	var err error
+	if upd.RedirectURIs != nil {
+		if redirectURIs, err = json.Marshal(upd.RedirectURIs); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+	if upd.GrantTypes != nil {
+		if grantTypes, err = json.Marshal(upd.GrantTypes); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	if upd.Tokens != nil {
+		if tokenSettings, err = encodeTokenSettings(*upd.Tokens); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+	if upd.Sessions != nil {
+		if sessionSettings, err = encodeSessionSettings(*upd.Sessions); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+	if upd.TLSClientAuth != nil {
+		if tlsClientAuth, err = encodeTLSClientAuth(*upd.TLSClientAuth); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+	if upd.Risk != nil {
+		if riskSettings, err = encodeRiskSettings(*upd.Risk); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	stmt, err := s.db.Prepare(`
+		update apps
+		set name             = coalesce($3, name),
+		    redirect_uris    = coalesce($4::jsonb, redirect_uris),
+		    grant_types      = coalesce($5::jsonb, grant_types),
+		    token_settings   = coalesce($6::jsonb, token_settings),
+		    session_settings = coalesce($7::jsonb, session_settings),
+		    tls_client_auth  = case
+		                           when $8::jsonb is null then tls_client_auth
+		                           when $8::jsonb = 'null'::jsonb then null
+		                           else $8::jsonb
+		        end,
+		    risk_settings    = coalesce($9::jsonb, risk_settings)
+		where org_id = $1
+		  and id = $2`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, appID, upd.Name, redirectURIs, grantTypes, tokenSettings,
+		sessionSettings, tlsClientAuth, riskSettings)
+	if err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+			return fmt.Errorf("%s %w", op, storage.ErrAppExists)
+		}
+
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrAppNotFound)
+}
+
+func (s *Storage) DeleteApp(ctx context.Context, orgID int32, appID int32) error {
+	const op = "storage.postgres.deleteApp"
+
+	stmt, err := s.db.Prepare("delete from apps where org_id = $1 and id = $2")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, appID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrAppNotFound)
+}
+
+// tokenSettingsRow is json of apps.token_settings, ttls are in seconds
+type tokenSettingsRow struct {
+	Format        string          `json:"format,omitempty"`
+	AccessTTL     int64           `json:"access_ttl,omitempty"`
+	EncryptionKey json.RawMessage `json:"encryption_key,omitempty"`
+	EncryptionAlg string          `json:"encryption_alg,omitempty"`
+	RequireDPoP   bool            `json:"require_dpop,omitempty"`
+	Claims        struct {
+		Version  int            `json:"version,omitempty"`
+		Email    *bool          `json:"email,omitempty"`
+		Roles    *bool          `json:"roles,omitempty"`
+		Admin    *bool          `json:"admin,omitempty"`
+		Static   map[string]any `json:"static,omitempty"`
+		Audience []string       `json:"audience,omitempty"`
+	} `json:"claims"`
+}
+
+func encodeTokenSettings(settings models.TokenSettings) ([]byte, error) {
+	var row tokenSettingsRow
+	row.Format = settings.Format
+	if settings.EncryptionKey != "" {
+		row.EncryptionKey = json.RawMessage(settings.EncryptionKey)
+	}
+	row.EncryptionAlg = settings.EncryptionAlg
+	row.RequireDPoP = settings.RequireDPoP
+	row.AccessTTL = int64(settings.AccessTTL / time.Second)
+	row.Claims.Version = settings.Claims.Version
+	row.Claims.Email = settings.Claims.Email
+	row.Claims.Roles = settings.Claims.Roles
+	row.Claims.Admin = settings.Claims.Admin
+	row.Claims.Static = settings.Claims.Static
+	row.Claims.Audience = settings.Claims.Audience
+
+	return json.Marshal(row)
+}
+
+func decodeTokenSettings(data []byte) (models.TokenSettings, error) {
+	var row tokenSettingsRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return models.TokenSettings{}, err
+	}
+
+	return models.TokenSettings{
+		Format:        row.Format,
+		EncryptionKey: string(row.EncryptionKey),
+		EncryptionAlg: row.EncryptionAlg,
+		RequireDPoP:   row.RequireDPoP,
+		AccessTTL:     time.Duration(row.AccessTTL) * time.Second,
+		Claims: models.ClaimSettings{
+			Version:  row.Claims.Version,
+			Email:    row.Claims.Email,
+			Roles:    row.Claims.Roles,
+			Admin:    row.Claims.Admin,
+			Static:   row.Claims.Static,
+			Audience: row.Claims.Audience,
+		},
+	}, nil
+}
+
+// sessionSettingsRow is json of apps.session_settings, durations are in seconds.
+// Absent fields aren't set for app, zero ones are
+type sessionSettingsRow struct {
+	IdleTimeout *int64 `json:"idle_timeout,omitempty"`
+	MaxLifetime *int64 `json:"max_lifetime,omitempty"`
+	MaxSessions *int   `json:"max_sessions,omitempty"`
+	LimitPolicy string `json:"limit_policy,omitempty"`
+}
+
+func encodeSessionSettings(settings models.SessionSettings) ([]byte, error) {
+	return json.Marshal(sessionSettingsRow{
+		IdleTimeout: seconds(settings.IdleTimeout),
+		MaxLifetime: seconds(settings.MaxLifetime),
+		MaxSessions: settings.MaxSessions,
+		LimitPolicy: settings.LimitPolicy,
+	})
+}
+
+func decodeSessionSettings(data []byte) (models.SessionSettings, error) {
+	var row sessionSettingsRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return models.SessionSettings{}, err
+	}
+
+	return models.SessionSettings{
+		IdleTimeout: fromSeconds(row.IdleTimeout),
+		MaxLifetime: fromSeconds(row.MaxLifetime),
+		MaxSessions: row.MaxSessions,
+		LimitPolicy: row.LimitPolicy,
+	}, nil
+}
+
+func seconds(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+
+	s := int64(*d / time.Second)
+	return &s
+}
+
+func fromSeconds(s *int64) *time.Duration {
+	if s == nil {
+		return nil
+	}
+
+	d := time.Duration(*s) * time.Second
+	return &d
+}
+
+// riskSettingsRow is json of apps.risk_settings
+type riskSettingsRow struct {
+	StepUpScore *int `json:"step_up_score,omitempty"`
+	DenyScore   *int `json:"deny_score,omitempty"`
+}
+
+func encodeRiskSettings(settings models.RiskSettings) ([]byte, error) {
+	return json.Marshal(riskSettingsRow{StepUpScore: settings.StepUpScore, DenyScore: settings.DenyScore})
+}
+
+func decodeRiskSettings(data []byte) (models.RiskSettings, error) {
+	var row riskSettingsRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return models.RiskSettings{}, err
+	}
+
+	return models.RiskSettings{StepUpScore: row.StepUpScore, DenyScore: row.DenyScore}, nil
+}
+
+// tlsClientAuthRow is json of apps.tls_client_auth
+type tlsClientAuthRow struct {
+	SubjectDN   string   `json:"subject_dn,omitempty"`
+	Thumbprints []string `json:"thumbprints,omitempty"`
+}
+
+// encodeTLSClientAuth encodes zero value as json null, it turns client certificate auth off
+func encodeTLSClientAuth(auth models.TLSClientAuth) ([]byte, error) {
+	if auth.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(tlsClientAuthRow{SubjectDN: auth.SubjectDN, Thumbprints: auth.Thumbprints})
+}
+
+func decodeTLSClientAuth(data []byte) (*models.TLSClientAuth, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var row *tlsClientAuthRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, nil
+	}
+
+	return &models.TLSClientAuth{SubjectDN: row.SubjectDN, Thumbprints: row.Thumbprints}, nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
+
+// RotateAppSecret makes secret primary one of app, previous primary stays valid until graceUntil
+func (s *Storage) RotateAppSecret(
+	ctx context.Context,
+	orgID int32,
+	appID int32,
+	secret string,
+	graceUntil time.Time,
+) error {
+	const op = "storage.postgres.rotateAppSecret"
+
+	secret, err := s.sealSecret(appID, secret)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var previous string
+	err = tx.QueryRowContext(
+		ctx,
+		"select secret from apps where org_id = $1 and id = $2 for update",
+		orgID,
+		appID,
+	).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s %w", op, storage.ErrAppNotFound)
+		}
+
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "delete from app_secrets where app_id = $1 and expires_at <= now()", appID); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if graceUntil.After(time.Now()) {
+		_, err = tx.ExecContext(
+			ctx,
+			"insert into app_secrets (app_id, secret, expires_at) values ($1, $2, $3)",
+			appID,
+			previous,
+			graceUntil,
+		)
+		if err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "update apps set secret = $2 where id = $1", appID, secret); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// AppSecrets returns secrets accepted for tokens of app, primary one goes first
+func (s *Storage) AppSecrets(ctx context.Context, appID int32) ([]string, error) {
+	const op = "storage.postgres.appSecrets"
+
+	stmt, err := s.db.Prepare(`
+		select secret
+		from (select secret, 'infinity'::timestamptz as expires_at
+		      from apps
+		      where id = $1
+		      union all
+		      select secret, expires_at
+		      from app_secrets
+		      where app_id = $1
+		        and expires_at > now()) s
+		order by expires_at desc`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var secrets []string
+	for rows.Next() {
+		var secret string
+		if err = rows.Scan(&secret); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		if secret, err = s.openSecret(appID, secret); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		secrets = append(secrets, secret)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("%s %w", op, storage.ErrAppNotFound)
+	}
+
+	return secrets, nil
+}
+
+// ResealAppSecrets encrypts plaintext secrets and the ones sealed with retired key,
+// returns number of changed secrets
+func (s *Storage) ResealAppSecrets(ctx context.Context) (int, error) {
+	const op = "storage.postgres.resealAppSecrets"
+
+	if s.cipher == nil {
+		return 0, fmt.Errorf("%s %w", op, storage.ErrNoSecretCipher)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var total int
+	for table, appColumn := range map[string]string{"apps": "id", "app_secrets": "app_id"} {
+		n, err := s.resealTable(ctx, tx, table, appColumn)
+		if err != nil {
+			return 0, fmt.Errorf("%s %w", op, err)
+		}
+		total += n
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return total, nil
+}
+
+// resealTable reseals secret column of table bound to app of appColumn, the names are never user input
+func (s *Storage) resealTable(ctx context.Context, tx *sql.Tx, table string, appColumn string) (int, error) {
+	rows, err := tx.QueryContext(ctx, "select id, "+appColumn+", secret from "+table+" for update")
+	if err != nil {
+		return 0, err
+	}
+
+	resealed := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id    int64
+			appID int32
+			value string
+		)
+		if err = rows.Scan(&id, &appID, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !s.cipher.NeedsReseal(value) {
+			continue
+		}
+
+		secret, err := s.cipher.Open(appID, value)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("secret %d of %s: %w", id, table, err)
+		}
+		if resealed[id], err = s.cipher.Seal(appID, secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, value := range resealed {
+		if _, err = tx.ExecContext(ctx, "update "+table+" set secret = $2 where id = $1", id, value); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(resealed), nil
+}
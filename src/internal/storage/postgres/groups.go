@@ -0,0 +1,420 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// groupsLock serializes changes of group nesting in organization,
+	// otherwise two concurrent edges could form a cycle
+	groupsLock = 7_301_033
+)
+
+func (s *Storage) SaveGroup(ctx context.Context, orgID int32, name string) (int32, error) {
+	const op = "storage.postgres.saveGroup"
+
+	stmt, err := s.db.Prepare("insert into groups (org_id, name) values ($1, $2) returning id")
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var id int32
+	if err = stmt.QueryRowContext(ctx, orgID, name).Scan(&id); err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+			return 0, fmt.Errorf("%s %w", op, storage.ErrGroupExists)
+		}
+
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) DeleteGroup(ctx context.Context, orgID int32, groupID int32) error {
+	const op = "storage.postgres.deleteGroup"
+
+	stmt, err := s.db.Prepare("delete from groups where org_id = $1 and id = $2")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, groupID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrGroupNotFound)
+}
+
+func (s *Storage) Groups(ctx context.Context, orgID int32) ([]models.Group, error) {
+	const op = "storage.postgres.groups"
+
+	stmt, err := s.db.Prepare("select id, org_id, name, created_at from groups where org_id = $1 order by name")
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	groups, err := scanGroups(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return groups, nil
+}
+
+func (s *Storage) AddGroupUser(ctx context.Context, orgID int32, groupID int32, userID int64) error {
+	const op = "storage.postgres.addGroupUser"
+
+	if err := s.checkGroupAndUser(ctx, orgID, groupID, userID); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"insert into group_users (group_id, user_id) values ($1, $2) on conflict do nothing",
+		groupID,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveGroupUser(ctx context.Context, orgID int32, groupID int32, userID int64) error {
+	const op = "storage.postgres.removeGroupUser"
+
+	stmt, err := s.db.Prepare(`
+		delete from group_users gu
+		using groups g
+		where g.id = gu.group_id
+		  and g.org_id = $1
+		  and gu.group_id = $2
+		  and gu.user_id = $3`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrNotFound)
+}
+
+// AddSubgroup makes child a member of parent, fails with ErrGroupCycle
+// if parent is already reachable from child
+func (s *Storage) AddSubgroup(ctx context.Context, orgID int32, parentID int32, childID int32) error {
+	const op = "storage.postgres.addSubgroup"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, "select pg_advisory_xact_lock($1, $2)", groupsLock, orgID); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	var found int
+	err = tx.QueryRowContext(
+		ctx,
+		"select count(*) from groups where org_id = $1 and id in ($2, $3)",
+		orgID,
+		parentID,
+		childID,
+	).Scan(&found)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if found != 2 && !(found == 1 && parentID == childID) {
+		return fmt.Errorf("%s %w", op, storage.ErrGroupNotFound)
+	}
+
+	var cycle bool
+	err = tx.QueryRowContext(ctx, `
+		with recursive descendants(id) as (
+		    select $1::int
+		    union
+		    select gg.child_id
+		    from group_groups gg
+		             join descendants d on gg.parent_id = d.id
+		)
+		select exists(select 1 from descendants where id = $2)`,
+		childID,
+		parentID,
+	).Scan(&cycle)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if cycle {
+		return fmt.Errorf("%s %w", op, storage.ErrGroupCycle)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"insert into group_groups (parent_id, child_id) values ($1, $2) on conflict do nothing",
+		parentID,
+		childID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveSubgroup(ctx context.Context, orgID int32, parentID int32, childID int32) error {
+	const op = "storage.postgres.removeSubgroup"
+
+	stmt, err := s.db.Prepare(`
+		delete from group_groups gg
+		using groups g
+		where g.id = gg.parent_id
+		  and g.org_id = $1
+		  and gg.parent_id = $2
+		  and gg.child_id = $3`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, parentID, childID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrGroupNotFound)
+}
+
+// UserGroups returns groups user belongs to directly or through nested groups
+func (s *Storage) UserGroups(ctx context.Context, orgID int32, userID int64) ([]models.Group, error) {
+	const op = "storage.postgres.userGroups"
+
+	stmt, err := s.db.Prepare(`
+		with recursive ancestors(id) as (
+		    select group_id
+		    from group_users
+		    where user_id = $2
+		    union
+		    select gg.parent_id
+		    from group_groups gg
+		             join ancestors a on gg.child_id = a.id
+		)
+		select g.id, g.org_id, g.name, g.created_at
+		from groups g
+		         join ancestors a on a.id = g.id
+		where g.org_id = $1
+		order by g.name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	groups, err := scanGroups(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return groups, nil
+}
+
+// GroupUsers returns ids of users in group directly or through nested groups
+func (s *Storage) GroupUsers(ctx context.Context, orgID int32, groupID int32) ([]int64, error) {
+	const op = "storage.postgres.groupUsers"
+
+	stmt, err := s.db.Prepare(`
+		with recursive descendants(id) as (
+		    select id
+		    from groups
+		    where org_id = $1
+		      and id = $2
+		    union
+		    select gg.child_id
+		    from group_groups gg
+		             join descendants d on gg.parent_id = d.id
+		)
+		select distinct gu.user_id
+		from group_users gu
+		         join descendants d on d.id = gu.group_id
+		order by gu.user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// AssignGroupRole grants role of app to every member of group
+func (s *Storage) AssignGroupRole(ctx context.Context, orgID int32, groupID int32, appID int32, role string) error {
+	const op = "storage.postgres.assignGroupRole"
+
+	stmt, err := s.db.Prepare(`
+		insert into group_roles (group_id, role_id)
+		select g.id, r.id
+		from groups g
+		         join apps a on a.org_id = g.org_id
+		         join roles r on r.app_id = a.id
+		where g.org_id = $1
+		  and g.id = $2
+		  and a.id = $3
+		  and r.name = $4
+		on conflict do nothing`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, groupID, appID, role)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if affected == 0 {
+		// nothing inserted: missing group or role, or role is already granted
+		return s.checkGroupRole(ctx, op, orgID, groupID, appID, role)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeGroupRole(ctx context.Context, orgID int32, groupID int32, appID int32, role string) error {
+	const op = "storage.postgres.revokeGroupRole"
+
+	stmt, err := s.db.Prepare(`
+		delete from group_roles gr
+		using groups g, roles r
+		where g.id = gr.group_id
+		  and r.id = gr.role_id
+		  and g.org_id = $1
+		  and g.id = $2
+		  and r.app_id = $3
+		  and r.name = $4`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, groupID, appID, role)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrRoleNotAssigned)
+}
+
+func (s *Storage) checkGroupAndUser(ctx context.Context, orgID int32, groupID int32, userID int64) error {
+	var groupExists, userExists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`select exists(select 1 from groups where org_id = $1 and id = $2),
+		        exists(select 1 from users where org_id = $1 and id = $3)`,
+		orgID,
+		groupID,
+		userID,
+	).Scan(&groupExists, &userExists)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !groupExists:
+		return storage.ErrGroupNotFound
+	case !userExists:
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) checkGroupRole(ctx context.Context, op string, orgID int32, groupID int32, appID int32, role string) error {
+	var groupExists, roleExists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`select exists(select 1 from groups where org_id = $1 and id = $2),
+		        exists(select 1 from roles r join apps a on a.id = r.app_id
+		               where a.org_id = $1 and a.id = $3 and r.name = $4)`,
+		orgID,
+		groupID,
+		appID,
+		role,
+	).Scan(&groupExists, &roleExists)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	switch {
+	case !groupExists:
+		return fmt.Errorf("%s %w", op, storage.ErrGroupNotFound)
+	case !roleExists:
+		return fmt.Errorf("%s %w", op, storage.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+func scanGroups(rows *sql.Rows) ([]models.Group, error) {
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		var g models.Group
+		if err := rows.Scan(&g.Id, &g.OrgId, &g.Name, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	invitationColumns = "id, org_id, email, roles, created_at, expires_at, accepted_at, revoked_at"
+)
+
+func (s *Storage) SaveInvitation(ctx context.Context, inv models.Invitation, nonceHash []byte) (int64, error) {
+	const op = "storage.postgres.saveInvitation"
+
+	roles, err := json.Marshal(inv.Roles)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	stmt, err := s.db.Prepare(`
+		insert into invitations (org_id, email, roles, nonce_hash, expires_at)
+		values ($1, $2, $3, $4, $5)
+		returning id`)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var id int64
+	if err = stmt.QueryRowContext(ctx, inv.OrgId, inv.Email, roles, nonceHash, inv.ExpiresAt).Scan(&id); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return id, nil
+}
+
+// Invitations returns invitations of organization, newest first
+func (s *Storage) Invitations(ctx context.Context, orgID int32) ([]models.Invitation, error) {
+	const op = "storage.postgres.invitations"
+
+	stmt, err := s.db.Prepare("select " + invitationColumns + " from invitations where org_id = $1 order by created_at desc")
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var list []models.Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		list = append(list, inv)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return list, nil
+}
+
+// RevokeInvitation revokes pending invitation of organization
+func (s *Storage) RevokeInvitation(ctx context.Context, orgID int32, invitationID int64) error {
+	const op = "storage.postgres.revokeInvitation"
+
+	stmt, err := s.db.Prepare(`
+		update invitations set revoked_at = now()
+		where org_id = $1
+		  and id = $2
+		  and accepted_at is null
+		  and revoked_at is null`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, invitationID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrInvitationNotFound)
+}
+
+// AcceptInvitation consumes pending invitation, creates invitee with passHash unless
+// the organization has the account already and assigns invitation roles, all in one transaction.
+// Without passHash a missing account is storage.ErrNotFound. Returns invitation and id of the user
+func (s *Storage) AcceptInvitation(
+	ctx context.Context,
+	invitationID int64,
+	nonceHash []byte,
+	passHash []byte,
+) (models.Invitation, int64, error) {
+	const op = "storage.postgres.acceptInvitation"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	inv, err := scanInvitation(tx.QueryRowContext(ctx, `
+		update invitations set accepted_at = now()
+		where id = $1
+		  and nonce_hash = $2
+		  and accepted_at is null
+		  and revoked_at is null
+		  and expires_at > now()
+		returning `+invitationColumns,
+		invitationID,
+		nonceHash,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Invitation{}, 0, fmt.Errorf("%s %w", op, storage.ErrInvitationNotFound)
+		}
+
+		return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	var userID int64
+	err = tx.QueryRowContext(ctx, "select id from users where org_id = $1 and email = $2", inv.OrgId, inv.Email).
+		Scan(&userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if passHash == nil {
+			return models.Invitation{}, 0, fmt.Errorf("%s %w", op, storage.ErrNotFound)
+		}
+
+		err = tx.QueryRowContext(
+			ctx,
+			"insert into users (org_id, email, pass_hash) values ($1, $2, $3) returning id",
+			inv.OrgId,
+			inv.Email,
+			passHash,
+		).Scan(&userID)
+		if err != nil {
+			var postgresErr *pgconn.PgError
+			if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+				return models.Invitation{}, 0, fmt.Errorf("%s %w", op, storage.ErrUserExists)
+			}
+
+			return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+		}
+	case err != nil:
+		return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	for _, r := range inv.Roles {
+		var roleID int32
+		err = tx.QueryRowContext(ctx, `
+			select r.id
+			from roles r
+			         join apps a on a.id = r.app_id
+			where r.app_id = $1
+			  and r.name = $2
+			  and a.org_id = $3`,
+			r.AppId,
+			r.Role,
+			inv.OrgId,
+		).Scan(&roleID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.Invitation{}, 0, fmt.Errorf("%s %w: %q of app %d", op, storage.ErrRoleNotFound, r.Role, r.AppId)
+			}
+
+			return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"insert into user_roles (user_id, role_id) values ($1, $2) on conflict do nothing",
+			userID,
+			roleID,
+		)
+		if err != nil {
+			return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Invitation{}, 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return inv, userID, nil
+}
+
+func scanInvitation(row scanner) (models.Invitation, error) {
+	var (
+		inv   models.Invitation
+		roles []byte
+	)
+	err := row.Scan(
+		&inv.Id,
+		&inv.OrgId,
+		&inv.Email,
+		&roles,
+		&inv.CreatedAt,
+		&inv.ExpiresAt,
+		&inv.AcceptedAt,
+		&inv.RevokedAt,
+	)
+	if err != nil {
+		return models.Invitation{}, err
+	}
+
+	if err = json.Unmarshal(roles, &inv.Roles); err != nil {
+		return models.Invitation{}, err
+	}
+
+	return inv, nil
+}
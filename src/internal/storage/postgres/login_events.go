@@ -0,0 +1,286 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	loginEventColumns = "id, org_id, app_id, user_id, email, success, reason, ip, user_agent, device, network, " +
+		"country, city, asn, as_org, latitude, longitude, impossible_travel, risk_score, risk_signals, step_up, created_at"
+)
+
+// SaveLoginEvent saves login attempt and reports whether successful one came from device
+// user never logged in from before. The very first login of user isn't from a new device
+func (s *Storage) SaveLoginEvent(ctx context.Context, event models.LoginEvent) (bool, error) {
+	const op = "storage.postgres.saveLoginEvent"
+
+	var latitude, longitude *float64
+	if event.Geo.HasCoordinates {
+		latitude, longitude = &event.Geo.Latitude, &event.Geo.Longitude
+	}
+
+	riskSignals, err := json.Marshal(nonNil(event.RiskSignals))
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	// subqueries of returning see table as it was before the insert
+	stmt, err := s.db.Prepare(`
+		insert into login_events (org_id, app_id, user_id, email, success, reason, ip, user_agent, device,
+		                          country, city, asn, as_org, latitude, longitude, impossible_travel,
+		                          network, risk_score, risk_signals, step_up)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		returning $5 and $3::int is not null
+		    and exists(select 1 from login_events where user_id = $3 and success)
+		    and not exists(select 1 from login_events where user_id = $3 and device = $9 and success)`)
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var newDevice bool
+	err = stmt.QueryRowContext(
+		ctx,
+		event.OrgId,
+		event.AppId,
+		event.UserId,
+		event.Email,
+		event.Success,
+		event.Reason,
+		event.IP,
+		event.UserAgent,
+		event.Device,
+		event.Geo.Country,
+		event.Geo.City,
+		int64(event.Geo.ASN),
+		event.Geo.ASOrg,
+		latitude,
+		longitude,
+		event.ImpossibleTravel,
+		event.Network,
+		event.RiskScore,
+		riskSignals,
+		event.StepUp,
+	).Scan(&newDevice)
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	return newDevice, nil
+}
+
+// LastLocatedLogin returns the latest successful login of user with known coordinates
+func (s *Storage) LastLocatedLogin(ctx context.Context, userID int64) (models.LoginEvent, error) {
+	const op = "storage.postgres.lastLocatedLogin"
+
+	stmt, err := s.db.Prepare(`
+		select ` + loginEventColumns + `
+		from login_events
+		where user_id = $1
+		  and success
+		  and latitude is not null
+		order by id desc
+		limit 1`)
+	if err != nil {
+		return models.LoginEvent{}, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	event, err := scanLoginEvent(stmt.QueryRowContext(ctx, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.LoginEvent{}, fmt.Errorf("%s %w", op, storage.ErrLoginEventNotFound)
+		}
+
+		return models.LoginEvent{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	return event, nil
+}
+
+// LoginFamiliarity compares login of user from event with previous successful ones.
+// Failed password attempts are counted over the last failureWindow from IP or device of event only,
+// so failures of others knowing the email don't raise risk of user's own logins. Time of day is compared in UTC
+func (s *Storage) LoginFamiliarity(
+	ctx context.Context,
+	userID int64,
+	event models.LoginEvent,
+	failureWindow time.Duration,
+) (models.LoginFamiliarity, error) {
+	const op = "storage.postgres.loginFamiliarity"
+
+	stmt, err := s.db.Prepare(`
+		select (select count(*)
+		        from login_events
+		        where user_id = $1
+		          and reason = $2
+		          and created_at > now() - make_interval(secs => $3)
+		          and (ip = $7 and $7 <> '' or device = $4 and $4 <> '')),
+		       not exists(select 1 from login_events where user_id = $1 and success),
+		       exists(select 1 from login_events where user_id = $1 and success and device = $4),
+		       exists(select 1 from login_events where user_id = $1 and success and network = $5),
+		       exists(select 1 from login_events where user_id = $1 and success and country = $6),
+		       exists(select 1
+		              from login_events
+		              where user_id = $1
+		                and success
+		                and abs(extract(hour from created_at at time zone 'UTC') -
+		                        extract(hour from now() at time zone 'UTC')) in (0, 1, 23))`)
+	if err != nil {
+		return models.LoginFamiliarity{}, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var res models.LoginFamiliarity
+	err = stmt.QueryRowContext(
+		ctx,
+		userID,
+		models.LoginInvalidPassword,
+		failureWindow.Seconds(),
+		event.Device,
+		event.Network,
+		event.Geo.Country,
+		event.IP,
+	).Scan(
+		&res.RecentFailures,
+		&res.FirstLogin,
+		&res.KnownDevice,
+		&res.KnownNetwork,
+		&res.KnownCountry,
+		&res.KnownHour,
+	)
+	if err != nil {
+		return models.LoginFamiliarity{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	return res, nil
+}
+
+// LoginEvents returns up to limit login attempts of user with id less than beforeID, latest first.
+// beforeID 0 starts from the latest one
+func (s *Storage) LoginEvents(
+	ctx context.Context,
+	orgID int32,
+	userID int64,
+	beforeID int64,
+	limit int,
+) ([]models.LoginEvent, error) {
+	const op = "storage.postgres.loginEvents"
+
+	stmt, err := s.db.Prepare(`
+		select ` + loginEventColumns + `
+		from login_events
+		where org_id = $1
+		  and user_id = $2
+		  and ($3 = 0 or id < $3)
+		order by id desc
+		limit $4`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID, userID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.LoginEvent
+	for rows.Next() {
+		event, err := scanLoginEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return events, nil
+}
+
+// DeleteLoginEvents deletes up to limit login attempts made before, returns number of deleted ones
+func (s *Storage) DeleteLoginEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	const op = "storage.postgres.deleteLoginEvents"
+
+	stmt, err := s.db.Prepare(`
+		delete
+		from login_events
+		where id in (select id
+		             from login_events
+		             where created_at < $1
+		             limit $2 for update skip locked)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return int(deleted), nil
+}
+
+func scanLoginEvent(row scanner) (models.LoginEvent, error) {
+	var (
+		event               models.LoginEvent
+		asn                 int64
+		latitude, longitude *float64
+		riskSignals         []byte
+	)
+	err := row.Scan(
+		&event.Id,
+		&event.OrgId,
+		&event.AppId,
+		&event.UserId,
+		&event.Email,
+		&event.Success,
+		&event.Reason,
+		&event.IP,
+		&event.UserAgent,
+		&event.Device,
+		&event.Network,
+		&event.Geo.Country,
+		&event.Geo.City,
+		&asn,
+		&event.Geo.ASOrg,
+		&latitude,
+		&longitude,
+		&event.ImpossibleTravel,
+		&event.RiskScore,
+		&riskSignals,
+		&event.StepUp,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return models.LoginEvent{}, err
+	}
+
+	if err = json.Unmarshal(riskSignals, &event.RiskSignals); err != nil {
+		return models.LoginEvent{}, err
+	}
+
+	event.Geo.ASN = uint32(asn)
+	if latitude != nil && longitude != nil {
+		event.Geo.Latitude, event.Geo.Longitude = *latitude, *longitude
+		event.Geo.HasCoordinates = true
+	}
+
+	return event, nil
+}
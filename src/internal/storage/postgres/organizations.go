@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// orgContentConstraints are foreign keys of rows that have to be removed before their organization
+var orgContentConstraints = map[string]bool{
+	"users_org_id_fkey":           true,
+	"apps_org_id_fkey":            true,
+	"relation_tuples_org_id_fkey": true,
+}
+
+func (s *Storage) SaveOrg(ctx context.Context, name string) (int32, error) {
+	const op = "storage.postgres.saveOrg"
+
+	stmt, err := s.db.Prepare("insert into organizations (name) values ($1) returning id")
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var id int32
+	if err = stmt.QueryRowContext(ctx, name).Scan(&id); err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+			return 0, fmt.Errorf("%s %w", op, storage.ErrOrgExists)
+		}
+
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Org(ctx context.Context, orgID int32) (models.Organization, error) {
+	const op = "storage.postgres.org"
+
+	stmt, err := s.db.Prepare("select id, name, created_at from organizations where id = $1")
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var org models.Organization
+	if err = stmt.QueryRowContext(ctx, orgID).Scan(&org.Id, &org.Name, &org.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return org, fmt.Errorf("%s %w", op, storage.ErrOrgNotFound)
+		}
+
+		return org, fmt.Errorf("%s %w", op, err)
+	}
+
+	return org, nil
+}
+
+func (s *Storage) Orgs(ctx context.Context) ([]models.Organization, error) {
+	const op = "storage.postgres.orgs"
+
+	rows, err := s.db.QueryContext(ctx, "select id, name, created_at from organizations order by id")
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var org models.Organization
+		if err = rows.Scan(&org.Id, &org.Name, &org.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		orgs = append(orgs, org)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return orgs, nil
+}
+
+func (s *Storage) RenameOrg(ctx context.Context, orgID int32, name string) error {
+	const op = "storage.postgres.renameOrg"
+
+	stmt, err := s.db.Prepare("update organizations set name = $2 where id = $1")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, name)
+	if err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+			return fmt.Errorf("%s %w", op, storage.ErrOrgExists)
+		}
+
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrOrgNotFound)
+}
+
+// DeleteOrg removes empty organization, users, apps and relation tuples have to be removed first
+func (s *Storage) DeleteOrg(ctx context.Context, orgID int32) error {
+	const op = "storage.postgres.deleteOrg"
+
+	stmt, err := s.db.Prepare("delete from organizations where id = $1")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID)
+	if err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) &&
+			postgresErr.Code == ForeignKeyViolationErr &&
+			orgContentConstraints[postgresErr.ConstraintName] {
+			return fmt.Errorf("%s %w", op, storage.ErrOrgNotEmpty)
+		}
+
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrOrgNotFound)
+}
+
+func affectedOrNotFound(op string, res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s %w", op, notFound)
+	}
+
+	return nil
+}
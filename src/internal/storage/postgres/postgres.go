@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"domofon/internal/domain/models"
 	"domofon/internal/storage"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
@@ -13,14 +14,25 @@ import (
 )
 
 type Storage struct {
-	db *sql.DB
+	db     *sql.DB
+	cipher SecretCipher
+}
+
+// SecretCipher encrypts app secrets at rest, bound to the app they belong to
+type SecretCipher interface {
+	Seal(appID int32, plaintext string) (string, error)
+	Open(appID int32, value string) (string, error)
+	// NeedsReseal reports whether stored value isn't sealed with the current key
+	NeedsReseal(value string) bool
 }
 
 const (
-	UniqueViolationErr = "23505"
+	UniqueViolationErr     = "23505"
+	ForeignKeyViolationErr = "23503"
 )
 
-func NewStorage(dsn string) (*Storage, error) {
+// NewStorage returns postgres storage, app secrets are kept in plaintext when cipher is nil
+func NewStorage(dsn string, cipher SecretCipher) (*Storage, error) {
 	const op = "storage.postgres.new"
 
 	db, err := sql.Open("pgx", dsn)
@@ -28,23 +40,26 @@ func NewStorage(dsn string) (*Storage, error) {
 		return nil, fmt.Errorf("%s %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return &Storage{db: db, cipher: cipher}, nil
 }
 
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
+func (s *Storage) SaveUser(ctx context.Context, orgID int32, email string, passHash []byte) (int64, error) {
 	const op = "storage.postgres.saveUser"
 
-	stmt, err := s.db.Prepare("insert into users (email, pass_hash) VALUES ($1,$2) RETURNING id")
+	stmt, err := s.db.Prepare("insert into users (org_id, email, pass_hash) VALUES ($1,$2,$3) RETURNING id")
 	if err != nil {
 		return 0, fmt.Errorf("%s %w", op, err)
 	}
 
 	var id int64
-	if err = stmt.QueryRowContext(ctx, email, passHash).Scan(&id); err != nil {
+	if err = stmt.QueryRowContext(ctx, orgID, email, passHash).Scan(&id); err != nil {
 		var postgresErr *pgconn.PgError
 		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
 			return 0, fmt.Errorf("%s %w", op, storage.ErrUserExists)
 		}
+		if errors.As(err, &postgresErr) && postgresErr.Code == ForeignKeyViolationErr {
+			return 0, fmt.Errorf("%s %w", op, storage.ErrOrgNotFound)
+		}
 
 		return 0, fmt.Errorf("%s %w", op, err)
 	}
@@ -52,18 +67,36 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 	return id, nil
 }
 
-func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
+func (s *Storage) User(ctx context.Context, orgID int32, email string) (models.User, error) {
 	const op = "storage.postgres.user"
 
-	stmt, err := s.db.Prepare("select * from users where email = $1")
+	stmt, err := s.db.Prepare("select " + userColumns + " from users where org_id = $1 and email = $2")
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s %w", op, err)
 	}
 
-	result := stmt.QueryRowContext(ctx, email)
+	user, err := scanUser(stmt.QueryRowContext(ctx, orgID, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s %w", op, storage.ErrNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) UserByID(ctx context.Context, orgID int32, userID int64) (models.User, error) {
+	const op = "storage.postgres.userByID"
+
+	stmt, err := s.db.Prepare("select " + userColumns + " from users where org_id = $1 and id = $2")
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
 
-	var user models.User
-	err = result.Scan(&user.Id, &user.Email, &user.PassHash, &user.IsAdmin)
+	user, err := scanUser(stmt.QueryRowContext(ctx, orgID, userID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s %w", op, storage.ErrNotFound)
@@ -75,15 +108,52 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	return user, nil
 }
 
-func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+const (
+	userColumns = "id, org_id, email, pass_hash, is_admin, email_verified, profile, created_at, disabled_at"
+	appColumns  = "id, org_id, name, secret, login_policy, claims_policy, groups_claim, " +
+		"redirect_uris, grant_types, token_settings, session_settings, risk_settings, tls_client_auth, created_at"
+)
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (models.User, error) {
+	var (
+		user    models.User
+		profile []byte
+	)
+	err := row.Scan(
+		&user.Id,
+		&user.OrgId,
+		&user.Email,
+		&user.PassHash,
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&profile,
+		&user.CreatedAt,
+		&user.DisabledAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err = json.Unmarshal(profile, &user.Profile); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, orgID int32, userID int64) (bool, error) {
 	const op = "storage.postgres.isAdmin"
 
-	stmt, err := s.db.Prepare("select users.is_admin from users where id = $1")
+	stmt, err := s.db.Prepare("select users.is_admin from users where id = $1 and org_id = $2")
 	if err != nil {
 		return false, fmt.Errorf("%s %w", op, err)
 	}
 
-	result := stmt.QueryRowContext(ctx, userID)
+	result := stmt.QueryRowContext(ctx, userID, orgID)
 
 	var isAdmin bool
 	if err = result.Scan(&isAdmin); err != nil {
@@ -97,18 +167,17 @@ func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	return isAdmin, nil
 }
 
+// App isn't scoped by organization, the app of a request is what selects its tenant
 func (s *Storage) App(ctx context.Context, appID int32) (models.App, error) {
 	const op = "storage.postgres.app"
 
-	stmt, err := s.db.Prepare("select * from apps where id = $1")
+	stmt, err := s.db.Prepare("select " + appColumns + " from apps where id = $1")
 	if err != nil {
 		return models.App{}, fmt.Errorf("%s %w", op, err)
 	}
 
-	result := stmt.QueryRowContext(ctx, appID)
-
-	var app models.App
-	if err = result.Scan(&app.Id, &app.Name, &app.Secret); err != nil {
+	app, err := s.scanApp(stmt.QueryRowContext(ctx, appID))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return app, fmt.Errorf("%s %w", op, storage.ErrAppNotFound)
 		}
@@ -118,3 +187,107 @@ func (s *Storage) App(ctx context.Context, appID int32) (models.App, error) {
 
 	return app, nil
 }
+
+// scanApp decrypts secret of scanned app
+func (s *Storage) scanApp(row scanner) (models.App, error) {
+	var (
+		app             models.App
+		redirectURIs    []byte
+		grantTypes      []byte
+		tokenSettings   []byte
+		sessionSettings []byte
+		riskSettings    []byte
+		tlsClientAuth   []byte
+	)
+	err := row.Scan(
+		&app.Id,
+		&app.OrgId,
+		&app.Name,
+		&app.Secret,
+		&app.LoginPolicy,
+		&app.ClaimsPolicy,
+		&app.GroupsClaim,
+		&redirectURIs,
+		&grantTypes,
+		&tokenSettings,
+		&sessionSettings,
+		&riskSettings,
+		&tlsClientAuth,
+		&app.CreatedAt,
+	)
+	if err != nil {
+		return models.App{}, err
+	}
+
+	if err = json.Unmarshal(redirectURIs, &app.RedirectURIs); err != nil {
+		return models.App{}, err
+	}
+	if err = json.Unmarshal(grantTypes, &app.GrantTypes); err != nil {
+		return models.App{}, err
+	}
+	if app.Tokens, err = decodeTokenSettings(tokenSettings); err != nil {
+		return models.App{}, err
+	}
+	if app.Sessions, err = decodeSessionSettings(sessionSettings); err != nil {
+		return models.App{}, err
+	}
+	if app.Risk, err = decodeRiskSettings(riskSettings); err != nil {
+		return models.App{}, err
+	}
+	if app.TLSClientAuth, err = decodeTLSClientAuth(tlsClientAuth); err != nil {
+		return models.App{}, err
+	}
+
+	if app.Secret, err = s.openSecret(app.Id, app.Secret); err != nil {
+		return models.App{}, err
+	}
+
+	return app, nil
+}
+
+func (s *Storage) sealSecret(appID int32, secret string) (string, error) {
+	if s.cipher == nil {
+		return secret, nil
+	}
+
+	return s.cipher.Seal(appID, secret)
+}
+
+func (s *Storage) openSecret(appID int32, value string) (string, error) {
+	if s.cipher == nil {
+		return value, nil
+	}
+
+	return s.cipher.Open(appID, value)
+}
+
+func (s *Storage) SaveAppPolicies(
+	ctx context.Context,
+	orgID int32,
+	appID int32,
+	loginPolicy string,
+	claimsPolicy string,
+) error {
+	const op = "storage.postgres.saveAppPolicies"
+
+	stmt, err := s.db.Prepare("update apps set login_policy = $3, claims_policy = $4 where org_id = $1 and id = $2")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, appID, loginPolicy, claimsPolicy)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AssignRole grants role of app to user, both have to belong to organization
+func (s *Storage) AssignRole(ctx context.Context, orgID int32, userID int64, appID int32, role string) error {
+	const op = "storage.postgres.assignRole"
+
+	stmt, err := s.db.Prepare(`
+		insert into user_roles (user_id, role_id)
+		select u.id, r.id
+		from roles r
+		         join apps a on a.id = r.app_id and a.org_id = $4
+		         join users u on u.org_id = a.org_id and u.id = $1
+		where r.app_id = $2
+		  and r.name = $3
+		on conflict do nothing
+		returning role_id`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var roleID int32
+	if err = stmt.QueryRowContext(ctx, userID, appID, role, orgID).Scan(&roleID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// role or user is missing, or role is already assigned
+			return s.checkAssignment(ctx, op, orgID, userID, appID, role)
+		}
+
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeRole(ctx context.Context, orgID int32, userID int64, appID int32, role string) error {
+	const op = "storage.postgres.revokeRole"
+
+	stmt, err := s.db.Prepare(`
+		delete from user_roles
+		where user_id = (select id from users where id = $1 and org_id = $4)
+		  and role_id = (select r.id
+		                 from roles r
+		                          join apps a on a.id = r.app_id
+		                 where r.app_id = $2
+		                   and r.name = $3
+		                   and a.org_id = $4)`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, userID, appID, role, orgID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s %w", op, storage.ErrRoleNotAssigned)
+	}
+
+	return nil
+}
+
+// userRoleIDs selects ids of roles granted to user $1 directly or through groups.
+// Queries using it have to check org of the user and the role
+const userRoleIDs = `
+	with recursive ancestors(id) as (
+	    select group_id
+	    from group_users
+	    where user_id = $1
+	    union
+	    select gg.parent_id
+	    from group_groups gg
+	             join ancestors a on gg.child_id = a.id
+	),
+	granted(role_id) as (
+	    select role_id
+	    from user_roles
+	    where user_id = $1
+	    union
+	    select gr.role_id
+	    from group_roles gr
+	             join ancestors a on a.id = gr.group_id
+	)`
+
+// UserRoles returns roles of user in app of organization with their permissions,
+// roles of groups user belongs to are included
+func (s *Storage) UserRoles(ctx context.Context, orgID int32, userID int64, appID int32) ([]models.Role, error) {
+	const op = "storage.postgres.userRoles"
+
+	stmt, err := s.db.Prepare(userRoleIDs + `
+		select r.id, r.app_id, r.name, p.name
+		from granted g
+		         join roles r on r.id = g.role_id
+		         join apps a on a.id = r.app_id
+		         join users u on u.id = $1 and u.org_id = a.org_id
+		         left join role_permissions rp on rp.role_id = r.id
+		         left join permissions p on p.id = rp.permission_id
+		where r.app_id = $2
+		  and a.org_id = $3
+		order by r.name, p.name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID, appID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var (
+			role       models.Role
+			permission sql.NullString
+		)
+		if err = rows.Scan(&role.Id, &role.AppId, &role.Name, &permission); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Id != role.Id {
+			roles = append(roles, role)
+		}
+		if permission.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) HasPermission(
+	ctx context.Context,
+	orgID int32,
+	userID int64,
+	appID int32,
+	permission string,
+) (bool, error) {
+	const op = "storage.postgres.hasPermission"
+
+	stmt, err := s.db.Prepare(userRoleIDs + `
+		select exists(
+			select 1
+			from granted g
+			         join roles r on r.id = g.role_id
+			         join apps a on a.id = r.app_id
+			         join users u on u.id = $1 and u.org_id = a.org_id
+			         join role_permissions rp on rp.role_id = r.id
+			         join permissions p on p.id = rp.permission_id
+			where r.app_id = $2
+			  and p.name = $3
+			  and a.org_id = $4)`)
+	if err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var allowed bool
+	if err = stmt.QueryRowContext(ctx, userID, appID, permission, orgID).Scan(&allowed); err != nil {
+		return false, fmt.Errorf("%s %w", op, err)
+	}
+
+	return allowed, nil
+}
+
+// ValidateRoles returns storage.ErrRoleNotFound unless every role belongs to app of organization
+func (s *Storage) ValidateRoles(ctx context.Context, orgID int32, roles []models.InvitationRole) error {
+	const op = "storage.postgres.validateRoles"
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(roles)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	stmt, err := s.db.Prepare(`
+		select x.app_id, x.role
+		from jsonb_to_recordset($2::jsonb) as x(app_id int, role text)
+		where not exists(select 1
+		                 from roles r
+		                          join apps a on a.id = r.app_id
+		                 where r.app_id = x.app_id
+		                   and r.name = x.role
+		                   and a.org_id = $1)
+		limit 1`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	var missing models.InvitationRole
+	err = stmt.QueryRowContext(ctx, orgID, raw).Scan(&missing.AppId, &missing.Role)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return fmt.Errorf("%s %w: %q of app %d", op, storage.ErrRoleNotFound, missing.Role, missing.AppId)
+}
+
+func (s *Storage) checkAssignment(
+	ctx context.Context,
+	op string,
+	orgID int32,
+	userID int64,
+	appID int32,
+	role string,
+) error {
+	var roleExists, userExists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`select exists(select 1
+		               from roles r
+		                        join apps a on a.id = r.app_id
+		               where r.app_id = $2
+		                 and r.name = $3
+		                 and a.org_id = $4),
+		        exists(select 1 from users where id = $1 and org_id = $4)`,
+		userID,
+		appID,
+		role,
+		orgID,
+	).Scan(&roleExists, &userExists)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	switch {
+	case !roleExists:
+		return fmt.Errorf("%s %w", op, storage.ErrRoleNotFound)
+	case !userExists:
+		return fmt.Errorf("%s %w", op, storage.ErrNotFound)
+	}
+
+	return nil
+}
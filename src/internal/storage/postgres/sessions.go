@@ -0,0 +1,270 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+const (
+	// sessionEnded is condition of session ended by its timeouts
+	sessionEnded = "(coalesce(expires_at <= now(), false) or " +
+		"(idle_timeout > 0 and last_seen_at + make_interval(secs => idle_timeout) <= now()))"
+	sessionColumns = "id, org_id, app_id, user_id, ip, user_agent, created_at, last_seen_at, " +
+		"idle_timeout, expires_at, revoked_at"
+)
+
+// SaveSession saves session of user keeping at most maxSessions active ones of user in app.
+// Over the limit the oldest sessions are revoked with evictOldest, otherwise ErrSessionLimit is returned.
+// maxSessions 0 doesn't limit sessions
+func (s *Storage) SaveSession(ctx context.Context, session models.Session, maxSessions int, evictOldest bool) error {
+	const op = "storage.postgres.saveSession"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if maxSessions > 0 {
+		if err = limitSessions(ctx, tx, session, maxSessions, evictOldest); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`insert into sessions (id, org_id, app_id, user_id, ip, user_agent, created_at, last_seen_at, idle_timeout, expires_at)
+		 values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		session.Id,
+		session.OrgId,
+		session.AppId,
+		session.UserId,
+		session.IP,
+		session.UserAgent,
+		session.CreatedAt,
+		session.LastSeenAt,
+		int64(session.IdleTimeout/time.Second),
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// limitSessions makes room for one more session of user in app.
+// Advisory lock of user and app serializes concurrent logins until tx ends,
+// so they can't all see free room and exceed the limit together
+func limitSessions(ctx context.Context, tx *sql.Tx, session models.Session, maxSessions int, evictOldest bool) error {
+	_, err := tx.ExecContext(ctx, "select pg_advisory_xact_lock($1)", sessionsLock(session.UserId, session.AppId))
+	if err != nil {
+		return err
+	}
+
+	const activeSessions = `
+		from sessions
+		where user_id = $1
+		  and app_id = $2
+		  and revoked_at is null
+		  and not ` + sessionEnded
+
+	var active int
+	err = tx.QueryRowContext(ctx, "select count(*) "+activeSessions, session.UserId, session.AppId).Scan(&active)
+	if err != nil {
+		return err
+	}
+
+	if active < maxSessions {
+		return nil
+	}
+	if !evictOldest {
+		return storage.ErrSessionLimit
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`update sessions set revoked_at = now()
+		 where id in (select id `+activeSessions+` order by created_at limit $3)`,
+		session.UserId,
+		session.AppId,
+		active-maxSessions+1,
+	)
+
+	return err
+}
+
+// sessionsLock is advisory lock key of sessions of user in app. Single bigint keys
+// don't overlap with two-key locks, hashing the namespace in keeps it apart from other bigint ones
+func sessionsLock(userID int64, appID int32) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("sessions:" + strconv.FormatInt(userID, 10) + ":" + strconv.Itoa(int(appID))))
+
+	return int64(h.Sum64())
+}
+
+func (s *Storage) Session(ctx context.Context, sessionID string) (models.Session, error) {
+	const op = "storage.postgres.session"
+
+	stmt, err := s.db.Prepare("select " + sessionColumns + " from sessions where id = $1")
+	if err != nil {
+		return models.Session{}, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	session, err := scanSession(stmt.QueryRowContext(ctx, sessionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s %w", op, storage.ErrSessionNotFound)
+		}
+
+		return models.Session{}, fmt.Errorf("%s %w", op, err)
+	}
+
+	return session, nil
+}
+
+// Sessions returns sessions of user not revoked or ended, recently seen first
+func (s *Storage) Sessions(ctx context.Context, orgID int32, userID int64) ([]models.Session, error) {
+	const op = "storage.postgres.sessions"
+
+	stmt, err := s.db.Prepare(`
+		select ` + sessionColumns + `
+		from sessions
+		where org_id = $1
+		  and user_id = $2
+		  and revoked_at is null
+		  and not ` + sessionEnded + `
+		order by last_seen_at desc`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes active session of user
+func (s *Storage) RevokeSession(ctx context.Context, orgID int32, userID int64, sessionID string) error {
+	const op = "storage.postgres.revokeSession"
+
+	stmt, err := s.db.Prepare(`
+		update sessions set revoked_at = now()
+		where id = $1
+		  and org_id = $2
+		  and user_id = $3
+		  and revoked_at is null`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, sessionID, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrSessionNotFound)
+}
+
+// TouchSession sets last seen time of session to now
+func (s *Storage) TouchSession(ctx context.Context, sessionID string) error {
+	const op = "storage.postgres.touchSession"
+
+	stmt, err := s.db.Prepare("update sessions set last_seen_at = now() where id = $1")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, sessionID); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteEndedSessions deletes up to limit revoked or ended sessions, returns number of deleted ones
+func (s *Storage) DeleteEndedSessions(ctx context.Context, limit int) (int, error) {
+	const op = "storage.postgres.deleteEndedSessions"
+
+	stmt, err := s.db.Prepare(`
+		delete
+		from sessions
+		where id in (select id
+		             from sessions
+		             where revoked_at is not null
+		                or ` + sessionEnded + `
+		             limit $1 for update skip locked)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return int(deleted), nil
+}
+
+func scanSession(row scanner) (models.Session, error) {
+	var (
+		session     models.Session
+		idleTimeout int64
+	)
+	err := row.Scan(
+		&session.Id,
+		&session.OrgId,
+		&session.AppId,
+		&session.UserId,
+		&session.IP,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&idleTimeout,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		return models.Session{}, err
+	}
+	session.IdleTimeout = time.Duration(idleTimeout) * time.Second
+
+	return session, nil
+}
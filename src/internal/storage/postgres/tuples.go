@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"fmt"
+)
+
+const (
+	// relationWriteLock serializes tuple writes, so revisions commit in order
+	relationWriteLock = 7_301_029
+)
+
+// WriteTuples inserts and deletes tuples of organization as one new revision and returns it
+func (s *Storage) WriteTuples(
+	ctx context.Context,
+	orgID int32,
+	inserts []models.RelationTuple,
+	deletes []models.RelationTuple,
+) (int64, error) {
+	const op = "storage.postgres.writeTuples"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, "select pg_advisory_xact_lock($1)", relationWriteLock); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	var rev int64
+	err = tx.QueryRowContext(ctx, `
+		insert into relation_revisions (rev)
+		select coalesce(max(rev), 0) + 1 from relation_revisions
+		returning rev`).Scan(&rev)
+	if err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	for _, t := range deletes {
+		_, err = tx.ExecContext(ctx, `
+			update relation_tuples set deleted_rev = $1
+			where org_id = $2
+			  and namespace = $3
+			  and object_id = $4
+			  and relation = $5
+			  and subject_namespace = $6
+			  and subject_id = $7
+			  and subject_relation = $8
+			  and deleted_rev is null`,
+			rev, orgID, t.Namespace, t.ObjectId, t.Relation, t.Subject.Namespace, t.Subject.Id, t.Subject.Relation,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	for _, t := range inserts {
+		_, err = tx.ExecContext(ctx, `
+			insert into relation_tuples
+			    (org_id, namespace, object_id, relation, subject_namespace, subject_id, subject_relation, created_rev)
+			values ($1, $2, $3, $4, $5, $6, $7, $8)
+			on conflict do nothing`,
+			orgID, t.Namespace, t.ObjectId, t.Relation, t.Subject.Namespace, t.Subject.Id, t.Subject.Relation, rev,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return rev, nil
+}
+
+// Revision returns latest committed revision of tuples
+func (s *Storage) Revision(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.revision"
+
+	var rev int64
+	if err := s.db.QueryRowContext(ctx, "select coalesce(max(rev), 0) from relation_revisions").Scan(&rev); err != nil {
+		return 0, fmt.Errorf("%s %w", op, err)
+	}
+
+	return rev, nil
+}
+
+// Subjects returns subjects related to object by relation at revision
+func (s *Storage) Subjects(
+	ctx context.Context,
+	orgID int32,
+	namespace string,
+	objectID string,
+	relation string,
+	rev int64,
+) ([]models.Subject, error) {
+	const op = "storage.postgres.subjects"
+
+	stmt, err := s.db.Prepare(`
+		select subject_namespace, subject_id, subject_relation
+		from relation_tuples
+		where org_id = $1
+		  and namespace = $2
+		  and object_id = $3
+		  and relation = $4
+		  and created_rev <= $5
+		  and (deleted_rev is null or deleted_rev > $5)`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID, namespace, objectID, relation, rev)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var subjects []models.Subject
+	for rows.Next() {
+		var subject models.Subject
+		if err = rows.Scan(&subject.Namespace, &subject.Id, &subject.Relation); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		subjects = append(subjects, subject)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return subjects, nil
+}
+
+// Objects returns up to limit ids of objects in namespace having any tuple at revision,
+// ordered by id and starting after afterID
+func (s *Storage) Objects(
+	ctx context.Context,
+	orgID int32,
+	namespace string,
+	rev int64,
+	afterID string,
+	limit int,
+) ([]string, error) {
+	const op = "storage.postgres.objects"
+
+	stmt, err := s.db.Prepare(`
+		select distinct object_id
+		from relation_tuples
+		where org_id = $1
+		  and namespace = $2
+		  and created_rev <= $3
+		  and (deleted_rev is null or deleted_rev > $3)
+		  and object_id > $4
+		order by object_id
+		limit $5`)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, orgID, namespace, rev, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		objects = append(objects, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return objects, nil
+}
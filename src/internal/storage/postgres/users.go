@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"domofon/internal/domain/models"
+	"domofon/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strconv"
+	"strings"
+)
+
+// Users returns up to limit users of organization with id greater than afterID
+func (s *Storage) Users(
+	ctx context.Context,
+	orgID int32,
+	filter models.UserFilter,
+	afterID int64,
+	limit int,
+) ([]models.User, error) {
+	const op = "storage.postgres.users"
+
+	query := "select " + userColumns + " from users where org_id = $1 and id > $2"
+	args := []any{orgID, afterID}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.EmailPrefix != "" {
+		query += " and email like " + arg(escapeLike(filter.EmailPrefix)+"%")
+	}
+	if filter.IsAdmin != nil {
+		query += " and is_admin = " + arg(*filter.IsAdmin)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query += " and created_at >= " + arg(filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query += " and created_at < " + arg(filter.CreatedBefore)
+	}
+	query += " order by id limit " + arg(limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, orgID int32, userID int64, upd models.UserUpdate) error {
+	const op = "storage.postgres.updateUser"
+
+	var profile []byte
+	if upd.Profile != nil {
+		var err error
+		if profile, err = json.Marshal(upd.Profile); err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	stmt, err := s.db.Prepare(`
+		update users
+		set email          = coalesce($3, email),
+		    email_verified = coalesce($4, email_verified),
+		    profile        = coalesce($5::jsonb, profile)
+		where org_id = $1
+		  and id = $2`)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, userID, upd.Email, upd.EmailVerified, profile)
+	if err != nil {
+		var postgresErr *pgconn.PgError
+		if errors.As(err, &postgresErr) && postgresErr.Code == UniqueViolationErr {
+			return fmt.Errorf("%s %w", op, storage.ErrUserExists)
+		}
+
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrNotFound)
+}
+
+// SetUserDisabled disables or enables user. Disabling revokes sessions and opaque access tokens
+// of the user in the same transaction, they stay revoked once user is enabled back
+func (s *Storage) SetUserDisabled(ctx context.Context, orgID int32, userID int64, disabled bool) error {
+	const op = "storage.postgres.setUserDisabled"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		update users
+		set disabled_at = case when $3 then coalesce(disabled_at, now()) end
+		where org_id = $1
+		  and id = $2`,
+		orgID,
+		userID,
+		disabled,
+	)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	if err = affectedOrNotFound(op, res, storage.ErrNotFound); err != nil {
+		return err
+	}
+
+	if disabled {
+		_, err = tx.ExecContext(
+			ctx,
+			"update sessions set revoked_at = now() where org_id = $1 and user_id = $2 and revoked_at is null",
+			orgID,
+			userID,
+		)
+		if err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"update access_tokens set revoked_at = now() where org_id = $1 and user_id = $2 and revoked_at is null",
+			orgID,
+			userID,
+		)
+		if err != nil {
+			return fmt.Errorf("%s %w", op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetAdmin(ctx context.Context, orgID int32, userID int64, isAdmin bool) error {
+	const op = "storage.postgres.setAdmin"
+
+	stmt, err := s.db.Prepare("update users set is_admin = $3 where org_id = $1 and id = $2")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, userID, isAdmin)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrNotFound)
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, orgID int32, userID int64) error {
+	const op = "storage.postgres.deleteUser"
+
+	stmt, err := s.db.Prepare("delete from users where org_id = $1 and id = $2")
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("%s %w", op, err)
+	}
+
+	return affectedOrNotFound(op, res, storage.ErrNotFound)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -6,4 +6,27 @@ var (
 	ErrUserExists  = errors.New("user already exists")
 	ErrNotFound    = errors.New("user not found")
 	ErrAppNotFound = errors.New("application not found")
+	ErrAppExists   = errors.New("application already exists")
+
+	ErrOrgNotFound = errors.New("organization not found")
+	ErrOrgExists   = errors.New("organization already exists")
+	ErrOrgNotEmpty = errors.New("organization has users, apps or relation tuples")
+
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	ErrGroupNotFound = errors.New("group not found")
+	ErrGroupExists   = errors.New("group already exists")
+	ErrGroupCycle    = errors.New("group membership cycle")
+
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrRoleNotAssigned = errors.New("role not assigned")
+
+	ErrNoSecretCipher = errors.New("secret encryption isn't configured")
+
+	ErrTokenNotFound = errors.New("token not found")
+
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionLimit    = errors.New("session limit reached")
+
+	ErrLoginEventNotFound = errors.New("login event not found")
 )
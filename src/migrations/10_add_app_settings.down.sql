@@ -0,0 +1,9 @@
+begin;
+
+alter table apps
+    drop column if exists created_at,
+    drop column if exists token_ttl,
+    drop column if exists grant_types,
+    drop column if exists redirect_uris;
+
+commit
@@ -0,0 +1,9 @@
+begin;
+
+alter table apps
+    add column if not exists redirect_uris jsonb       not null default '[]',
+    add column if not exists grant_types   jsonb       not null default '["password"]',
+    add column if not exists token_ttl     bigint,
+    add column if not exists created_at    timestamptz not null default now();
+
+commit
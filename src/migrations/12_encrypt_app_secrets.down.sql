@@ -0,0 +1,6 @@
+begin;
+
+alter table apps
+    add constraint apps_secret_key unique (secret);
+
+commit
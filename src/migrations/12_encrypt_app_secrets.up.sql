@@ -0,0 +1,7 @@
+begin;
+
+-- sealed secrets are never equal, uniqueness of plaintext can't be checked by db
+alter table apps
+    drop constraint if exists apps_secret_key;
+
+commit
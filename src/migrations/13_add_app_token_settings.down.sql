@@ -0,0 +1,13 @@
+begin;
+
+alter table apps
+    add column if not exists token_ttl bigint;
+
+update apps
+set token_ttl = (token_settings ->> 'access_ttl')::bigint
+where token_settings ? 'access_ttl';
+
+alter table apps
+    drop column if exists token_settings;
+
+commit
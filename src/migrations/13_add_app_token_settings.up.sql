@@ -0,0 +1,13 @@
+begin;
+
+alter table apps
+    add column if not exists token_settings jsonb not null default '{}';
+
+update apps
+set token_settings = jsonb_build_object('access_ttl', token_ttl)
+where token_ttl is not null;
+
+alter table apps
+    drop column if exists token_ttl;
+
+commit
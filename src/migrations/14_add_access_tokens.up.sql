@@ -0,0 +1,19 @@
+begin;
+
+-- state of opaque access tokens, tokens themselves are never stored
+create table if not exists access_tokens
+(
+    id         bigint primary key generated always as identity,
+    token_hash bytea       not null unique,
+    org_id     int         not null references organizations (id) on delete cascade,
+    app_id     int         not null references apps (id) on delete cascade,
+    user_id    int         not null references users (id) on delete cascade,
+    claims     jsonb       not null default '{}',
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    revoked_at timestamptz
+);
+
+create index if not exists idx_access_tokens_expires_at on access_tokens (expires_at);
+
+commit
@@ -0,0 +1,6 @@
+begin;
+
+alter table apps
+    drop column if exists tls_client_auth;
+
+commit
@@ -0,0 +1,7 @@
+begin;
+
+-- client certificate of confidential apps, null allows login without one
+alter table apps
+    add column if not exists tls_client_auth jsonb;
+
+commit
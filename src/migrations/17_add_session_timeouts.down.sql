@@ -0,0 +1,10 @@
+begin;
+
+alter table sessions
+    drop column if exists idle_timeout,
+    drop column if exists expires_at;
+
+alter table apps
+    drop column if exists session_settings;
+
+commit
@@ -0,0 +1,21 @@
+begin;
+
+create table if not exists login_events
+(
+    id         bigint primary key generated always as identity,
+    org_id     int         not null references organizations (id) on delete cascade,
+    app_id     int         not null references apps (id) on delete cascade,
+    user_id    int references users (id) on delete cascade,
+    email      text        not null,
+    success    bool        not null,
+    reason     text        not null default '',
+    ip         text        not null default '',
+    user_agent text        not null default '',
+    device     text        not null default '',
+    created_at timestamptz not null default now()
+);
+
+create index if not exists idx_login_events_user on login_events (user_id, id);
+create index if not exists idx_login_events_device on login_events (user_id, device) where success;
+
+commit
@@ -0,0 +1,5 @@
+begin;
+
+drop index if exists idx_login_events_created_at;
+
+commit
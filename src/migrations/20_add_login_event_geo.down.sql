@@ -0,0 +1,12 @@
+begin;
+
+alter table login_events
+    drop column if exists country,
+    drop column if exists city,
+    drop column if exists asn,
+    drop column if exists as_org,
+    drop column if exists latitude,
+    drop column if exists longitude,
+    drop column if exists impossible_travel;
+
+commit
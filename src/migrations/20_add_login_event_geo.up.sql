@@ -0,0 +1,12 @@
+begin;
+
+alter table login_events
+    add column if not exists country           text             not null default '',
+    add column if not exists city              text             not null default '',
+    add column if not exists asn               bigint           not null default 0,
+    add column if not exists as_org            text             not null default '',
+    add column if not exists latitude          double precision,
+    add column if not exists longitude         double precision,
+    add column if not exists impossible_travel bool             not null default false;
+
+commit
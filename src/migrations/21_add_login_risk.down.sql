@@ -0,0 +1,13 @@
+begin;
+
+drop index if exists idx_login_events_network;
+
+alter table login_events
+    drop column if exists network,
+    drop column if exists risk_score,
+    drop column if exists risk_signals;
+
+alter table apps
+    drop column if exists risk_settings;
+
+commit
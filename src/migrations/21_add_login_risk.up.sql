@@ -0,0 +1,13 @@
+begin;
+
+alter table apps
+    add column if not exists risk_settings jsonb not null default '{}';
+
+alter table login_events
+    add column if not exists network      text  not null default '',
+    add column if not exists risk_score   int   not null default 0,
+    add column if not exists risk_signals jsonb not null default '[]';
+
+create index if not exists idx_login_events_network on login_events (user_id, network) where success;
+
+commit
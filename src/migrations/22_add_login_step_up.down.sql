@@ -0,0 +1,6 @@
+begin;
+
+alter table login_events
+    drop column if exists step_up;
+
+commit
@@ -0,0 +1,5 @@
+begin;
+
+drop index if exists idx_access_tokens_revoked_at;
+
+commit
@@ -0,0 +1,6 @@
+begin;
+
+-- sweeper deletes revoked tokens before they expire, expired ones are found by idx_access_tokens_expires_at
+create index if not exists idx_access_tokens_revoked_at on access_tokens (revoked_at) where revoked_at is not null;
+
+commit
@@ -0,0 +1,9 @@
+begin;
+
+drop index if exists idx_user_roles_role;
+drop table if exists user_roles;
+drop table if exists role_permissions;
+drop table if exists permissions;
+drop table if exists roles;
+
+commit
@@ -0,0 +1,35 @@
+begin;
+
+create table if not exists roles
+(
+    id     int primary key generated always as identity,
+    app_id int  not null references apps (id) on delete cascade,
+    name   text not null,
+    unique (app_id, name)
+);
+
+create table if not exists permissions
+(
+    id     int primary key generated always as identity,
+    app_id int  not null references apps (id) on delete cascade,
+    name   text not null,
+    unique (app_id, name)
+);
+
+create table if not exists role_permissions
+(
+    role_id       int not null references roles (id) on delete cascade,
+    permission_id int not null references permissions (id) on delete cascade,
+    primary key (role_id, permission_id)
+);
+
+create table if not exists user_roles
+(
+    user_id int not null references users (id) on delete cascade,
+    role_id int not null references roles (id) on delete cascade,
+    primary key (user_id, role_id)
+);
+
+create index if not exists idx_user_roles_role on user_roles (role_id);
+
+commit
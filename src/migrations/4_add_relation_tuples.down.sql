@@ -0,0 +1,8 @@
+begin;
+
+drop index if exists idx_relation_tuples_live;
+drop index if exists idx_relation_tuples_object;
+drop table if exists relation_tuples;
+drop table if exists relation_revisions;
+
+commit
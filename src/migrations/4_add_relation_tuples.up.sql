@@ -0,0 +1,29 @@
+begin;
+
+create table if not exists relation_revisions
+(
+    rev        bigint primary key,
+    created_at timestamptz not null default now()
+);
+
+create table if not exists relation_tuples
+(
+    id                bigint primary key generated always as identity,
+    namespace         text   not null,
+    object_id         text   not null,
+    relation          text   not null,
+    subject_namespace text   not null,
+    subject_id        text   not null,
+    subject_relation  text   not null default '',
+    created_rev       bigint not null references relation_revisions (rev),
+    deleted_rev       bigint references relation_revisions (rev)
+);
+
+create index if not exists idx_relation_tuples_object
+    on relation_tuples (namespace, object_id, relation);
+
+create unique index if not exists idx_relation_tuples_live
+    on relation_tuples (namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+    where deleted_rev is null;
+
+commit
@@ -0,0 +1,11 @@
+begin;
+
+alter table users
+    drop column if exists profile,
+    drop column if exists email_verified;
+
+alter table apps
+    drop column if exists claims_policy,
+    drop column if exists login_policy;
+
+commit
@@ -0,0 +1,32 @@
+begin;
+
+drop index if exists idx_relation_tuples_live;
+drop index if exists idx_relation_tuples_object;
+
+alter table relation_tuples
+    drop column if exists org_id;
+
+create index if not exists idx_relation_tuples_object
+    on relation_tuples (namespace, object_id, relation);
+
+create unique index if not exists idx_relation_tuples_live
+    on relation_tuples (namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+    where deleted_rev is null;
+
+alter table apps
+    drop constraint if exists apps_org_id_name_key,
+    drop column if exists org_id,
+    add constraint apps_name_key unique (name);
+
+drop index if exists idx_users_org_email;
+
+alter table users
+    drop constraint if exists users_org_id_email_key,
+    drop column if exists org_id,
+    add constraint users_email_key unique (email);
+
+create index if not exists idx_email on users (email);
+
+drop table if exists organizations;
+
+commit
@@ -0,0 +1,6 @@
+begin;
+
+drop index if exists idx_invitations_org;
+drop table if exists invitations;
+
+commit
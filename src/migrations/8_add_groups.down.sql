@@ -0,0 +1,13 @@
+begin;
+
+alter table apps
+    drop column if exists groups_claim;
+
+drop table if exists group_roles;
+drop index if exists idx_group_groups_child;
+drop table if exists group_groups;
+drop index if exists idx_group_users_user;
+drop table if exists group_users;
+drop table if exists groups;
+
+commit
@@ -0,0 +1,41 @@
+begin;
+
+create table if not exists groups
+(
+    id         int primary key generated always as identity,
+    org_id     int         not null references organizations (id) on delete cascade,
+    name       text        not null,
+    created_at timestamptz not null default now(),
+    unique (org_id, name)
+);
+
+create table if not exists group_users
+(
+    group_id int not null references groups (id) on delete cascade,
+    user_id  int not null references users (id) on delete cascade,
+    primary key (group_id, user_id)
+);
+
+create index if not exists idx_group_users_user on group_users (user_id);
+
+create table if not exists group_groups
+(
+    parent_id int not null references groups (id) on delete cascade,
+    child_id  int not null references groups (id) on delete cascade,
+    primary key (parent_id, child_id),
+    check (parent_id <> child_id)
+);
+
+create index if not exists idx_group_groups_child on group_groups (child_id);
+
+create table if not exists group_roles
+(
+    group_id int not null references groups (id) on delete cascade,
+    role_id  int not null references roles (id) on delete cascade,
+    primary key (group_id, role_id)
+);
+
+alter table apps
+    add column if not exists groups_claim bool not null default false;
+
+commit
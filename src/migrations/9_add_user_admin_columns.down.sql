@@ -0,0 +1,9 @@
+begin;
+
+drop index if exists idx_users_org_created;
+
+alter table users
+    drop column if exists disabled_at,
+    drop column if exists created_at;
+
+commit
@@ -0,0 +1,9 @@
+begin;
+
+alter table users
+    add column if not exists created_at  timestamptz not null default now(),
+    add column if not exists disabled_at timestamptz;
+
+create index if not exists idx_users_org_created on users (org_id, created_at);
+
+commit
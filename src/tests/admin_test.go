@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestAdmin_GetUpdateUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	userID, _ := newUser(ctx, st)
+
+	user, err := st.AdminClient.GetUser(adminCtx, &api.GetUserRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+	assert.False(t, user.IsAdmin)
+
+	verified := true
+	_, err = st.AdminClient.UpdateUser(adminCtx, &api.UpdateUserRequest{
+		UserId:        userID,
+		EmailVerified: &verified,
+		Profile:       map[string]any{"building_id": "7"},
+	})
+	require.NoError(t, err)
+
+	user, err = st.AdminClient.GetUser(adminCtx, &api.GetUserRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, "7", user.Profile["building_id"])
+
+	_, err = st.AdminClient.GetUser(adminCtx, &api.GetUserRequest{UserId: -1})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestAdmin_ListUsers_paging(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	prefix := gofakeit.LetterN(12)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		resp, err := register(ctx, st, prefix+gofakeit.Email(), randomFakePassport())
+		require.NoError(t, err)
+		ids = append(ids, resp.GetId())
+	}
+
+	var (
+		got    []int64
+		cursor string
+	)
+	for {
+		page, err := st.AdminClient.ListUsers(adminCtx, &api.ListUsersRequest{
+			EmailPrefix: prefix,
+			Cursor:      cursor,
+			PageSize:    2,
+		})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page.Users), 2)
+
+		for _, user := range page.Users {
+			got = append(got, user.Id)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, ids, got)
+
+	_, err := st.AdminClient.ListUsers(adminCtx, &api.ListUsersRequest{Cursor: "%%%"})
+	requireCode(st, err, codes.InvalidArgument)
+}
+
+func TestAdmin_DisableUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+	userID, userCtx := respRegister.GetId(), suite.WithToken(ctx, respLogin.GetToken())
+
+	_, err = st.AdminClient.DisableUser(adminCtx, &api.DisableUserRequest{UserId: userID, Disabled: true})
+	require.NoError(t, err)
+
+	_, err = st.RBACClient.ListRoles(userCtx, &api.ListRolesRequest{UserId: userID, AppId: AppId})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = login(ctx, st, email, pass)
+	require.Error(t, err)
+
+	_, err = st.AdminClient.DisableUser(adminCtx, &api.DisableUserRequest{UserId: userID, Disabled: false})
+	require.NoError(t, err)
+
+	// sessions revoked on disabling stay revoked
+	_, err = st.RBACClient.ListRoles(userCtx, &api.ListRolesRequest{UserId: userID, AppId: AppId})
+	requireCode(st, err, codes.Unauthenticated)
+
+	_, err = login(ctx, st, email, pass)
+	require.NoError(t, err)
+}
+
+func TestAdmin_selfLockout(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	adminID := adminUserID(st, adminCtx)
+
+	_, err := st.AdminClient.DisableUser(adminCtx, &api.DisableUserRequest{UserId: adminID, Disabled: true})
+	requireCode(st, err, codes.FailedPrecondition)
+
+	_, err = st.AdminClient.SetAdmin(adminCtx, &api.SetAdminRequest{UserId: adminID, IsAdmin: false})
+	requireCode(st, err, codes.FailedPrecondition)
+
+	_, err = st.AdminClient.DeleteUser(adminCtx, &api.DeleteUserRequest{UserId: adminID})
+	requireCode(st, err, codes.FailedPrecondition)
+}
+
+func TestAdmin_SetAdminDeleteUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	userID, userCtx := newUser(ctx, st)
+
+	_, err := st.AdminClient.GetUser(userCtx, &api.GetUserRequest{UserId: userID})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.AdminClient.SetAdmin(adminCtx, &api.SetAdminRequest{UserId: userID, IsAdmin: true})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.GetUser(userCtx, &api.GetUserRequest{UserId: userID})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.DeleteUser(adminCtx, &api.DeleteUserRequest{UserId: userID})
+	require.NoError(t, err)
+
+	_, err = st.AdminClient.GetUser(adminCtx, &api.GetUserRequest{UserId: userID})
+	requireCode(st, err, codes.NotFound)
+}
+
+// adminUserID returns id of seeded admin
+func adminUserID(st *suite.Suite, adminCtx context.Context) int64 {
+	st.Helper()
+
+	page, err := st.AdminClient.ListUsers(adminCtx, &api.ListUsersRequest{EmailPrefix: adminEmail})
+	require.NoError(st, err)
+	require.Len(st, page.Users, 1)
+
+	return page.Users[0].Id
+}
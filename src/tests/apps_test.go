@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	domofon_v1 "github.com/zose43/domofon-proto/out/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestApps_CreateLoginUpdateDelete(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	name := "panel " + gofakeit.UUID()
+
+	created, err := st.AppsClient.CreateApp(adminCtx, &api.CreateAppRequest{
+		Name:         name,
+		RedirectURIs: []string{"https://panel.domofon.test/callback"},
+		Tokens:       &api.TokenSettings{AccessTTL: "15m"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Secret)
+	assert.Equal(t, name, created.App.Name)
+	assert.Equal(t, []string{"password"}, created.App.GrantTypes)
+	assert.Equal(t, "15m0s", created.App.Tokens.AccessTTL)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	_, err = register(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &domofon_v1.LoginRequest{
+		Email:    email,
+		Password: pass,
+		AppId:    created.App.Id,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respLogin.GetToken())
+
+	newName := name + " v2"
+	_, err = st.AppsClient.UpdateApp(adminCtx, &api.UpdateAppRequest{
+		AppId:        created.App.Id,
+		Name:         &newName,
+		RedirectURIs: []string{},
+	})
+	require.NoError(t, err)
+
+	app, err := st.AppsClient.GetApp(adminCtx, &api.GetAppRequest{AppId: created.App.Id})
+	require.NoError(t, err)
+	assert.Equal(t, newName, app.Name)
+	assert.Empty(t, app.RedirectURIs)
+	assert.Equal(t, "15m0s", app.Tokens.AccessTTL)
+
+	list, err := st.AppsClient.ListApps(adminCtx, &api.Empty{})
+	require.NoError(t, err)
+	var ids []int32
+	for _, a := range list.Apps {
+		ids = append(ids, a.Id)
+	}
+	assert.Contains(t, ids, created.App.Id)
+
+	_, err = st.AppsClient.DeleteApp(adminCtx, &api.DeleteAppRequest{AppId: created.App.Id})
+	require.NoError(t, err)
+
+	_, err = st.AppsClient.GetApp(adminCtx, &api.GetAppRequest{AppId: created.App.Id})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestApps_CreateApp_invalid(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+
+	tests := []struct {
+		name    string
+		request *api.CreateAppRequest
+	}{
+		{
+			name:    "empty name",
+			request: &api.CreateAppRequest{},
+		},
+		{
+			name:    "relative redirect uri",
+			request: &api.CreateAppRequest{Name: gofakeit.UUID(), RedirectURIs: []string{"/callback"}},
+		},
+		{
+			name:    "unknown grant type",
+			request: &api.CreateAppRequest{Name: gofakeit.UUID(), GrantTypes: []string{"implicit"}},
+		},
+		{
+			name:    "invalid duration",
+			request: &api.CreateAppRequest{Name: gofakeit.UUID(), Tokens: &api.TokenSettings{AccessTTL: "soon"}},
+		},
+		{
+			name: "reserved static claim",
+			request: &api.CreateAppRequest{
+				Name:   gofakeit.UUID(),
+				Tokens: &api.TokenSettings{Claims: api.ClaimSettings{Static: map[string]any{"sub": "x"}}},
+			},
+		},
+		{
+			name:    "step-up above deny",
+			request: &api.CreateAppRequest{Name: gofakeit.UUID(), Risk: &api.RiskSettings{StepUpScore: number(80), DenyScore: number(60)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AppsClient.CreateApp(adminCtx, tt.request)
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), err.Error())
+		})
+	}
+}
+
+func TestApps_CreateApp_explicitZero(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+
+	created, err := st.AppsClient.CreateApp(adminCtx, &api.CreateAppRequest{
+		Name:     gofakeit.UUID(),
+		Sessions: &api.SessionSettings{IdleTimeout: "0s", MaxSessions: number(0)},
+		Risk:     &api.RiskSettings{DenyScore: number(0)},
+	})
+	require.NoError(t, err)
+
+	app, err := st.AppsClient.GetApp(adminCtx, &api.GetAppRequest{AppId: created.App.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "0s", app.Sessions.IdleTimeout)
+	assert.Empty(t, app.Sessions.MaxLifetime)
+	require.NotNil(t, app.Sessions.MaxSessions)
+	assert.Zero(t, *app.Sessions.MaxSessions)
+	require.NotNil(t, app.Risk.DenyScore)
+	assert.Zero(t, *app.Risk.DenyScore)
+	assert.Nil(t, app.Risk.StepUpScore)
+}
+
+func TestApps_access(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, userCtx := newUser(ctx, st)
+
+	_, err := st.AppsClient.CreateApp(userCtx, &api.CreateAppRequest{Name: gofakeit.UUID()})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.AppsClient.GetApp(userCtx, &api.GetAppRequest{AppId: AppId})
+	requireCode(st, err, codes.PermissionDenied)
+}
+
+func TestApps_RotateAppSecret_grace(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+
+	created, err := st.AppsClient.CreateApp(adminCtx, &api.CreateAppRequest{Name: "kiosk " + gofakeit.UUID()})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	userID := respRegister.GetId()
+
+	loginApp := func() context.Context {
+		resp, err := st.AuthClient.Login(ctx, &domofon_v1.LoginRequest{
+			Email:    email,
+			Password: pass,
+			AppId:    created.App.Id,
+		})
+		require.NoError(t, err)
+
+		return suite.WithToken(ctx, resp.GetToken())
+	}
+	listRoles := func(userCtx context.Context) error {
+		_, err := st.RBACClient.ListRoles(userCtx, &api.ListRolesRequest{UserId: userID, AppId: created.App.Id})
+		return err
+	}
+
+	oldCtx := loginApp()
+
+	rotated, err := st.AppsClient.RotateAppSecret(adminCtx, &api.RotateAppSecretRequest{AppId: created.App.Id})
+	require.NoError(t, err)
+	assert.NotEqual(t, created.Secret, rotated.Secret)
+
+	// token signed with previous secret is accepted within the grace window
+	require.NoError(t, listRoles(oldCtx))
+	require.NoError(t, listRoles(loginApp()))
+
+	time.Sleep(st.Cfg.Apps.SecretGrace + time.Second)
+
+	requireCode(st, listRoles(oldCtx), codes.Unauthenticated)
+	require.NoError(t, listRoles(loginApp()))
+
+	_, err = st.AppsClient.RotateAppSecret(adminCtx, &api.RotateAppSecretRequest{AppId: -1})
+	requireCode(st, err, codes.NotFound)
+}
+
+func number(v int) *int {
+	return &v
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	domofon_v1 "github.com/zose43/domofon-proto/out/go"
+	"google.golang.org/grpc/codes"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -15,6 +17,7 @@ import (
 const (
 	emptyAppId     = 0
 	AppId          = 1
+	defaultOrgId   = 1
 	appSecret      = "test-secret"
 	passDefaultLen = 10
 )
@@ -48,6 +51,12 @@ func TestRegisterLogin_Login_happyPath(t *testing.T) {
 	assert.Equal(t, respRegister.GetId(), int64(claims["uid"].(float64)))
 	assert.Equal(t, email, claims["email"].(string))
 	assert.Equal(t, AppId, int(claims["app"].(float64)))
+	assert.Empty(t, claims["roles"], "new user has no roles")
+	assert.Equal(t, defaultOrgId, int(claims["org"].(float64)))
+	assert.Equal(t, st.Cfg.Tokens.Issuer, claims["iss"])
+	assert.Equal(t, strconv.FormatInt(respRegister.GetId(), 10), claims["sub"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.NotEmpty(t, claims["sid"])
 
 	const deltaSec = 1
 	assert.InDelta(t, loginTime.Add(st.Cfg.TokenTTL).Unix(), int64(claims["exp"].(float64)), deltaSec)
@@ -139,9 +148,10 @@ func TestRegister_validationErrors(t *testing.T) {
 
 	ctx, st := suite.NewSuite(t)
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			_, err := st.AuthClient.Register(ctx, &domofon_v1.RegisterRequest{
+			_, err := st.AuthClient.Register(suite.WithApp(ctx, AppId), &domofon_v1.RegisterRequest{
 				Email:    tt.email,
 				Password: tt.password,
 			})
@@ -151,6 +161,22 @@ func TestRegister_validationErrors(t *testing.T) {
 	}
 }
 
+func TestRegister_appRequired(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, err := st.AuthClient.Register(ctx, &domofon_v1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: randomFakePassport(),
+	})
+	requireCode(st, err, codes.InvalidArgument)
+
+	_, err = st.AuthClient.Register(suite.WithApp(ctx, 1<<30), &domofon_v1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: randomFakePassport(),
+	})
+	requireCode(st, err, codes.NotFound)
+}
+
 func TestLogin_validationErrors(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -188,6 +214,7 @@ func TestLogin_validationErrors(t *testing.T) {
 
 	ctx, st := suite.NewSuite(t)
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			_, err := st.AuthClient.Login(ctx, &domofon_v1.LoginRequest{
@@ -218,7 +245,7 @@ func register(
 	email string,
 	pass string,
 ) (*domofon_v1.RegisterResponse, error) {
-	return st.AuthClient.Register(ctx, &domofon_v1.RegisterRequest{
+	return st.AuthClient.Register(suite.WithApp(ctx, AppId), &domofon_v1.RegisterRequest{
 		Email:    email,
 		Password: pass,
 	})
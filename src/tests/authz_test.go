@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestAuthz_WriteCheckExpand_happyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	_, userCtx := newUser(ctx, st)
+
+	building := "building:" + gofakeit.UUID()
+	door := "door:" + gofakeit.UUID()
+
+	written, err := st.AuthzClient.WriteTuples(adminCtx, &api.WriteTuplesRequest{
+		Inserts: []string{
+			building + "#resident@user:12",
+			door + "#building@" + building,
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, written.Token)
+
+	check, err := st.AuthzClient.Check(userCtx, &api.CheckRequest{Tuple: door + "#opener@user:12", Token: written.Token})
+	require.NoError(t, err)
+	assert.True(t, check.Allowed)
+	assert.Equal(t, written.Token, check.Token)
+
+	check, err = st.AuthzClient.Check(userCtx, &api.CheckRequest{Tuple: door + "#opener@user:13"})
+	require.NoError(t, err)
+	assert.False(t, check.Allowed)
+
+	expand, err := st.AuthzClient.Expand(userCtx, &api.ExpandRequest{Userset: building + "#resident"})
+	require.NoError(t, err)
+	assert.Equal(t, "union", expand.Tree.Operation)
+	require.NotEmpty(t, expand.Tree.Children)
+	assert.Contains(t, expand.Tree.Children[0].Subjects, "user:12")
+
+	deleted, err := st.AuthzClient.WriteTuples(adminCtx, &api.WriteTuplesRequest{
+		Deletes: []string{building + "#resident@user:12"},
+	})
+	require.NoError(t, err)
+
+	check, err = st.AuthzClient.Check(userCtx, &api.CheckRequest{Tuple: door + "#opener@user:12", Token: deleted.Token})
+	require.NoError(t, err)
+	assert.False(t, check.Allowed)
+
+	// snapshot before the delete still sees the tuple
+	check, err = st.AuthzClient.Check(userCtx, &api.CheckRequest{Tuple: door + "#opener@user:12", Token: written.Token})
+	require.NoError(t, err)
+	assert.True(t, check.Allowed)
+}
+
+func TestAuthz_ListObjects_pages(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	subject := "user:" + gofakeit.UUID()
+	doors := []string{"door:" + gofakeit.UUID(), "door:" + gofakeit.UUID(), "door:" + gofakeit.UUID()}
+
+	var inserts []string
+	for _, d := range doors {
+		inserts = append(inserts, d+"#opener@"+subject)
+	}
+
+	written, err := st.AuthzClient.WriteTuples(adminContext(ctx, st), &api.WriteTuplesRequest{Inserts: inserts})
+	require.NoError(t, err)
+
+	_, userCtx := newUser(ctx, st)
+
+	var (
+		found  []string
+		cursor string
+	)
+	for i := 0; i < 1000; i++ {
+		resp, err := st.AuthzClient.ListObjects(userCtx, &api.ListObjectsRequest{
+			Namespace: "door",
+			Relation:  "opener",
+			Subject:   subject,
+			Token:     written.Token,
+			Cursor:    cursor,
+			PageSize:  50,
+		})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(resp.ObjectIds), 50)
+
+		found = append(found, resp.ObjectIds...)
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	for _, d := range doors {
+		assert.Contains(t, found, d[len("door:"):])
+	}
+}
+
+func TestAuthz_errors(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, userCtx := newUser(ctx, st)
+
+	_, err := st.AuthzClient.Check(ctx, &api.CheckRequest{Tuple: "door:1#opener@user:1"})
+	requireCode(st, err, codes.Unauthenticated)
+
+	_, err = st.AuthzClient.WriteTuples(userCtx, &api.WriteTuplesRequest{Inserts: []string{"door:1#opener@user:1"}})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.AuthzClient.Check(userCtx, &api.CheckRequest{Tuple: "door:1#closer@user:1"})
+	requireCode(st, err, codes.InvalidArgument)
+
+	_, err = st.AuthzClient.Check(userCtx, &api.CheckRequest{Tuple: "not a tuple"})
+	requireCode(st, err, codes.InvalidArgument)
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestGroups_nestedMembership(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	userID, userCtx := newUser(ctx, st)
+
+	building, err := st.GroupsClient.CreateGroup(adminCtx, &api.CreateGroupRequest{Name: "building " + gofakeit.UUID()})
+	require.NoError(t, err)
+	entrance, err := st.GroupsClient.CreateGroup(adminCtx, &api.CreateGroupRequest{Name: "entrance " + gofakeit.UUID()})
+	require.NoError(t, err)
+
+	_, err = st.GroupsClient.AddSubgroup(adminCtx, &api.SubgroupRequest{ParentId: building.Id, ChildId: entrance.Id})
+	require.NoError(t, err)
+	_, err = st.GroupsClient.AddUser(adminCtx, &api.GroupUserRequest{GroupId: entrance.Id, UserId: userID})
+	require.NoError(t, err)
+
+	groups, err := st.GroupsClient.UserGroups(userCtx, &api.UserGroupsRequest{UserId: userID})
+	require.NoError(t, err)
+	ids := make([]int32, 0, len(groups.Groups))
+	for _, g := range groups.Groups {
+		ids = append(ids, g.Id)
+	}
+	assert.ElementsMatch(t, []int32{building.Id, entrance.Id}, ids)
+
+	users, err := st.GroupsClient.GroupUsers(adminCtx, &api.GroupUsersRequest{GroupId: building.Id})
+	require.NoError(t, err)
+	assert.Contains(t, users.UserIds, userID)
+
+	_, err = st.GroupsClient.AssignRole(adminCtx, &api.GroupRoleRequest{GroupId: building.Id, AppId: AppId, Role: residentRole})
+	require.NoError(t, err)
+
+	check, err := st.RBACClient.CheckPermission(userCtx, &api.CheckPermissionRequest{
+		UserId:     userID,
+		AppId:      AppId,
+		Permission: doorPermission,
+	})
+	require.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	_, err = st.GroupsClient.RemoveSubgroup(adminCtx, &api.SubgroupRequest{ParentId: building.Id, ChildId: entrance.Id})
+	require.NoError(t, err)
+
+	check, err = st.RBACClient.CheckPermission(userCtx, &api.CheckPermissionRequest{
+		UserId:     userID,
+		AppId:      AppId,
+		Permission: doorPermission,
+	})
+	require.NoError(t, err)
+	assert.False(t, check.Allowed)
+
+	_, err = st.GroupsClient.RevokeRole(adminCtx, &api.GroupRoleRequest{GroupId: building.Id, AppId: AppId, Role: residentRole})
+	require.NoError(t, err)
+	_, err = st.GroupsClient.RemoveUser(adminCtx, &api.GroupUserRequest{GroupId: entrance.Id, UserId: userID})
+	require.NoError(t, err)
+
+	_, err = st.GroupsClient.DeleteGroup(adminCtx, &api.DeleteGroupRequest{GroupId: entrance.Id})
+	require.NoError(t, err)
+	_, err = st.GroupsClient.DeleteGroup(adminCtx, &api.DeleteGroupRequest{GroupId: entrance.Id})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestGroups_AddSubgroup_cycle(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+
+	a, err := st.GroupsClient.CreateGroup(adminCtx, &api.CreateGroupRequest{Name: gofakeit.UUID()})
+	require.NoError(t, err)
+	b, err := st.GroupsClient.CreateGroup(adminCtx, &api.CreateGroupRequest{Name: gofakeit.UUID()})
+	require.NoError(t, err)
+
+	_, err = st.GroupsClient.AddSubgroup(adminCtx, &api.SubgroupRequest{ParentId: a.Id, ChildId: b.Id})
+	require.NoError(t, err)
+
+	_, err = st.GroupsClient.AddSubgroup(adminCtx, &api.SubgroupRequest{ParentId: b.Id, ChildId: a.Id})
+	requireCode(st, err, codes.FailedPrecondition)
+
+	_, err = st.GroupsClient.AddSubgroup(adminCtx, &api.SubgroupRequest{ParentId: a.Id, ChildId: a.Id})
+	requireCode(st, err, codes.FailedPrecondition)
+}
+
+func TestGroups_CreateList(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	name := gofakeit.UUID()
+
+	created, err := st.GroupsClient.CreateGroup(adminCtx, &api.CreateGroupRequest{Name: name})
+	require.NoError(t, err)
+
+	_, err = st.GroupsClient.CreateGroup(adminCtx, &api.CreateGroupRequest{Name: name})
+	requireCode(st, err, codes.AlreadyExists)
+
+	list, err := st.GroupsClient.ListGroups(adminCtx, &api.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, name, findGroup(list, created.Id).Name)
+}
+
+func TestGroups_access(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userID, userCtx := newUser(ctx, st)
+	otherID, _ := newUser(ctx, st)
+
+	_, err := st.GroupsClient.CreateGroup(userCtx, &api.CreateGroupRequest{Name: gofakeit.UUID()})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.GroupsClient.UserGroups(ctx, &api.UserGroupsRequest{UserId: userID})
+	requireCode(st, err, codes.Unauthenticated)
+
+	_, err = st.GroupsClient.UserGroups(userCtx, &api.UserGroupsRequest{UserId: userID})
+	require.NoError(t, err)
+
+	_, err = st.GroupsClient.UserGroups(userCtx, &api.UserGroupsRequest{UserId: otherID})
+	requireCode(st, err, codes.PermissionDenied)
+}
+
+func findGroup(list *api.ListGroupsResponse, id int32) api.Group {
+	for _, g := range list.Groups {
+		if g.Id == id {
+			return g
+		}
+	}
+
+	return api.Group{}
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"context"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// adminEmail is admin of default organization seeded by test migrations
+	adminEmail = "admin@domofon.test"
+	adminPass  = "admin-password"
+)
+
+// adminContext returns ctx authorized as seeded admin
+func adminContext(ctx context.Context, st *suite.Suite) context.Context {
+	st.Helper()
+
+	resp, err := login(ctx, st, adminEmail, adminPass)
+	require.NoError(st, err)
+
+	return suite.WithToken(ctx, resp.GetToken())
+}
+
+// newUser registers user and returns its id with ctx authorized as the user
+func newUser(ctx context.Context, st *suite.Suite) (int64, context.Context) {
+	st.Helper()
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(st, err)
+
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(st, err)
+
+	return respRegister.GetId(), suite.WithToken(ctx, respLogin.GetToken())
+}
+
+func requireCode(st *suite.Suite, err error, code codes.Code) {
+	st.Helper()
+
+	require.Error(st, err)
+	require.Equal(st, code, status.Code(err), err.Error())
+}
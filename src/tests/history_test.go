@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestHistory_ListLoginEvents(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	userID := respRegister.GetId()
+
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+	_, err = login(ctx, st, email, pass+"wrong")
+	require.Error(t, err)
+	_, err = login(ctx, st, email, pass)
+	require.NoError(t, err)
+	userCtx := suite.WithToken(ctx, respLogin.GetToken())
+
+	page, err := st.HistoryClient.ListLoginEvents(userCtx, &api.ListLoginEventsRequest{UserId: userID, PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	require.NotEmpty(t, page.NextCursor)
+	assert.True(t, page.Events[0].Success)
+	assert.False(t, page.Events[1].Success)
+	assert.Greater(t, page.Events[0].Id, page.Events[1].Id)
+
+	page, err = st.HistoryClient.ListLoginEvents(userCtx, &api.ListLoginEventsRequest{
+		UserId:   userID,
+		Cursor:   page.NextCursor,
+		PageSize: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.True(t, page.Events[0].Success)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = st.HistoryClient.ListLoginEvents(userCtx, &api.ListLoginEventsRequest{UserId: userID, Cursor: "!"})
+	requireCode(st, err, codes.InvalidArgument)
+}
+
+func TestHistory_ListLoginEvents_otherUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userID, _ := newUser(ctx, st)
+	_, otherCtx := newUser(ctx, st)
+
+	_, err := st.HistoryClient.ListLoginEvents(otherCtx, &api.ListLoginEventsRequest{UserId: userID})
+	requireCode(st, err, codes.PermissionDenied)
+
+	page, err := st.HistoryClient.ListLoginEvents(adminContext(ctx, st), &api.ListLoginEventsRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.Len(t, page.Events, 1)
+}
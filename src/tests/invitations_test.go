@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestInvitations_CreateAccept_happyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+
+	created, err := st.InvitationsClient.CreateInvitation(adminCtx, &api.CreateInvitationRequest{
+		Email: email,
+		Roles: []api.InvitationRole{{AppId: AppId, Role: residentRole}},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Token)
+
+	_, err = st.InvitationsClient.AcceptInvitation(ctx, &api.AcceptInvitationRequest{Token: created.Token})
+	requireCode(st, err, codes.InvalidArgument)
+
+	accepted, err := st.InvitationsClient.AcceptInvitation(ctx, &api.AcceptInvitationRequest{
+		Token:    created.Token,
+		Password: pass,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, accepted.UserId)
+
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	check, err := st.RBACClient.CheckPermission(suite.WithToken(ctx, respLogin.GetToken()), &api.CheckPermissionRequest{
+		UserId:     accepted.UserId,
+		AppId:      AppId,
+		Permission: doorPermission,
+	})
+	require.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	_, err = st.InvitationsClient.AcceptInvitation(ctx, &api.AcceptInvitationRequest{
+		Token:    created.Token,
+		Password: pass,
+	})
+	requireCode(st, err, codes.NotFound)
+
+	list, err := st.InvitationsClient.ListInvitations(adminCtx, &api.Empty{})
+	require.NoError(t, err)
+
+	var found *api.Invitation
+	for i := range list.Invitations {
+		if list.Invitations[i].Id == created.Id {
+			found = &list.Invitations[i]
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, email, found.Email)
+	assert.NotNil(t, found.AcceptedAt)
+}
+
+func TestInvitations_Accept_existingUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	created, err := st.InvitationsClient.CreateInvitation(adminContext(ctx, st), &api.CreateInvitationRequest{
+		Email: email,
+		Roles: []api.InvitationRole{{AppId: AppId, Role: residentRole}},
+	})
+	require.NoError(t, err)
+
+	accepted, err := st.InvitationsClient.AcceptInvitation(ctx, &api.AcceptInvitationRequest{Token: created.Token})
+	require.NoError(t, err)
+	assert.Equal(t, respRegister.GetId(), accepted.UserId)
+}
+
+func TestInvitations_Create_unknownRole(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, err := st.InvitationsClient.CreateInvitation(adminContext(ctx, st), &api.CreateInvitationRequest{
+		Email: gofakeit.Email(),
+		Roles: []api.InvitationRole{{AppId: AppId, Role: "no-such-role"}},
+	})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestInvitations_Revoke(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+
+	created, err := st.InvitationsClient.CreateInvitation(adminCtx, &api.CreateInvitationRequest{Email: gofakeit.Email()})
+	require.NoError(t, err)
+
+	_, err = st.InvitationsClient.RevokeInvitation(adminCtx, &api.RevokeInvitationRequest{InvitationId: created.Id})
+	require.NoError(t, err)
+
+	_, err = st.InvitationsClient.RevokeInvitation(adminCtx, &api.RevokeInvitationRequest{InvitationId: created.Id})
+	requireCode(st, err, codes.NotFound)
+
+	_, err = st.InvitationsClient.AcceptInvitation(ctx, &api.AcceptInvitationRequest{
+		Token:    created.Token,
+		Password: randomFakePassport(),
+	})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestInvitations_access(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, userCtx := newUser(ctx, st)
+
+	_, err := st.InvitationsClient.CreateInvitation(ctx, &api.CreateInvitationRequest{Email: gofakeit.Email()})
+	requireCode(st, err, codes.Unauthenticated)
+
+	_, err = st.InvitationsClient.CreateInvitation(userCtx, &api.CreateInvitationRequest{Email: gofakeit.Email()})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.InvitationsClient.ListInvitations(userCtx, &api.Empty{})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.InvitationsClient.AcceptInvitation(ctx, &api.AcceptInvitationRequest{Token: "forged", Password: "pass"})
+	requireCode(st, err, codes.NotFound)
+}
@@ -0,0 +1,18 @@
+begin;
+
+create table if not exists invitations
+(
+    id          bigint primary key generated always as identity,
+    org_id      int         not null references organizations (id) on delete cascade,
+    email       text        not null,
+    roles       jsonb       not null default '[]',
+    nonce_hash  bytea       not null,
+    created_at  timestamptz not null default now(),
+    expires_at  timestamptz not null,
+    accepted_at timestamptz,
+    revoked_at  timestamptz
+);
+
+create index if not exists idx_invitations_org on invitations (org_id, created_at);
+
+commit
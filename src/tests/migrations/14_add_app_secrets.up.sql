@@ -0,0 +1,15 @@
+begin;
+
+-- secrets replaced by rotation, tokens signed with them are accepted until expires_at
+create table if not exists app_secrets
+(
+    id         bigserial primary key,
+    app_id     integer     not null references apps (id) on delete cascade,
+    secret     text        not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null
+);
+
+create index if not exists idx_app_secrets_app_id on app_secrets (app_id, expires_at);
+
+commit
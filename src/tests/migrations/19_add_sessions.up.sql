@@ -0,0 +1,19 @@
+begin;
+
+-- sign-ins of users, id is sid claim of tokens issued on them
+create table if not exists sessions
+(
+    id           text primary key,
+    org_id       int         not null references organizations (id) on delete cascade,
+    app_id       int         not null references apps (id) on delete cascade,
+    user_id      int         not null references users (id) on delete cascade,
+    ip           text        not null default '',
+    user_agent   text        not null default '',
+    created_at   timestamptz not null default now(),
+    last_seen_at timestamptz not null default now(),
+    revoked_at   timestamptz
+);
+
+create index if not exists idx_sessions_user on sessions (user_id);
+
+commit
@@ -0,0 +1,11 @@
+begin;
+
+alter table apps
+    add column if not exists session_settings jsonb not null default '{}';
+
+-- limits are copied from app on login, changing app affects only new sessions
+alter table sessions
+    add column if not exists idle_timeout bigint not null default 0,
+    add column if not exists expires_at   timestamptz;
+
+commit
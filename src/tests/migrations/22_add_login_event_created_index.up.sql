@@ -0,0 +1,6 @@
+begin;
+
+-- sweeper deletes login events older than retention
+create index if not exists idx_login_events_created_at on login_events (created_at);
+
+commit
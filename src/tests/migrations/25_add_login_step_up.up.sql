@@ -0,0 +1,7 @@
+begin;
+
+-- login reaching step-up score is flagged, its tokens get acr 0
+alter table login_events
+    add column if not exists step_up bool not null default false;
+
+commit
@@ -0,0 +1,23 @@
+begin;
+
+insert into roles (app_id, name)
+select id, 'resident'
+from apps
+where name = 'test'
+on conflict do nothing;
+
+insert into permissions (app_id, name)
+select id, 'door:open'
+from apps
+where name = 'test'
+on conflict do nothing;
+
+insert into role_permissions (role_id, permission_id)
+select r.id, p.id
+from roles r
+         join permissions p on p.app_id = r.app_id
+where r.name = 'resident'
+  and p.name = 'door:open'
+on conflict do nothing;
+
+commit
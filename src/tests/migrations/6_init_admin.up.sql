@@ -0,0 +1,4 @@
+-- admin, password is admin-password
+insert into users (email, pass_hash, is_admin)
+values ('admin@domofon.test', '$2a$10$FyK.8WXgGy46CS3/w0AIe.vNDdQ8HjA/41Nd5B29eVlVsYw.b.Qb2', true)
+on conflict do nothing
@@ -0,0 +1,11 @@
+begin;
+
+alter table apps
+    add column if not exists login_policy  text not null default '',
+    add column if not exists claims_policy text not null default '';
+
+alter table users
+    add column if not exists email_verified bool  not null default false,
+    add column if not exists profile        jsonb not null default '{}';
+
+commit
@@ -0,0 +1,53 @@
+begin;
+
+create table if not exists organizations
+(
+    id         int primary key generated always as identity,
+    name       text        not null unique,
+    created_at timestamptz not null default now()
+);
+
+-- existing users and apps move to the default organization
+insert into organizations (name)
+values ('default')
+on conflict do nothing;
+
+alter table users
+    add column if not exists org_id int references organizations (id);
+update users
+set org_id = (select id from organizations where name = 'default');
+alter table users
+    alter column org_id set not null,
+    drop constraint if exists users_email_key,
+    add constraint users_org_id_email_key unique (org_id, email);
+
+drop index if exists idx_email;
+create index if not exists idx_users_org_email on users (org_id, email);
+
+alter table apps
+    add column if not exists org_id int references organizations (id);
+update apps
+set org_id = (select id from organizations where name = 'default');
+alter table apps
+    alter column org_id set not null,
+    drop constraint if exists apps_name_key,
+    add constraint apps_org_id_name_key unique (org_id, name);
+
+alter table relation_tuples
+    add column if not exists org_id int references organizations (id);
+update relation_tuples
+set org_id = (select id from organizations where name = 'default');
+alter table relation_tuples
+    alter column org_id set not null;
+
+drop index if exists idx_relation_tuples_object;
+drop index if exists idx_relation_tuples_live;
+
+create index if not exists idx_relation_tuples_object
+    on relation_tuples (org_id, namespace, object_id, relation);
+
+create unique index if not exists idx_relation_tuples_live
+    on relation_tuples (org_id, namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+    where deleted_rev is null;
+
+commit
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestOrgs_CRUD_happyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	name := gofakeit.Company() + " " + gofakeit.UUID()
+
+	created, err := st.OrgsClient.CreateOrg(adminCtx, &api.CreateOrgRequest{Name: name})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Id)
+
+	org, err := st.OrgsClient.GetOrg(adminCtx, &api.GetOrgRequest{OrgId: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, name, org.Name)
+	assert.False(t, org.CreatedAt.IsZero())
+
+	list, err := st.OrgsClient.ListOrgs(adminCtx, &api.Empty{})
+	require.NoError(t, err)
+	assert.Contains(t, list.Orgs, *org)
+
+	renamed := name + " renamed"
+	_, err = st.OrgsClient.RenameOrg(adminCtx, &api.RenameOrgRequest{OrgId: created.Id, Name: renamed})
+	require.NoError(t, err)
+
+	org, err = st.OrgsClient.GetOrg(adminCtx, &api.GetOrgRequest{OrgId: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, renamed, org.Name)
+
+	_, err = st.OrgsClient.DeleteOrg(adminCtx, &api.DeleteOrgRequest{OrgId: created.Id})
+	require.NoError(t, err)
+
+	_, err = st.OrgsClient.GetOrg(adminCtx, &api.GetOrgRequest{OrgId: created.Id})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestOrgs_errors(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	_, userCtx := newUser(ctx, st)
+	name := gofakeit.Company() + " " + gofakeit.UUID()
+
+	_, err := st.OrgsClient.CreateOrg(adminCtx, &api.CreateOrgRequest{Name: name})
+	require.NoError(t, err)
+
+	_, err = st.OrgsClient.CreateOrg(adminCtx, &api.CreateOrgRequest{Name: name})
+	requireCode(st, err, codes.AlreadyExists)
+
+	// default organization has users and apps
+	_, err = st.OrgsClient.DeleteOrg(adminCtx, &api.DeleteOrgRequest{OrgId: defaultOrgId})
+	requireCode(st, err, codes.FailedPrecondition)
+
+	_, err = st.OrgsClient.ListOrgs(userCtx, &api.Empty{})
+	requireCode(st, err, codes.PermissionDenied)
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func TestPolicy_DryRun(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	userID, _ := newUser(ctx, st)
+
+	tests := []struct {
+		name        string
+		login       string
+		claims      string
+		wantAllowed bool
+		wantClaims  map[string]any
+	}{
+		{
+			name:        "app without policies",
+			wantAllowed: true,
+		},
+		{
+			name:        "allowed with claims",
+			login:       "!user.is_admin",
+			claims:      `{"building_id": "7", "app": app.name}`,
+			wantAllowed: true,
+			wantClaims:  map[string]any{"building_id": "7", "app": "test"},
+		},
+		{
+			name:  "denied",
+			login: "user.is_admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := st.PolicyClient.DryRun(adminCtx, &api.DryRunRequest{
+				AppId:        AppId,
+				UserId:       userID,
+				LoginPolicy:  tt.login,
+				ClaimsPolicy: tt.claims,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAllowed, resp.Allowed)
+			if tt.wantClaims != nil {
+				assert.Equal(t, tt.wantClaims, resp.Claims)
+			}
+		})
+	}
+}
+
+func TestPolicy_errors(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	userID, userCtx := newUser(ctx, st)
+
+	_, err := st.PolicyClient.SetAppPolicies(adminCtx, &api.SetAppPoliciesRequest{AppId: AppId, LoginPolicy: "user.email +"})
+	requireCode(st, err, codes.InvalidArgument)
+
+	_, err = st.PolicyClient.SetAppPolicies(adminCtx, &api.SetAppPoliciesRequest{AppId: AppId, ClaimsPolicy: "true"})
+	requireCode(st, err, codes.InvalidArgument)
+
+	_, err = st.PolicyClient.DryRun(adminCtx, &api.DryRunRequest{AppId: AppId, UserId: userID + 1_000_000})
+	requireCode(st, err, codes.NotFound)
+
+	_, err = st.PolicyClient.DryRun(userCtx, &api.DryRunRequest{AppId: AppId, UserId: userID})
+	requireCode(st, err, codes.PermissionDenied)
+}
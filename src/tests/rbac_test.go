@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+const (
+	residentRole   = "resident"
+	doorPermission = "door:open"
+)
+
+func TestRBAC_AssignCheckRevoke_happyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := adminContext(ctx, st)
+	userID, userCtx := newUser(ctx, st)
+
+	_, err := st.RBACClient.AssignRole(adminCtx, &api.AssignRoleRequest{UserId: userID, AppId: AppId, Role: residentRole})
+	require.NoError(t, err)
+
+	roles, err := st.RBACClient.ListRoles(userCtx, &api.ListRolesRequest{UserId: userID, AppId: AppId})
+	require.NoError(t, err)
+	require.Len(t, roles.Roles, 1)
+	assert.Equal(t, residentRole, roles.Roles[0].Name)
+	assert.Contains(t, roles.Roles[0].Permissions, doorPermission)
+
+	check, err := st.RBACClient.CheckPermission(userCtx, &api.CheckPermissionRequest{
+		UserId:     userID,
+		AppId:      AppId,
+		Permission: doorPermission,
+	})
+	require.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	_, err = st.RBACClient.RevokeRole(adminCtx, &api.RevokeRoleRequest{UserId: userID, AppId: AppId, Role: residentRole})
+	require.NoError(t, err)
+
+	check, err = st.RBACClient.CheckPermission(adminCtx, &api.CheckPermissionRequest{
+		UserId:     userID,
+		AppId:      AppId,
+		Permission: doorPermission,
+	})
+	require.NoError(t, err)
+	assert.False(t, check.Allowed)
+
+	_, err = st.RBACClient.RevokeRole(adminCtx, &api.RevokeRoleRequest{UserId: userID, AppId: AppId, Role: residentRole})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestRBAC_AssignRole_unknownRole(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userID, _ := newUser(ctx, st)
+
+	_, err := st.RBACClient.AssignRole(adminContext(ctx, st), &api.AssignRoleRequest{
+		UserId: userID,
+		AppId:  AppId,
+		Role:   "no-such-role",
+	})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestRBAC_access(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userID, userCtx := newUser(ctx, st)
+	otherID, _ := newUser(ctx, st)
+
+	_, err := st.RBACClient.AssignRole(ctx, &api.AssignRoleRequest{UserId: userID, AppId: AppId, Role: residentRole})
+	requireCode(st, err, codes.Unauthenticated)
+
+	_, err = st.RBACClient.AssignRole(userCtx, &api.AssignRoleRequest{UserId: userID, AppId: AppId, Role: residentRole})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.RBACClient.ListRoles(userCtx, &api.ListRolesRequest{UserId: otherID, AppId: AppId})
+	requireCode(st, err, codes.PermissionDenied)
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	domofon_v1 "github.com/zose43/domofon-proto/out/go"
+	"testing"
+)
+
+func TestLogin_stepUp(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	created, err := st.AppsClient.CreateApp(adminContext(ctx, st), &api.CreateAppRequest{
+		Name: "risky " + gofakeit.UUID(),
+		Risk: &api.RiskSettings{StepUpScore: number(10), DenyScore: number(0)},
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	_, err = register(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	loginApp := func(pass string) (string, error) {
+		resp, err := st.AuthClient.Login(ctx, &domofon_v1.LoginRequest{
+			Email:    email,
+			Password: pass,
+			AppId:    created.App.Id,
+		})
+		return resp.GetToken(), err
+	}
+
+	token, err := loginApp(pass)
+	require.NoError(t, err)
+	res, err := st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: token})
+	require.NoError(t, err)
+	assert.Equal(t, "1", res.ACR)
+
+	// failed attempt raises risk of the next login to step-up score, it still succeeds
+	_, err = loginApp(pass + "wrong")
+	require.Error(t, err)
+	token, err = loginApp(pass)
+	require.NoError(t, err)
+
+	res, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: token})
+	require.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.Equal(t, "0", res.ACR)
+}
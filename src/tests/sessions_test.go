@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"testing"
+	"time"
+)
+
+func TestSessions_ListRevoke(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	userID := respRegister.GetId()
+
+	first, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+	second, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+	userCtx := suite.WithToken(ctx, first.GetToken())
+
+	list, err := st.SessionsClient.ListSessions(userCtx, &api.ListSessionsRequest{UserId: userID})
+	require.NoError(t, err)
+	require.Len(t, list.Sessions, 2)
+	for _, s := range list.Sessions {
+		assert.EqualValues(t, AppId, s.AppId)
+		assert.WithinDuration(t, time.Now(), s.LastSeenAt, time.Minute)
+	}
+
+	validated, err := st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: second.GetToken()})
+	require.NoError(t, err)
+	require.True(t, validated.Active)
+	sessionID := validated.SessionId
+
+	_, err = st.SessionsClient.RevokeSession(userCtx, &api.RevokeSessionRequest{
+		UserId:    userID,
+		SessionId: sessionID,
+	})
+	require.NoError(t, err)
+
+	validated, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: second.GetToken()})
+	require.NoError(t, err)
+	assert.False(t, validated.Active)
+
+	list, err = st.SessionsClient.ListSessions(userCtx, &api.ListSessionsRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.Len(t, list.Sessions, 1)
+
+	_, err = st.SessionsClient.RevokeSession(userCtx, &api.RevokeSessionRequest{
+		UserId:    userID,
+		SessionId: sessionID,
+	})
+	requireCode(st, err, codes.NotFound)
+
+	_, err = st.SessionsClient.RevokeSession(userCtx, &api.RevokeSessionRequest{UserId: userID})
+	requireCode(st, err, codes.InvalidArgument)
+}
+
+func TestSessions_otherUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userID, _ := newUser(ctx, st)
+	_, otherCtx := newUser(ctx, st)
+
+	_, err := st.SessionsClient.ListSessions(otherCtx, &api.ListSessionsRequest{UserId: userID})
+	requireCode(st, err, codes.PermissionDenied)
+
+	_, err = st.SessionsClient.RevokeSession(otherCtx, &api.RevokeSessionRequest{UserId: userID, SessionId: "any"})
+	requireCode(st, err, codes.PermissionDenied)
+
+	list, err := st.SessionsClient.ListSessions(adminContext(ctx, st), &api.ListSessionsRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.Len(t, list.Sessions, 1)
+
+	_, err = st.SessionsClient.ListSessions(ctx, &api.ListSessionsRequest{UserId: userID})
+	requireCode(st, err, codes.Unauthenticated)
+}
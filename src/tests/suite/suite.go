@@ -3,9 +3,12 @@ package suite
 import (
 	"context"
 	"domofon/internal/config"
+	"domofon/internal/grpc/api"
 	domofon_v1 "github.com/zose43/domofon-proto/out/go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"net"
 	"strconv"
 	"testing"
@@ -17,8 +20,19 @@ const (
 
 type Suite struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient domofon_v1.AuthClient
+	Cfg               *config.Config
+	AuthClient        domofon_v1.AuthClient
+	RBACClient        *api.RBACClient
+	AuthzClient       *api.AuthzClient
+	PolicyClient      *api.PolicyClient
+	OrgsClient        *api.OrgsClient
+	InvitationsClient *api.InvitationsClient
+	GroupsClient      *api.GroupsClient
+	AdminClient       *api.AdminClient
+	AppsClient        *api.AppsClient
+	TokensClient      *api.TokensClient
+	SessionsClient    *api.SessionsClient
+	HistoryClient     *api.HistoryClient
 }
 
 func NewSuite(t *testing.T) (context.Context, *Suite) {
@@ -37,19 +51,56 @@ func NewSuite(t *testing.T) (context.Context, *Suite) {
 	cc, err := grpc.DialContext(
 		context.Background(),
 		grpcAddress(cfg),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(transportCredentials(t, cfg)),
 	)
 	if err != nil {
 		t.Fatalf("grpc server connection failed: %v", err)
 	}
 
 	return ctx, &Suite{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: domofon_v1.NewAuthClient(cc),
+		T:                 t,
+		Cfg:               cfg,
+		AuthClient:        domofon_v1.NewAuthClient(cc),
+		RBACClient:        api.NewRBACClient(cc),
+		AuthzClient:       api.NewAuthzClient(cc),
+		PolicyClient:      api.NewPolicyClient(cc),
+		OrgsClient:        api.NewOrgsClient(cc),
+		InvitationsClient: api.NewInvitationsClient(cc),
+		GroupsClient:      api.NewGroupsClient(cc),
+		AdminClient:       api.NewAdminClient(cc),
+		AppsClient:        api.NewAppsClient(cc),
+		TokensClient:      api.NewTokensClient(cc),
+		SessionsClient:    api.NewSessionsClient(cc),
+		HistoryClient:     api.NewHistoryClient(cc),
 	}
 }
 
+// WithToken returns ctx sending token as bearer authorization
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// WithApp returns ctx sending app of requests without app_id field, like Register
+func WithApp(ctx context.Context, appID int32) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "x-app-id", strconv.Itoa(int(appID)))
+}
+
+// transportCredentials trust server certificate of config, plaintext when TLS is off
+func transportCredentials(t *testing.T, cfg *config.Config) credentials.TransportCredentials {
+	t.Helper()
+
+	if cfg.GrpcSrv.TLS.CertFile == "" {
+		return insecure.NewCredentials()
+	}
+
+	creds, err := credentials.NewClientTLSFromFile(cfg.GrpcSrv.TLS.CertFile, grpchost)
+	if err != nil {
+		t.Fatalf("failed loading server certificate: %v", err)
+	}
+
+	return creds
+}
+
 func grpcAddress(cfg *config.Config) string {
 	return net.JoinHostPort(grpchost, strconv.Itoa(cfg.GrpcSrv.Port))
 }
@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"domofon/internal/grpc/api"
+	"domofon/tests/suite"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	domofon_v1 "github.com/zose43/domofon-proto/out/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resourceURL is request of resource server validating tokens in tests
+const resourceURL = "https://doors.domofon.test/open"
+
+// testClient authenticates as the test app to token endpoints
+var testClient = api.ClientCredentials{ClientId: AppId, ClientSecret: appSecret}
+
+func TestTokens_IntrospectRevoke_opaque(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	created, err := st.AppsClient.CreateApp(adminContext(ctx, st), &api.CreateAppRequest{
+		Name:   "opaque " + gofakeit.UUID(),
+		Tokens: &api.TokenSettings{Format: "opaque"},
+	})
+	require.NoError(t, err)
+	client := api.ClientCredentials{ClientId: created.App.Id, ClientSecret: created.Secret}
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &domofon_v1.LoginRequest{
+		Email:    email,
+		Password: pass,
+		AppId:    created.App.Id,
+	})
+	require.NoError(t, err)
+	token := respLogin.GetToken()
+	require.True(t, strings.HasPrefix(token, "at_"))
+
+	res, err := st.TokensClient.Introspect(ctx, &api.IntrospectRequest{ClientCredentials: client, Token: token})
+	require.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.EqualValues(t, respRegister.GetId(), res.Claims["uid"])
+
+	// other apps can't revoke it
+	_, err = st.TokensClient.Revoke(ctx, &api.RevokeRequest{ClientCredentials: testClient, Token: token})
+	requireCode(st, err, codes.NotFound)
+
+	_, err = st.TokensClient.Revoke(ctx, &api.RevokeRequest{ClientCredentials: client, Token: token})
+	require.NoError(t, err)
+
+	res, err = st.TokensClient.Introspect(ctx, &api.IntrospectRequest{ClientCredentials: client, Token: token})
+	require.NoError(t, err)
+	assert.False(t, res.Active)
+	assert.Empty(t, res.Claims)
+
+	_, err = st.TokensClient.Revoke(ctx, &api.RevokeRequest{ClientCredentials: client, Token: token})
+	requireCode(st, err, codes.NotFound)
+}
+
+func TestTokens_Introspect_jwt(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	res, err := st.TokensClient.Introspect(ctx, &api.IntrospectRequest{
+		ClientCredentials: testClient,
+		Token:             respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.EqualValues(t, respRegister.GetId(), res.Claims["uid"])
+	assert.EqualValues(t, AppId, res.Claims["app"])
+	assert.NotEmpty(t, res.Claims["exp"])
+
+	_, err = st.TokensClient.Revoke(ctx, &api.RevokeRequest{ClientCredentials: testClient, Token: respLogin.GetToken()})
+	requireCode(st, err, codes.FailedPrecondition)
+
+	res, err = st.TokensClient.Introspect(ctx, &api.IntrospectRequest{ClientCredentials: testClient, Token: "garbage"})
+	require.NoError(t, err)
+	assert.False(t, res.Active)
+
+	_, err = st.TokensClient.Introspect(ctx, &api.IntrospectRequest{ClientCredentials: testClient})
+	requireCode(st, err, codes.InvalidArgument)
+}
+
+func TestTokens_Introspect_clientAuth(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	_, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+	token := respLogin.GetToken()
+
+	tests := []struct {
+		name   string
+		client api.ClientCredentials
+	}{
+		{
+			name: "no client",
+		},
+		{
+			name:   "no secret or certificate",
+			client: api.ClientCredentials{ClientId: AppId},
+		},
+		{
+			name:   "wrong secret",
+			client: api.ClientCredentials{ClientId: AppId, ClientSecret: "not-" + appSecret},
+		},
+		{
+			name:   "unknown app",
+			client: api.ClientCredentials{ClientId: 1 << 30, ClientSecret: appSecret},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.TokensClient.Introspect(ctx, &api.IntrospectRequest{ClientCredentials: tt.client, Token: token})
+			requireCode(st, err, codes.Unauthenticated)
+
+			_, err = st.TokensClient.Revoke(ctx, &api.RevokeRequest{ClientCredentials: tt.client, Token: token})
+			requireCode(st, err, codes.Unauthenticated)
+		})
+	}
+}
+
+func TestTokens_Introspect_otherApp(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	other, err := st.AppsClient.CreateApp(adminContext(ctx, st), &api.CreateAppRequest{Name: "doors " + gofakeit.UUID()})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	_, err = register(ctx, st, email, pass)
+	require.NoError(t, err)
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	res, err := st.TokensClient.Introspect(ctx, &api.IntrospectRequest{
+		ClientCredentials: api.ClientCredentials{ClientId: other.App.Id, ClientSecret: other.Secret},
+		Token:             respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.NotEmpty(t, res.Claims["exp"])
+	assert.NotContains(t, res.Claims, "uid")
+	assert.NotContains(t, res.Claims, "org")
+}
+
+func TestTokens_Validate_dpop(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	loginProof := dpopProof(st, key, http.MethodPost, st.Cfg.DPoP.BaseURL+"/domofon.Auth/Login", "")
+	respLogin, err := login(metadata.AppendToOutgoingContext(ctx, "dpop", loginProof), st, email, pass)
+	require.NoError(t, err)
+	token := respLogin.GetToken()
+
+	res, err := st.TokensClient.Validate(ctx, &api.ValidateRequest{
+		Token:     token,
+		DPoPProof: dpopProof(st, key, http.MethodGet, resourceURL, token),
+		Method:    http.MethodGet,
+		Url:       resourceURL,
+	})
+	require.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.Equal(t, respRegister.GetId(), res.UserId)
+	assert.NotEmpty(t, res.JKT)
+
+	// bound token is useless without proof of its key
+	_, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: token})
+	requireCode(st, err, codes.InvalidArgument)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{
+		Token:     token,
+		DPoPProof: dpopProof(st, otherKey, http.MethodGet, resourceURL, token),
+		Method:    http.MethodGet,
+		Url:       resourceURL,
+	})
+	requireCode(st, err, codes.InvalidArgument)
+
+	proof := dpopProof(st, key, http.MethodGet, resourceURL, token)
+	_, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{
+		Token:     token,
+		DPoPProof: proof,
+		Method:    http.MethodPost,
+		Url:       resourceURL,
+	})
+	requireCode(st, err, codes.InvalidArgument)
+
+	req := &api.ValidateRequest{Token: token, DPoPProof: proof, Method: http.MethodGet, Url: resourceURL}
+	_, err = st.TokensClient.Validate(ctx, req)
+	require.NoError(t, err)
+	_, err = st.TokensClient.Validate(ctx, req)
+	requireCode(st, err, codes.InvalidArgument)
+}
+
+func TestTokens_Validate_bearer(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassport()
+	respRegister, err := register(ctx, st, email, pass)
+	require.NoError(t, err)
+	respLogin, err := login(ctx, st, email, pass)
+	require.NoError(t, err)
+
+	res, err := st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: respLogin.GetToken()})
+	require.NoError(t, err)
+	assert.True(t, res.Active)
+	assert.Equal(t, respRegister.GetId(), res.UserId)
+	assert.EqualValues(t, AppId, res.AppId)
+	assert.Empty(t, res.JKT)
+
+	res, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{Token: "garbage"})
+	require.NoError(t, err)
+	assert.False(t, res.Active)
+
+	_, err = st.TokensClient.Validate(ctx, &api.ValidateRequest{
+		Token:     respLogin.GetToken(),
+		DPoPProof: "proof",
+	})
+	requireCode(st, err, codes.InvalidArgument)
+}
+
+// dpopProof returns proof of request by key, bound to accessToken when it isn't empty
+func dpopProof(st *suite.Suite, key *ecdsa.PrivateKey, method string, url string, accessToken string) string {
+	st.Helper()
+
+	raw, err := jose.JSONWebKey{Key: &key.PublicKey}.MarshalJSON()
+	require.NoError(st, err)
+	var jwk map[string]any
+	require.NoError(st, json.Unmarshal(raw, &jwk))
+
+	claims := jwt.MapClaims{
+		"jti": gofakeit.UUID(),
+		"htm": method,
+		"htu": url,
+		"iat": time.Now().Unix(),
+	}
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = jwk
+
+	proof, err := token.SignedString(key)
+	require.NoError(st, err)
+
+	return proof
+}